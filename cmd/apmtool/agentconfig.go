@@ -0,0 +1,116 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/elastic/apm-tools/pkg/kibanaclient"
+)
+
+func (cmd *Commands) setAgentConfigCommand(ctx context.Context, c *cli.Command) error {
+	settings, err := parseAgentConfigSettings(c.StringSlice("setting"))
+	if err != nil {
+		return err
+	}
+	client, err := cmd.kibanaClient()
+	if err != nil {
+		return err
+	}
+	return client.SetAgentConfig(ctx, serviceMatch(c), settings)
+}
+
+func (cmd *Commands) getAgentConfigCommand(ctx context.Context, c *cli.Command) error {
+	client, err := cmd.kibanaClient()
+	if err != nil {
+		return err
+	}
+	config, err := client.GetAgentConfig(ctx, serviceMatch(c))
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(c.Root().Writer)
+	enc.SetIndent("", "  ")
+	return enc.Encode(config)
+}
+
+// serviceMatch returns the kibanaclient.ServiceMatch specified by the
+// service and environment flags.
+func serviceMatch(c *cli.Command) kibanaclient.ServiceMatch {
+	return kibanaclient.ServiceMatch{
+		Name:        c.String("service"),
+		Environment: c.String("environment"),
+	}
+}
+
+// parseAgentConfigSettings parses a list of key=value settings.
+func parseAgentConfigSettings(values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, errors.New("at least one --setting must be specified")
+	}
+	settings := make(map[string]string, len(values))
+	for _, v := range values {
+		key, value, ok := strings.Cut(v, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid setting %q, expected key=value", v)
+		}
+		settings[key] = value
+	}
+	return settings, nil
+}
+
+// NewAgentConfigCmd returns pointer to a Command that manages agent central configuration in Kibana
+func NewAgentConfigCmd(commands *Commands) *cli.Command {
+	serviceFlags := func() []cli.Flag {
+		return []cli.Flag{
+			&cli.StringFlag{
+				Name:     "service",
+				Usage:    "name of the service to which the configuration applies",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:  "environment",
+				Usage: "environment to which the configuration applies; all environments if unspecified",
+			},
+		}
+	}
+	return &cli.Command{
+		Name:  "agent-config",
+		Usage: "manage agent central configuration in Kibana",
+		Commands: []*cli.Command{{
+			Name:   "set",
+			Usage:  "create or overwrite the agent configuration of a service",
+			Action: commands.setAgentConfigCommand,
+			Flags: append(serviceFlags(), &cli.StringSliceFlag{
+				Name:  "setting",
+				Usage: "configuration setting as key=value, e.g. transaction_sample_rate=0.5. May be repeated.",
+			}),
+		}, {
+			Name:   "get",
+			Usage:  "print the agent configuration of a service as JSON",
+			Action: commands.getAgentConfigCommand,
+			Flags:  serviceFlags(),
+		}},
+	}
+}
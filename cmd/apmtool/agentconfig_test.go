@@ -0,0 +1,70 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v3"
+
+	"github.com/elastic/apm-tools/pkg/apmclient"
+)
+
+func TestAgentConfigSet(t *testing.T) {
+	var body json.RawMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+		assert.Equal(t, "/api/apm/settings/agent-configuration", r.URL.Path)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	commands := &Commands{cfg: apmclient.Config{ElasticsearchURL: "http://localhost:9200", KibanaURL: srv.URL}}
+	cmd := &cli.Command{
+		Name:     "apmtool",
+		Commands: []*cli.Command{NewAgentConfigCmd(commands)},
+	}
+	err := cmd.Run(context.Background(), []string{
+		"apmtool", "agent-config", "set",
+		"--service", "svc", "--environment", "dev",
+		"--setting", "capture_body=all", "--setting", "log_level=debug",
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+	  "service": {"name": "svc", "environment": "dev"},
+	  "settings": {"capture_body": "all", "log_level": "debug"}
+	}`, string(body))
+}
+
+func TestParseAgentConfigSettings(t *testing.T) {
+	settings, err := parseAgentConfigSettings([]string{"a=1", "b=x=y", "c="})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"a": "1", "b": "x=y", "c": ""}, settings)
+
+	_, err = parseAgentConfigSettings(nil)
+	assert.EqualError(t, err, "at least one --setting must be specified")
+	_, err = parseAgentConfigSettings([]string{"a"})
+	assert.EqualError(t, err, `invalid setting "a", expected key=value`)
+}
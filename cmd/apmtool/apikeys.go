@@ -0,0 +1,54 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+
+	"github.com/urfave/cli/v3"
+)
+
+func (cmd *Commands) revokeKeysCommand(ctx context.Context, c *cli.Command) error {
+	client, err := cmd.getClient()
+	if err != nil {
+		return err
+	}
+	keys, err := client.ListAgentAPIKeys(ctx)
+	if err != nil {
+		return err
+	}
+	ids := make([]string, len(keys))
+	for i, key := range keys {
+		ids[i] = key.ID
+		cmd.logger().Debug("invalidating API key", "name", key.Name, "id", key.ID)
+	}
+	if err := client.InvalidateAgentAPIKeys(ctx, ids...); err != nil {
+		return err
+	}
+	cmd.logger().Info("invalidated API keys", "count", len(ids))
+	return removeCachedCredentials(cmd.cfg.APMServerURL)
+}
+
+// NewRevokeKeysCmd returns pointer to a Command that invalidates all agent API Keys created by apmtool
+func NewRevokeKeysCmd(commands *Commands) *cli.Command {
+	return &cli.Command{
+		Name:   "revoke-keys",
+		Usage:  "invalidate all agent API Keys created by apmtool, and clear cached credentials",
+		Action: commands.revokeKeysCommand,
+	}
+}
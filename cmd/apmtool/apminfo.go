@@ -0,0 +1,59 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/urfave/cli/v3"
+)
+
+func (cmd *Commands) apmInfoCommand(ctx context.Context, c *cli.Command) error {
+	creds, err := cmd.getCredentials(ctx, c)
+	if err != nil {
+		return err
+	}
+	client, err := cmd.getClient()
+	if err != nil {
+		return err
+	}
+	info, err := client.APMServerInfo(ctx, creds.APIKey, creds.SecretToken)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("version:    %s\n", info.Version)
+	fmt.Printf("build_sha:  %s\n", info.BuildSHA)
+	fmt.Printf("build_date: %s\n", info.BuildDate)
+	return nil
+}
+
+// NewAPMInfoCmd returns pointer to a Command that prints APM Server build information
+func NewAPMInfoCmd(commands *Commands) *cli.Command {
+	return &cli.Command{
+		Name:  "apm-info",
+		Usage: "check APM Server is reachable, and print its version",
+		Flags: []cli.Flag{
+			newAuthFlag(),
+			newMinTTLFlag(),
+			newYesFlag(),
+			newNoCreateKeyFlag(),
+		},
+		Action: commands.apmInfoCommand,
+	}
+}
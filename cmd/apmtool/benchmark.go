@@ -0,0 +1,114 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/elastic/apm-tools/pkg/tracegen"
+)
+
+func (cmd *Commands) benchmark(ctx context.Context, c *cli.Command) error {
+	var send func(context.Context, tracegen.Config) (tracegen.EventStats, error)
+	switch protocol := c.String("protocol"); protocol {
+	case "intake":
+		send = func(ctx context.Context, cfg tracegen.Config) (tracegen.EventStats, error) {
+			_, stats, err := tracegen.SendIntakeV2Trace(ctx, cfg)
+			return stats, err
+		}
+	case "otlp":
+		send = tracegen.SendOTLPTrace
+	default:
+		return fmt.Errorf("invalid protocol %q, expected one of: intake, otlp", protocol)
+	}
+
+	creds, err := cmd.getCredentials(ctx, c)
+	if err != nil {
+		return err
+	}
+	cfg := tracegen.NewConfig(
+		tracegen.WithAPMServerURL(cmd.cfg.APMServerURL),
+		tracegen.WithAPIKey(creds.APIKey),
+		tracegen.WithInsecureConn(cmd.cfg.APMServerTLSSkipVerify()),
+		tracegen.WithOTLPProtocol(c.String("otlp-protocol")),
+		tracegen.WithOTLPServiceName(newUniqueServiceName("benchmark", "otlp")),
+		tracegen.WithElasticAPMServiceName(newUniqueServiceName("benchmark", "intake")),
+		tracegen.WithDuration(c.Duration("duration")),
+	)
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Kill, os.Interrupt)
+	defer cancel()
+
+	result, err := tracegen.Benchmark(ctx, cfg, int(c.Uint("concurrency")), send)
+	if err != nil {
+		return fmt.Errorf("error running benchmark: %w", err)
+	}
+	fmt.Fprintf(c.Root().Writer,
+		"Sent %d event%s in %d request%s over %s (%.1f events/s), %d error%s\n",
+		result.Events(), pluralize(result.Events()),
+		result.Requests, pluralize(result.Requests),
+		result.Elapsed.Round(time.Millisecond), result.EventsPerSecond(),
+		result.Errors, pluralize(result.Errors),
+	)
+	fmt.Fprintf(c.Root().Writer,
+		"Request latency: p50 %s, p95 %s\n",
+		result.LatencyP50.Round(time.Microsecond),
+		result.LatencyP95.Round(time.Microsecond),
+	)
+	return nil
+}
+
+// NewBenchmarkCmd returns pointer to a Command that sends traces as fast
+// as possible from concurrent workers, and reports the throughput achieved.
+func NewBenchmarkCmd(commands *Commands) *cli.Command {
+	return &cli.Command{
+		Name:   "benchmark",
+		Usage:  "send traces as fast as possible for a duration and report the throughput achieved",
+		Action: commands.benchmark,
+		Flags: []cli.Flag{
+			&cli.DurationFlag{
+				Name:  "duration",
+				Usage: "how long to send traces for. 0 means until interrupted.",
+				Value: 30 * time.Second,
+			},
+			&cli.UintFlag{
+				Name:  "concurrency",
+				Usage: "number of workers sending traces concurrently",
+				Value: 4,
+			},
+			&cli.StringFlag{
+				Name:  "protocol",
+				Usage: "set the protocol used to send traces to one of: intake (default), otlp",
+				Value: "intake",
+			},
+			&cli.StringFlag{
+				Name:  "otlp-protocol",
+				Usage: "set OTLP transport protocol to one of: grpc (default), http/protobuf. Used with --protocol=otlp",
+				Value: "grpc",
+			},
+			newMinTTLFlag(),
+			newYesFlag(),
+			newNoCreateKeyFlag(),
+		},
+	}
+}
@@ -96,6 +96,29 @@ func updateCache(filename string, update func([]byte) ([]byte, error)) error {
 	return nil
 }
 
+// clearCache removes all files in the cache directory with a file lock.
+func clearCache() error {
+	cacheFlock := newCacheFlock()
+	if err := cacheFlock.Lock(); err != nil {
+		return fmt.Errorf("error acquiring lock on cache directory: %w", err)
+	}
+	defer cacheFlock.Unlock()
+
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		return fmt.Errorf("error reading cache directory: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.Name() == ".flock" {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(cacheDir, entry.Name())); err != nil {
+			return fmt.Errorf("error removing cache file %q: %w", entry.Name(), err)
+		}
+	}
+	return nil
+}
+
 func newCacheFlock() *flock.Flock {
 	return flock.New(filepath.Join(cacheDir, ".flock"))
 }
@@ -18,11 +18,76 @@
 package main
 
 import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
 	"github.com/elastic/apm-tools/pkg/apmclient"
+	"github.com/elastic/apm-tools/pkg/kibanaclient"
 )
 
 type Commands struct {
 	cfg apmclient.Config
+
+	// httpTimeout holds the timeout for HTTP requests made
+	// directly to APM Server and Kibana. Zero means no timeout.
+	httpTimeout time.Duration
+
+	// log holds the logger for diagnostic messages. If nil,
+	// slog.Default() is used.
+	log *slog.Logger
+
+	// isTerminal reports whether the given input is an interactive
+	// terminal, for prompting. If nil, isTerminal is used.
+	isTerminal func(io.Reader) bool
+}
+
+// logger returns the logger for diagnostic messages.
+func (cmd *Commands) logger() *slog.Logger {
+	if cmd.log == nil {
+		return slog.Default()
+	}
+	return cmd.log
+}
+
+// newLogger returns a logger writing records to w in the given format,
+// which must be "text" or "json". If verbose is true, debug-level
+// records are written; otherwise only info-level and above.
+func newLogger(w io.Writer, format string, verbose bool) (*slog.Logger, error) {
+	opts := &slog.HandlerOptions{Level: slog.LevelInfo}
+	if verbose {
+		opts.Level = slog.LevelDebug
+	}
+	switch format {
+	case "", "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	}
+	return nil, fmt.Errorf("invalid log format %q, expected one of: text, json", format)
+}
+
+// apmServerHTTPClient returns an HTTP client for requests made directly
+// to APM Server, which skips TLS certificate verification if configured
+// with --insecure or --apm-insecure.
+func (cmd *Commands) apmServerHTTPClient() *http.Client {
+	transport := cmd.cfg.Transport
+	if transport == nil {
+		defaultTransport := http.DefaultTransport.(*http.Transport).Clone()
+		defaultTransport.TLSClientConfig = &tls.Config{
+			InsecureSkipVerify: cmd.cfg.APMServerTLSSkipVerify(),
+		}
+		transport = defaultTransport
+	}
+	return &http.Client{Transport: transport, Timeout: cmd.httpTimeout}
+}
+
+// kibanaClient returns a client for the Kibana APIs.
+func (cmd *Commands) kibanaClient() (*kibanaclient.Client, error) {
+	return kibanaclient.New(cmd.cfg, kibanaclient.WithTimeout(cmd.httpTimeout))
 }
 
 func (cmd *Commands) getClient() (*apmclient.Client, error) {
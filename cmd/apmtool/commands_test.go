@@ -0,0 +1,83 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/apm-tools/pkg/apmclient"
+)
+
+func TestNewLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := newLogger(&buf, "json", false)
+	require.NoError(t, err)
+	assert.False(t, logger.Enabled(context.Background(), slog.LevelDebug))
+	logger.Debug("hidden")
+	logger.Info("shown")
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "shown", record["msg"])
+	assert.Equal(t, "INFO", record["level"])
+
+	buf.Reset()
+	logger, err = newLogger(&buf, "text", true)
+	require.NoError(t, err)
+	assert.True(t, logger.Enabled(context.Background(), slog.LevelDebug))
+	logger.Debug("shown")
+	assert.Contains(t, buf.String(), "level=DEBUG msg=shown")
+
+	_, err = newLogger(&buf, "xml", false)
+	assert.EqualError(t, err, `invalid log format "xml", expected one of: text, json`)
+}
+
+func TestAPMServerHTTPClientTLSSkipVerify(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	for name, tc := range map[string]struct {
+		cfg      apmclient.Config
+		expectOK bool
+	}{
+		"verify":       {cfg: apmclient.Config{}},
+		"insecure":     {cfg: apmclient.Config{TLSSkipVerify: true}, expectOK: true},
+		"apm_insecure": {cfg: apmclient.Config{APMTLSSkipVerify: true}, expectOK: true},
+		"es_insecure":  {cfg: apmclient.Config{ESTLSSkipVerify: true}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			commands := &Commands{cfg: tc.cfg}
+			resp, err := commands.apmServerHTTPClient().Get(srv.URL)
+			if !tc.expectOK {
+				assert.ErrorContains(t, err, "certificate")
+				return
+			}
+			require.NoError(t, err)
+			resp.Body.Close()
+		})
+	}
+}
@@ -77,10 +77,172 @@ func updateCachedCredentials(url string, c *credentials) error {
 	return nil
 }
 
+// removeCachedCredentials removes any cached credentials for the given URL.
+func removeCachedCredentials(url string) error {
+	if err := updateCache("credentials.json", func(data []byte) ([]byte, error) {
+		m := make(map[string]*credentials)
+		if data != nil {
+			if err := json.Unmarshal(data, &m); err != nil {
+				return nil, err
+			}
+		}
+		delete(m, url)
+		return json.Marshal(m)
+	}); err != nil {
+		return fmt.Errorf("error removing cached credentials: %w", err)
+	}
+	return nil
+}
+
+// clearCachedCredentials removes all cached credentials.
+func clearCachedCredentials() error {
+	if err := updateCache("credentials.json", func([]byte) ([]byte, error) {
+		return []byte("{}"), nil
+	}); err != nil {
+		return fmt.Errorf("error clearing cached credentials: %w", err)
+	}
+	return nil
+}
+
+// Supported values for the --auth flag.
+const (
+	authModeAuto        = "auto"
+	authModeAPIKey      = "apikey"
+	authModeSecretToken = "secret-token"
+)
+
+// newAuthFlag returns a flag for selecting which agent credentials
+// getCredentials returns.
+func newAuthFlag() *cli.StringFlag {
+	return &cli.StringFlag{
+		Name:  "auth",
+		Usage: "select agent credentials: apikey, secret-token, or auto to use any available",
+		Value: authModeAuto,
+	}
+}
+
+// defaultMinTTL holds the default minimum remaining lifetime of
+// cached credentials for getCredentials to return them.
+const defaultMinTTL = 5 * time.Minute
+
+// newMinTTLFlag returns a flag for controlling how close to expiry
+// cached credentials may be before getCredentials recreates them.
+func newMinTTLFlag() *cli.DurationFlag {
+	return &cli.DurationFlag{
+		Name:  "min-ttl",
+		Usage: "recreate cached credentials that expire within this duration",
+		Value: defaultMinTTL,
+	}
+}
+
+// newYesFlag returns a flag for allowing getCredentials
+// to create an API Key without prompting.
+func newYesFlag() *cli.BoolFlag {
+	return &cli.BoolFlag{
+		Name:    "yes",
+		Aliases: []string{"y"},
+		Usage:   "create an agent API Key, if needed, without prompting for confirmation",
+	}
+}
+
+// newNoCreateKeyFlag returns a flag for preventing
+// getCredentials from creating an API Key.
+func newNoCreateKeyFlag() *cli.BoolFlag {
+	return &cli.BoolFlag{
+		Name:  "no-create-key",
+		Usage: "fail rather than create an agent API Key if there are no usable cached credentials",
+	}
+}
+
+// confirmCreateAPIKey returns an error if an agent API Key should not
+// be created, according to the --yes and --no-create-key flags. Unless
+// --yes is specified, the user is prompted for confirmation if the
+// input is an interactive terminal.
+func (cmd *Commands) confirmCreateAPIKey(c *cli.Command) error {
+	if c.Bool("no-create-key") {
+		return errors.New("no usable cached credentials, and --no-create-key specified")
+	}
+	if c.Bool("yes") {
+		return nil
+	}
+	root := c.Root()
+	if !cmd.interactive(root.Reader) {
+		return errors.New(
+			"creating an agent API Key requires confirmation: " +
+				"specify --yes to create one non-interactively",
+		)
+	}
+	ok, err := confirm(root.Reader, root.ErrWriter, fmt.Sprintf(
+		"No usable cached credentials for %s. Create an agent API Key in %s?",
+		cmd.cfg.APMServerURL, cmd.cfg.ElasticsearchURL,
+	))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("agent API Key creation declined")
+	}
+	return nil
+}
+
+// expiresWithin reports whether creds expire within d of now.
+// Credentials with no expiry never expire.
+func (creds *credentials) expiresWithin(d time.Duration, now time.Time) bool {
+	return !creds.Expiry.IsZero() && creds.Expiry.Before(now.Add(d))
+}
+
+// selectCredentials returns the credentials for the given auth mode,
+// returning an error if the requested credentials are unavailable.
+func selectCredentials(creds *credentials, mode string) (*credentials, error) {
+	switch mode {
+	case authModeAuto:
+		return creds, nil
+	case authModeAPIKey:
+		if creds.APIKey == "" {
+			return nil, errors.New("API Key auth requested, but no API Key is available")
+		}
+		return &credentials{Expiry: creds.Expiry, APIKey: creds.APIKey}, nil
+	case authModeSecretToken:
+		if creds.SecretToken == "" {
+			return nil, errors.New(
+				"secret token auth requested, but no secret token is available; " +
+					"secret tokens are only available for Elastic Cloud deployments",
+			)
+		}
+		return &credentials{Expiry: creds.Expiry, SecretToken: creds.SecretToken}, nil
+	}
+	return nil, fmt.Errorf("invalid auth mode %q, expected one of: apikey, secret-token, auto", mode)
+}
+
 func (cmd *Commands) getCredentials(ctx context.Context, c *cli.Command) (*credentials, error) {
-	creds, err := readCachedCredentials(cmd.cfg.APMServerURL)
+	mode := c.String("auth")
+	if mode == "" {
+		// The command does not define the --auth flag.
+		mode = authModeAuto
+	}
+	switch mode {
+	case authModeAuto, authModeAPIKey, authModeSecretToken:
+	default:
+		return nil, fmt.Errorf("invalid auth mode %q, expected one of: apikey, secret-token, auto", mode)
+	}
+
+	minTTL := defaultMinTTL
+	if c.IsSet("min-ttl") {
+		minTTL = c.Duration("min-ttl")
+	}
+
+	cached, err := readCachedCredentials(cmd.cfg.APMServerURL)
 	if err == nil {
-		return creds, nil
+		if cached.expiresWithin(minTTL, time.Now()) {
+			// The cached credentials are about to expire; discard
+			// them so they are recreated below.
+			cmd.logger().Debug("cached credentials expire soon, recreating", "expiry", cached.Expiry)
+			cached = nil
+		} else if selected, err := selectCredentials(cached, mode); err == nil {
+			return selected, nil
+		}
+		// The cached credentials do not satisfy the auth mode;
+		// try to obtain the requested credentials below.
 	} else if !errors.Is(err, os.ErrNotExist) {
 		return nil, err
 	}
@@ -95,30 +257,44 @@ func (cmd *Commands) getCredentials(ctx context.Context, c *cli.Command) (*crede
 	// and extract a secret token from that. Otherwise, create an
 	// API Key.
 	var apiKey, secretToken string
+	var policyErr error
 	policy, err := client.GetElasticCloudAPMInput(ctx)
-	policyErr := fmt.Errorf("error getting APM cloud input: %w", err)
 	if err != nil {
-		if c.Bool("verbose") {
-			fmt.Fprintln(os.Stderr, policyErr)
-		}
+		policyErr = fmt.Errorf("error getting APM cloud input: %w", err)
+		cmd.logger().Debug("no Elastic Cloud APM policy found", "error", err)
 	} else {
 		secretToken = policy.Get("apm-server.auth.secret_token").String()
 	}
-	// Create an API Key.
-	fmt.Fprintln(os.Stderr, "Creating agent API Key...")
-	expiryDuration := c.Duration("api-key-expiration")
-	if expiryDuration > 0 {
-		expiry = time.Now().Add(expiryDuration)
-	}
-	apiKey, err = client.CreateAgentAPIKey(ctx, expiryDuration)
-	if err != nil {
-		apiKeyErr := err
-		return nil, fmt.Errorf(
-			"failed to obtain agent credentials: %w",
-			errors.Join(apiKeyErr, policyErr),
-		)
+	if mode == authModeSecretToken {
+		if secretToken == "" {
+			if policyErr != nil {
+				return nil, fmt.Errorf("failed to obtain secret token: %w", policyErr)
+			}
+			return nil, errors.New("failed to obtain secret token: secret token not available")
+		}
+		// Retain any previously cached API Key.
+		if cached != nil {
+			apiKey, expiry = cached.APIKey, cached.Expiry
+		}
+	} else {
+		// Create an API Key.
+		if err := cmd.confirmCreateAPIKey(c); err != nil {
+			return nil, err
+		}
+		cmd.logger().Info("creating agent API Key")
+		expiryDuration := c.Duration("api-key-expiration")
+		if expiryDuration > 0 {
+			expiry = time.Now().Add(expiryDuration)
+		}
+		apiKey, err = client.CreateAgentAPIKey(ctx, expiryDuration)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to obtain agent credentials: %w",
+				errors.Join(err, policyErr),
+			)
+		}
 	}
-	creds = &credentials{
+	creds := &credentials{
 		Expiry:      expiry,
 		APIKey:      apiKey,
 		SecretToken: secretToken,
@@ -126,5 +302,5 @@ func (cmd *Commands) getCredentials(ctx context.Context, c *cli.Command) (*crede
 	if err := updateCachedCredentials(cmd.cfg.APMServerURL, creds); err != nil {
 		return nil, err
 	}
-	return creds, nil
+	return selectCredentials(creds, mode)
 }
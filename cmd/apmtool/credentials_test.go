@@ -0,0 +1,249 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v3"
+)
+
+func TestSelectCredentials(t *testing.T) {
+	both := &credentials{APIKey: "api_key", SecretToken: "secret_token"}
+
+	creds, err := selectCredentials(both, authModeAuto)
+	require.NoError(t, err)
+	assert.Equal(t, both, creds)
+
+	creds, err = selectCredentials(both, authModeAPIKey)
+	require.NoError(t, err)
+	assert.Equal(t, &credentials{APIKey: "api_key"}, creds)
+
+	creds, err = selectCredentials(both, authModeSecretToken)
+	require.NoError(t, err)
+	assert.Equal(t, &credentials{SecretToken: "secret_token"}, creds)
+
+	_, err = selectCredentials(&credentials{SecretToken: "secret_token"}, authModeAPIKey)
+	assert.EqualError(t, err, "API Key auth requested, but no API Key is available")
+
+	_, err = selectCredentials(&credentials{APIKey: "api_key"}, authModeSecretToken)
+	assert.ErrorContains(t, err, "secret token auth requested, but no secret token is available")
+
+	_, err = selectCredentials(both, "bogus")
+	assert.EqualError(t, err, `invalid auth mode "bogus", expected one of: apikey, secret-token, auto`)
+}
+
+func TestGetCredentialsWithoutAuthFlag(t *testing.T) {
+	setTestCacheDir(t)
+
+	const apmServerURL = "http://apm.testing"
+	cached := &credentials{APIKey: "api_key", SecretToken: "secret_token"}
+	require.NoError(t, updateCachedCredentials(apmServerURL, cached))
+
+	commands := &Commands{}
+	commands.cfg.APMServerURL = apmServerURL
+	var creds *credentials
+	cmd := &cli.Command{
+		Name: "no-auth-flag",
+		Action: func(ctx context.Context, c *cli.Command) (err error) {
+			creds, err = commands.getCredentials(ctx, c)
+			return err
+		},
+	}
+	require.NoError(t, cmd.Run(context.Background(), []string{"no-auth-flag"}))
+	assert.Equal(t, cached, creds)
+}
+
+func TestGetCredentialsMinTTL(t *testing.T) {
+	setTestCacheDir(t)
+
+	var apiKeysCreated int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/.fleet-policies/_search":
+			w.Write([]byte(`{"hits":{"total":{"value":0,"relation":"eq"},"hits":[]}}`))
+		case "/_security/api_key":
+			apiKeysCreated++
+			json.NewEncoder(w).Encode(map[string]any{
+				"id": "id", "name": "apm-agent", "api_key": "secret", "encoded": "new_api_key",
+			})
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	const apmServerURL = "http://apm.testing"
+	commands := &Commands{}
+	commands.cfg.ElasticsearchURL = srv.URL
+	commands.cfg.APMServerURL = apmServerURL
+
+	getCredentials := func(args ...string) *credentials {
+		var creds *credentials
+		cmd := &cli.Command{
+			Name:  "get-credentials",
+			Flags: []cli.Flag{newMinTTLFlag(), newYesFlag()},
+			Action: func(ctx context.Context, c *cli.Command) (err error) {
+				creds, err = commands.getCredentials(ctx, c)
+				return err
+			},
+		}
+		require.NoError(t, cmd.Run(context.Background(), append([]string{"get-credentials", "--yes"}, args...)))
+		return creds
+	}
+
+	// Credentials expiring in 1 minute are within the default 5 minute
+	// minimum TTL, so a new API Key should be created.
+	expiring := &credentials{APIKey: "old_api_key", Expiry: time.Now().Add(time.Minute)}
+	require.NoError(t, updateCachedCredentials(apmServerURL, expiring))
+	creds := getCredentials()
+	assert.Equal(t, "new_api_key", creds.APIKey)
+	assert.Equal(t, 1, apiKeysCreated)
+
+	// With a smaller minimum TTL, the cached credentials are returned.
+	require.NoError(t, updateCachedCredentials(apmServerURL, expiring))
+	creds = getCredentials("--min-ttl=30s")
+	assert.Equal(t, "old_api_key", creds.APIKey)
+	assert.Equal(t, 1, apiKeysCreated)
+}
+
+func TestGetCredentialsConfirmCreateAPIKey(t *testing.T) {
+	var apiKeysCreated int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/.fleet-policies/_search":
+			w.Write([]byte(`{"hits":{"total":{"value":0,"relation":"eq"},"hits":[]}}`))
+		case "/_security/api_key":
+			apiKeysCreated++
+			json.NewEncoder(w).Encode(map[string]any{
+				"id": "id", "name": "apm-agent", "api_key": "secret", "encoded": "new_api_key",
+			})
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	for name, tc := range map[string]struct {
+		interactive bool
+		input       string
+		args        []string
+		expectErr   string
+		expectKey   bool
+	}{
+		"non_interactive": {
+			expectErr: "creating an agent API Key requires confirmation: specify --yes to create one non-interactively",
+		},
+		"non_interactive_yes": {
+			args:      []string{"--yes"},
+			expectKey: true,
+		},
+		"interactive_confirmed": {
+			interactive: true,
+			input:       "y\n",
+			expectKey:   true,
+		},
+		"interactive_declined": {
+			interactive: true,
+			input:       "n\n",
+			expectErr:   "agent API Key creation declined",
+		},
+		"interactive_no_answer": {
+			interactive: true,
+			expectErr:   "agent API Key creation declined",
+		},
+		"no_create_key": {
+			interactive: true,
+			input:       "y\n",
+			args:        []string{"--no-create-key"},
+			expectErr:   "no usable cached credentials, and --no-create-key specified",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			setTestCacheDir(t)
+			apiKeysCreated = 0
+
+			commands := &Commands{
+				isTerminal: func(io.Reader) bool { return tc.interactive },
+			}
+			commands.cfg.ElasticsearchURL = srv.URL
+			commands.cfg.APMServerURL = "http://apm.testing"
+
+			var prompt bytes.Buffer
+			var creds *credentials
+			cmd := &cli.Command{
+				Name:      "get-credentials",
+				Reader:    strings.NewReader(tc.input),
+				ErrWriter: &prompt,
+				Flags:     []cli.Flag{newYesFlag(), newNoCreateKeyFlag()},
+				Action: func(ctx context.Context, c *cli.Command) (err error) {
+					creds, err = commands.getCredentials(ctx, c)
+					return err
+				},
+			}
+			err := cmd.Run(context.Background(), append([]string{"get-credentials"}, tc.args...))
+			if tc.expectErr != "" {
+				assert.EqualError(t, err, tc.expectErr)
+				assert.Zero(t, apiKeysCreated)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, "new_api_key", creds.APIKey)
+				assert.Equal(t, 1, apiKeysCreated)
+			}
+			if tc.interactive && !slices.Contains(tc.args, "--no-create-key") {
+				assert.Contains(t, prompt.String(), "Create an agent API Key in "+srv.URL+"? [y/N] ")
+			} else {
+				assert.Empty(t, prompt.String())
+			}
+		})
+	}
+}
+
+func TestIsTerminal(t *testing.T) {
+	assert.False(t, isTerminal(strings.NewReader("y\n")))
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "input"))
+	require.NoError(t, err)
+	defer f.Close()
+	assert.False(t, isTerminal(f))
+}
+
+// setTestCacheDir sets the cache directory to a temporary
+// directory for the duration of the test.
+func setTestCacheDir(t *testing.T) {
+	origCacheDir := cacheDir
+	cacheDir = t.TempDir()
+	t.Cleanup(func() { cacheDir = origCacheDir })
+}
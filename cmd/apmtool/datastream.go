@@ -0,0 +1,58 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v3"
+)
+
+func (cmd *Commands) streamStatsCommand(ctx context.Context, c *cli.Command) error {
+	pattern := c.Args().First()
+	if pattern == "" {
+		return errors.New("data stream pattern must be specified")
+	}
+	client, err := cmd.getClient()
+	if err != nil {
+		return err
+	}
+	stats, err := client.DataStreamStats(ctx, pattern)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(c.Root().Writer, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "DATA STREAM\tBACKING INDICES\tDOCS")
+	for _, stat := range stats {
+		fmt.Fprintf(tw, "%s\t%d\t%d\n", stat.Name, stat.BackingIndices, stat.DocCount)
+	}
+	return tw.Flush()
+}
+
+// NewStreamStatsCmd returns pointer to a Command that prints the backing index and document counts of data streams
+func NewStreamStatsCmd(commands *Commands) *cli.Command {
+	return &cli.Command{
+		Name:      "stream-stats",
+		Usage:     "print the number of backing indices and documents of data streams matching a pattern",
+		ArgsUsage: "<pattern>",
+		Action:    commands.streamStatsCommand,
+	}
+}
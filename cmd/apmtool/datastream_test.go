@@ -0,0 +1,83 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v3"
+
+	"github.com/elastic/apm-tools/pkg/apmclient"
+)
+
+func TestStreamStats(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/_data_stream/metrics-apm*/_stats":
+			w.Write([]byte(`{
+			  "_shards": {"total": 3, "successful": 3, "failed": 0},
+			  "data_stream_count": 2,
+			  "backing_indices": 3,
+			  "total_store_size_bytes": 1024,
+			  "data_streams": [
+			    {"data_stream": "metrics-apm.internal-default", "backing_indices": 2, "store_size_bytes": 768, "maximum_timestamp": 1700000000000},
+			    {"data_stream": "metrics-apm.app.svc-default", "backing_indices": 1, "store_size_bytes": 256, "maximum_timestamp": 1700000000000}
+			  ]
+			}`))
+		case "/metrics-apm.internal-default/_count":
+			w.Write([]byte(`{"count": 1234, "_shards": {"total": 2, "successful": 2, "failed": 0}}`))
+		case "/metrics-apm.app.svc-default/_count":
+			w.Write([]byte(`{"count": 5, "_shards": {"total": 1, "successful": 1, "failed": 0}}`))
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	commands := &Commands{cfg: apmclient.Config{ElasticsearchURL: srv.URL}}
+	var out bytes.Buffer
+	cmd := &cli.Command{
+		Writer:   &out,
+		Commands: []*cli.Command{NewStreamStatsCmd(commands)},
+	}
+	err := cmd.Run(context.Background(), []string{"apmtool", "stream-stats", "metrics-apm*"})
+	require.NoError(t, err)
+	assert.Equal(t, ""+
+		"DATA STREAM                   BACKING INDICES  DOCS\n"+
+		"metrics-apm.app.svc-default   1                5\n"+
+		"metrics-apm.internal-default  2                1234\n",
+		out.String(),
+	)
+}
+
+func TestStreamStatsNoPattern(t *testing.T) {
+	cmd := &cli.Command{
+		Commands: []*cli.Command{NewStreamStatsCmd(&Commands{})},
+	}
+	err := cmd.Run(context.Background(), []string{"apmtool", "stream-stats"})
+	assert.EqualError(t, err, "data stream pattern must be specified")
+}
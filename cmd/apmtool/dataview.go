@@ -0,0 +1,111 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/elastic/apm-tools/pkg/kibanaclient"
+)
+
+func (cmd *Commands) createDataViewCommand(ctx context.Context, c *cli.Command) error {
+	client, err := cmd.kibanaClient()
+	if err != nil {
+		return err
+	}
+	id, created, err := createDataView(ctx, client, c.String("title"), c.String("time-field"))
+	if err != nil {
+		return err
+	}
+	if !created {
+		cmd.logger().Info("data view already exists", "title", c.String("title"), "id", id)
+	}
+	fmt.Fprintln(c.Root().Writer, id)
+	return nil
+}
+
+// dataView holds the fields of a Kibana data view used by apmtool.
+type dataView struct {
+	ID            string `json:"id,omitempty"`
+	Title         string `json:"title"`
+	TimeFieldName string `json:"timeFieldName,omitempty"`
+}
+
+// createDataView creates a Kibana data view matching the index pattern
+// title, returning its ID. If a data view with the same title already
+// exists, its ID is returned instead, and created is false.
+func createDataView(ctx context.Context, client *kibanaclient.Client, title, timeField string) (id string, created bool, err error) {
+	in := struct {
+		DataView dataView `json:"data_view"`
+	}{DataView: dataView{Title: title, TimeFieldName: timeField}}
+	var out struct {
+		DataView dataView `json:"data_view"`
+	}
+	err = client.Do(ctx, http.MethodPost, "/api/data_views/data_view", in, &out)
+	var kibanaErr *kibanaclient.Error
+	if errors.As(err, &kibanaErr) && kibanaErr.StatusCode == http.StatusConflict {
+		id, err := findDataView(ctx, client, title)
+		return id, false, err
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("error creating data view: %w", err)
+	}
+	return out.DataView.ID, true, nil
+}
+
+// findDataView returns the ID of the Kibana data view with the given title.
+func findDataView(ctx context.Context, client *kibanaclient.Client, title string) (string, error) {
+	var out struct {
+		DataViews []dataView `json:"data_view"`
+	}
+	if err := client.Do(ctx, http.MethodGet, "/api/data_views", nil, &out); err != nil {
+		return "", fmt.Errorf("error listing data views: %w", err)
+	}
+	for _, dv := range out.DataViews {
+		if dv.Title == title {
+			return dv.ID, nil
+		}
+	}
+	return "", fmt.Errorf("data view %q already exists, but was not found", title)
+}
+
+// NewCreateDataViewCmd returns pointer to a Command that creates a data view in Kibana
+func NewCreateDataViewCmd(commands *Commands) *cli.Command {
+	return &cli.Command{
+		Name:   "create-data-view",
+		Usage:  "create a data view in Kibana, printing its ID",
+		Action: commands.createDataViewCommand,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "title",
+				Required: true,
+				Usage:    "index pattern matched by the data view, e.g. traces-apm*",
+			},
+			&cli.StringFlag{
+				Name:  "time-field",
+				Value: "@timestamp",
+				Usage: "name of the data view's time field; empty for none",
+			},
+		},
+	}
+}
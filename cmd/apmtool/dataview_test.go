@@ -0,0 +1,96 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v3"
+
+	"github.com/elastic/apm-tools/pkg/apmclient"
+	"github.com/elastic/apm-tools/pkg/kibanaclient"
+)
+
+func TestCreateDataView(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/api/data_views/data_view", r.URL.Path)
+		assert.Equal(t, "1", r.Header.Get("kbn-xsrf"))
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		username, password, _ := r.BasicAuth()
+		assert.Equal(t, "elastic", username)
+		assert.Equal(t, "changeme", password)
+
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, map[string]any{
+			"data_view": map[string]any{
+				"title":         "traces-apm*",
+				"timeFieldName": "@timestamp",
+			},
+		}, body)
+		w.Write([]byte(`{"data_view":{"id":"abc123","title":"traces-apm*"}}`))
+	}))
+	defer srv.Close()
+
+	commands := &Commands{}
+	commands.cfg.KibanaURL = srv.URL
+	commands.cfg.Username = "elastic"
+	commands.cfg.Password = "changeme"
+	var out bytes.Buffer
+	cmd := &cli.Command{
+		Name:     "apmtool",
+		Writer:   &out,
+		Commands: []*cli.Command{NewCreateDataViewCmd(commands)},
+	}
+	err := cmd.Run(context.Background(), []string{"apmtool", "create-data-view", "--title", "traces-apm*"})
+	require.NoError(t, err)
+	assert.Equal(t, "abc123\n", out.String())
+}
+
+func TestCreateDataViewExists(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /api/data_views/data_view":
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"statusCode":409,"error":"Conflict","message":"Duplicate data view: logs-*"}`))
+		case "GET /api/data_views":
+			w.Write([]byte(`{"data_view":[{"id":"other","title":"traces-*"},{"id":"def456","title":"logs-*"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := kibanaclient.New(apmclient.Config{KibanaURL: srv.URL})
+	require.NoError(t, err)
+	id, created, err := createDataView(context.Background(), client, "logs-*", "")
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, "def456", id)
+
+	_, _, err = createDataView(context.Background(), client, "metrics-*", "")
+	assert.EqualError(t, err, `data view "metrics-*" already exists, but was not found`)
+}
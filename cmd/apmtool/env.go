@@ -20,32 +20,107 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
+	"strings"
 
 	"github.com/urfave/cli/v3"
 )
 
+// envVar holds the name and value of an environment variable.
+type envVar struct {
+	name  string
+	value string
+}
+
 func (cmd *Commands) envCommand(ctx context.Context, c *cli.Command) error {
+	target := c.String("target")
+	switch target {
+	case "elastic", "otlp", "both":
+	default:
+		return fmt.Errorf("invalid target %q, expected one of: elastic, otlp, both", target)
+	}
+	format := c.String("format")
+	if _, err := envFormatter(format); err != nil {
+		return err
+	}
+
 	creds, err := cmd.getCredentials(ctx, c)
 	if err != nil {
 		return err
 	}
+	return writeEnv(c.Root().Writer, format, agentEnvVars(cmd.cfg.APMServerURL, creds, target))
+}
 
-	fmt.Printf("export ELASTIC_APM_SERVER_URL=%q;\n", cmd.cfg.APMServerURL)
-	if creds.APIKey != "" {
-		fmt.Printf("export ELASTIC_APM_API_KEY=%q;\n", creds.APIKey)
-	} else if creds.SecretToken != "" {
-		fmt.Printf("export ELASTIC_APM_SECRET_TOKEN=%q;\n", creds.SecretToken)
+// agentEnvVars returns the environment variables for configuring agents
+// to send to serverURL with creds. The target must be one of "elastic"
+// for Elastic APM agents, "otlp" for OpenTelemetry SDKs, or "both".
+func agentEnvVars(serverURL string, creds *credentials, target string) []envVar {
+	var vars []envVar
+	if target == "elastic" || target == "both" {
+		vars = append(vars, envVar{"ELASTIC_APM_SERVER_URL", serverURL})
+		if creds.APIKey != "" {
+			vars = append(vars, envVar{"ELASTIC_APM_API_KEY", creds.APIKey})
+		} else if creds.SecretToken != "" {
+			vars = append(vars, envVar{"ELASTIC_APM_SECRET_TOKEN", creds.SecretToken})
+		}
+	}
+	if target == "otlp" || target == "both" {
+		vars = append(vars, envVar{"OTEL_EXPORTER_OTLP_ENDPOINT", serverURL})
+		if creds.APIKey != "" {
+			vars = append(vars, envVar{"OTEL_EXPORTER_OTLP_HEADERS", "Authorization=ApiKey " + creds.APIKey})
+		} else if creds.SecretToken != "" {
+			vars = append(vars, envVar{"OTEL_EXPORTER_OTLP_HEADERS", "Authorization=Bearer " + creds.SecretToken})
+		}
 	}
+	return vars
+}
 
-	fmt.Printf("export OTEL_EXPORTER_OTLP_ENDPOINT=%q;\n", cmd.cfg.APMServerURL)
-	if creds.APIKey != "" {
-		fmt.Printf("export OTEL_EXPORTER_OTLP_HEADERS=%q;\n", "Authorization=ApiKey "+creds.APIKey)
-	} else if creds.SecretToken != "" {
-		fmt.Printf("export OTEL_EXPORTER_OTLP_HEADERS=%q;\n", "Authorization=Bearer "+creds.SecretToken)
+// writeEnv writes vars to w, one per line, in the given format.
+func writeEnv(w io.Writer, format string, vars []envVar) error {
+	formatter, err := envFormatter(format)
+	if err != nil {
+		return err
+	}
+	for _, v := range vars {
+		if _, err := fmt.Fprintln(w, formatter(v)); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
+var (
+	shQuoter         = strings.NewReplacer(`'`, `'\''`)
+	fishQuoter       = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+	powershellQuoter = strings.NewReplacer(`'`, `''`)
+	dotenvQuoter     = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+)
+
+// envFormatter returns a function formatting an environment variable
+// assignment for the given format: sh, fish, powershell, or dotenv.
+// Values are quoted so that they are taken literally.
+func envFormatter(format string) (func(envVar) string, error) {
+	switch format {
+	case "", "sh":
+		return func(v envVar) string {
+			return fmt.Sprintf("export %s='%s';", v.name, shQuoter.Replace(v.value))
+		}, nil
+	case "fish":
+		return func(v envVar) string {
+			return fmt.Sprintf("set -gx %s '%s';", v.name, fishQuoter.Replace(v.value))
+		}, nil
+	case "powershell":
+		return func(v envVar) string {
+			return fmt.Sprintf("$env:%s = '%s'", v.name, powershellQuoter.Replace(v.value))
+		}, nil
+	case "dotenv":
+		return func(v envVar) string {
+			return fmt.Sprintf(`%s="%s"`, v.name, dotenvQuoter.Replace(v.value))
+		}, nil
+	}
+	return nil, fmt.Errorf("invalid format %q, expected one of: sh, fish, powershell, dotenv", format)
+}
+
 // NewPrintEnvCmd returns pointer to a Command that prints environment variables for configuring Elastic APM agent
 func NewPrintEnvCmd(commands *Commands) *cli.Command {
 	return &cli.Command{
@@ -57,6 +132,20 @@ func NewPrintEnvCmd(commands *Commands) *cli.Command {
 				Name:  "api-key-expiration",
 				Usage: "specify how long before a created API Key expires. 0 means it never expires.",
 			},
+			&cli.StringFlag{
+				Name:  "target",
+				Usage: "set the agents to print variables for to one of: elastic, otlp, both (default)",
+				Value: "both",
+			},
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "set the output format to one of: sh (default), fish, powershell, dotenv",
+				Value: "sh",
+			},
+			newAuthFlag(),
+			newMinTTLFlag(),
+			newYesFlag(),
+			newNoCreateKeyFlag(),
 		},
 	}
 }
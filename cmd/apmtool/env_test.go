@@ -0,0 +1,108 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgentEnvVars(t *testing.T) {
+	apiKey := &credentials{APIKey: "key"}
+	secretToken := &credentials{SecretToken: "token"}
+	for _, tc := range []struct {
+		target   string
+		creds    *credentials
+		expected []envVar
+	}{{
+		target: "elastic",
+		creds:  apiKey,
+		expected: []envVar{
+			{"ELASTIC_APM_SERVER_URL", "http://apm"},
+			{"ELASTIC_APM_API_KEY", "key"},
+		},
+	}, {
+		target: "elastic",
+		creds:  secretToken,
+		expected: []envVar{
+			{"ELASTIC_APM_SERVER_URL", "http://apm"},
+			{"ELASTIC_APM_SECRET_TOKEN", "token"},
+		},
+	}, {
+		target: "otlp",
+		creds:  apiKey,
+		expected: []envVar{
+			{"OTEL_EXPORTER_OTLP_ENDPOINT", "http://apm"},
+			{"OTEL_EXPORTER_OTLP_HEADERS", "Authorization=ApiKey key"},
+		},
+	}, {
+		target: "otlp",
+		creds:  secretToken,
+		expected: []envVar{
+			{"OTEL_EXPORTER_OTLP_ENDPOINT", "http://apm"},
+			{"OTEL_EXPORTER_OTLP_HEADERS", "Authorization=Bearer token"},
+		},
+	}, {
+		target: "both",
+		creds:  apiKey,
+		expected: []envVar{
+			{"ELASTIC_APM_SERVER_URL", "http://apm"},
+			{"ELASTIC_APM_API_KEY", "key"},
+			{"OTEL_EXPORTER_OTLP_ENDPOINT", "http://apm"},
+			{"OTEL_EXPORTER_OTLP_HEADERS", "Authorization=ApiKey key"},
+		},
+	}, {
+		target: "both",
+		creds:  &credentials{},
+		expected: []envVar{
+			{"ELASTIC_APM_SERVER_URL", "http://apm"},
+			{"OTEL_EXPORTER_OTLP_ENDPOINT", "http://apm"},
+		},
+	}} {
+		assert.Equal(t, tc.expected, agentEnvVars("http://apm", tc.creds, tc.target), tc.target)
+	}
+}
+
+func TestWriteEnv(t *testing.T) {
+	vars := []envVar{
+		{"ELASTIC_APM_SERVER_URL", "http://apm"},
+		{"OTEL_EXPORTER_OTLP_HEADERS", `Authorization=ApiKey a'b"c\d$e`},
+	}
+	for format, expected := range map[string]string{
+		"sh": `export ELASTIC_APM_SERVER_URL='http://apm';` + "\n" +
+			`export OTEL_EXPORTER_OTLP_HEADERS='Authorization=ApiKey a'\''b"c\d$e';` + "\n",
+		"fish": `set -gx ELASTIC_APM_SERVER_URL 'http://apm';` + "\n" +
+			`set -gx OTEL_EXPORTER_OTLP_HEADERS 'Authorization=ApiKey a\'b"c\\d$e';` + "\n",
+		"powershell": `$env:ELASTIC_APM_SERVER_URL = 'http://apm'` + "\n" +
+			`$env:OTEL_EXPORTER_OTLP_HEADERS = 'Authorization=ApiKey a''b"c\d$e'` + "\n",
+		"dotenv": `ELASTIC_APM_SERVER_URL="http://apm"` + "\n" +
+			`OTEL_EXPORTER_OTLP_HEADERS="Authorization=ApiKey a'b\"c\\d$e"` + "\n",
+	} {
+		var buf bytes.Buffer
+		require.NoError(t, writeEnv(&buf, format, vars), format)
+		assert.Equal(t, expected, buf.String(), format)
+	}
+
+	var buf bytes.Buffer
+	err := writeEnv(&buf, "cmd", vars)
+	assert.EqualError(t, err, `invalid format "cmd", expected one of: sh, fish, powershell, dotenv`)
+	assert.Empty(t, buf.String())
+}
@@ -0,0 +1,89 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/urfave/cli/v3"
+)
+
+func (cmd *Commands) listErrorsCommand(ctx context.Context, c *cli.Command) error {
+	service := c.String("service")
+	if service == "" {
+		return errors.New("--service must be specified")
+	}
+	output := c.String("output")
+	switch output {
+	case "text", "json":
+	default:
+		return fmt.Errorf("invalid output format %q, expected one of: text, json", output)
+	}
+	client, err := cmd.getClient()
+	if err != nil {
+		return err
+	}
+	apmErrors, err := client.RecentErrors(ctx, service, int(c.Uint("limit")))
+	if err != nil {
+		return err
+	}
+	w := c.Root().Writer
+	if output == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(apmErrors)
+	}
+	for _, apmError := range apmErrors {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+			apmError.Timestamp.Format(time.RFC3339Nano),
+			apmError.ID, apmError.GroupingKey, apmError.Message,
+		)
+	}
+	return nil
+}
+
+// NewListErrorsCmd returns pointer to a Command that lists the most recent errors of an APM service
+func NewListErrorsCmd(commands *Commands) *cli.Command {
+	return &cli.Command{
+		Name:   "list-errors",
+		Usage:  "list the most recent errors of an APM service",
+		Action: commands.listErrorsCommand,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "service",
+				Usage:    "name of the service whose errors are listed",
+				Required: true,
+			},
+			&cli.UintFlag{
+				Name:  "limit",
+				Value: 10,
+				Usage: "maximum number of errors to list",
+			},
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Value:   "text",
+				Usage:   "output format: text or json",
+			},
+		},
+	}
+}
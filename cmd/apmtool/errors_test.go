@@ -0,0 +1,60 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v3"
+
+	"github.com/elastic/apm-tools/pkg/apmclient"
+)
+
+func TestListErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/logs-apm.error-*/_search", r.URL.Path)
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+		  "hits": {
+		    "total": {"value": 1, "relation": "eq"},
+		    "hits": [{"_index": "logs-apm.error-default", "_id": "1", "_source": {
+		      "@timestamp": "2024-05-01T10:00:02Z",
+		      "error": {"id": "err1", "grouping_key": "group1", "exception": [{"message": "boom"}]}
+		    }}]
+		  }
+		}`))
+	}))
+	defer srv.Close()
+
+	commands := &Commands{cfg: apmclient.Config{ElasticsearchURL: srv.URL}}
+	var out bytes.Buffer
+	cmd := &cli.Command{
+		Writer:   &out,
+		Commands: []*cli.Command{NewListErrorsCmd(commands)},
+	}
+	err := cmd.Run(context.Background(), []string{"apmtool", "list-errors", "--service", "frontend", "--limit", "1"})
+	require.NoError(t, err)
+	assert.Equal(t, "2024-05-01T10:00:02Z\terr1\tgroup1\tboom\n", out.String())
+}
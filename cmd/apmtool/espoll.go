@@ -18,14 +18,12 @@
 package main
 
 import (
+	"bytes"
 	"context"
-	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"log"
-	"net/http"
 	"os"
 	"os/signal"
 	"strings"
@@ -34,11 +32,8 @@ import (
 	"github.com/urfave/cli/v3"
 
 	"github.com/elastic/apm-tools/pkg/espoll"
-	"github.com/elastic/go-elasticsearch/v8"
 )
 
-var maxElasticsearchBackoff = 10 * time.Second
-
 type config struct {
 	query      string
 	esURL      string
@@ -50,48 +45,112 @@ type config struct {
 	target  string
 	timeout time.Duration
 	hits    uint64
+	sort    []string
+	output  string
+
+	// expandWildcards, if non-empty, overrides the index
+	// types that wildcard targets are expanded to.
+	expandWildcards string
+
+	// noRefresh skips refreshing the target before searching,
+	// using eventual read consistency.
+	noRefresh bool
+
+	// countOnly counts the matching documents rather than searching,
+	// writing just the count to stdout.
+	countOnly bool
+
+	// stdout receives the result. Defaults to os.Stdout.
+	stdout io.Writer
+
+	// dumpRequest, if non-nil, receives each request
+	// to Elasticsearch before it is first sent.
+	dumpRequest io.Writer
 }
 
 func (cmd *Commands) pollDocs(ctx context.Context, c *cli.Command) error {
+	query, err := readQuery(c.String("query"), c.String("query-file"), os.Stdin)
+	if err != nil {
+		return err
+	}
+	sort, err := parseSort(c.StringSlice("sort"))
+	if err != nil {
+		return err
+	}
 	cfg := config{
-		query:      c.String("query"),
+		query:      query,
 		esURL:      cmd.cfg.ElasticsearchURL,
 		esUsername: cmd.cfg.Username,
 		esPassword: cmd.cfg.Password,
 
-		tlsSkipVerify: cmd.cfg.TLSSkipVerify,
+		tlsSkipVerify: cmd.cfg.ElasticsearchTLSSkipVerify(),
 
 		target:  c.String("target"),
 		timeout: c.Duration("timeout"),
 		hits:    c.Uint("min-hits"),
-	}
-	query := c.String("query")
-	if query == "" {
-		stat, err := os.Stdin.Stat()
-		if err != nil {
-			log.Fatalf("failed to stat stdin: %s", err.Error())
-		}
-		if stat.Size() == 0 {
-			log.Fatal("empty -query flag and stdin, please set one.")
-		}
+		sort:    sort,
+		output:  c.String("output"),
 
-		b, err := io.ReadAll(os.Stdin)
-		if err != nil {
-			log.Fatalf("failed to read stdin: %s", err.Error())
-		}
-		query = string(strings.Trim(string(b), "\n"))
+		expandWildcards: c.String("expand-wildcards"),
+		noRefresh:       c.Bool("no-refresh"),
+		countOnly:       c.Bool("count-only"),
+
+		stdout: c.Root().Writer,
+	}
+	if c.Bool("dump-request") {
+		cfg.dumpRequest = c.Root().ErrWriter
 	}
 
-	log.Println("query:", query)
+	cmd.logger().Debug("polling Elasticsearch", "target", cfg.target, "query", query)
 
 	ctxMain, cancel := signal.NotifyContext(ctx, os.Interrupt, os.Kill)
 	defer cancel()
 
-	if err := Main(ctxMain, cfg); err != nil {
-		log.Fatalf("ERROR: %s", err.Error())
+	return Main(ctxMain, cfg)
+}
+
+// readQuery returns the query from exactly one of the query flag,
+// the file named by the query-file flag, or stdin if it is non-empty.
+func readQuery(query, queryFile string, stdin *os.File) (string, error) {
+	var sources []string
+	if query != "" {
+		sources = append(sources, "--query")
+	}
+	if queryFile != "" {
+		sources = append(sources, "--query-file")
+	}
+	var stdinSet bool
+	if stat, err := stdin.Stat(); err == nil && stat.Size() > 0 {
+		stdinSet = true
+		sources = append(sources, "stdin")
+	}
+	switch len(sources) {
+	case 0:
+		return "", errors.New("query must be set via --query, --query-file, or stdin")
+	case 1:
+	default:
+		return "", fmt.Errorf("query must be set via only one of --query, --query-file, or stdin; got %s", strings.Join(sources, ", "))
 	}
 
-	return nil
+	var r io.Reader
+	switch {
+	case query != "":
+		return query, nil
+	case stdinSet:
+		r = stdin
+	default:
+		f, err := os.Open(queryFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to open query file: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("failed to read query: %w", err)
+	}
+	return strings.Trim(string(b), "\n"), nil
 }
 
 // NewESPollCmd returns pointer to Command that queries documents from Elasticsearch
@@ -103,7 +162,11 @@ func NewESPollCmd(commands *Commands) *cli.Command {
 		Flags: []cli.Flag{
 			&cli.StringFlag{
 				Name:  "query",
-				Usage: "The Elasticsearch query in Query DSL. Must be set via this flag or stdin.",
+				Usage: "The Elasticsearch query in Query DSL. Must be set via this flag, --query-file, or stdin.",
+			},
+			&cli.StringFlag{
+				Name:  "query-file",
+				Usage: "File containing the Elasticsearch query in Query DSL.",
 			},
 			&cli.StringFlag{
 				Name:  "target",
@@ -120,6 +183,31 @@ func NewESPollCmd(commands *Commands) *cli.Command {
 				Value: 1,
 				Usage: "When specified and > 10, this should cause the size parameter to be set.",
 			},
+			&cli.StringSliceFlag{
+				Name:  "sort",
+				Usage: "Sort hits by field:direction, where direction is asc or desc (e.g. @timestamp:desc). May be repeated.",
+			},
+			&cli.StringFlag{
+				Name:  "output",
+				Value: "result",
+				Usage: "Output mode: result (the full search result), or hits (the _source of each hit, one per line).",
+			},
+			&cli.StringFlag{
+				Name:  "expand-wildcards",
+				Usage: "Comma-separated index types that wildcard targets expand to: open, closed, hidden, none, or all. Defaults to open,hidden for searches and all for refreshes.",
+			},
+			&cli.BoolFlag{
+				Name:  "no-refresh",
+				Usage: "Skip refreshing the target before searching, and use the request cache. Faster for immutable data, but recently indexed documents may not be found.",
+			},
+			&cli.BoolFlag{
+				Name:  "count-only",
+				Usage: "Print just the number of matching documents, failing if fewer than --min-hits match before the timeout.",
+			},
+			&cli.BoolFlag{
+				Name:  "dump-request",
+				Usage: "Write each request to stderr before sending it, with authorization redacted",
+			},
 		},
 	}
 }
@@ -128,37 +216,98 @@ func Main(ctx context.Context, cfg config) error {
 	if cfg.query == "" {
 		return errors.New("query cannot be empty")
 	}
+	switch cfg.output {
+	case "result", "hits":
+	default:
+		return fmt.Errorf("invalid output %q, expected one of: result, hits", cfg.output)
+	}
+	if cfg.expandWildcards != "" {
+		if err := espoll.ValidateExpandWildcards(cfg.expandWildcards); err != nil {
+			return err
+		}
+	}
 
-	transport := http.DefaultTransport.(*http.Transport).Clone()
-	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: cfg.tlsSkipVerify}
-
-	client, err := elasticsearch.NewClient(elasticsearch.Config{
-		Username:   cfg.esUsername,
-		Password:   cfg.esPassword,
-		Addresses:  strings.Split(cfg.esURL, ","),
-		Transport:  transport,
-		MaxRetries: 5,
-		RetryBackoff: func(attempt int) time.Duration {
-			backoff := (500 * time.Millisecond) * (1 << (attempt - 1))
-			if backoff > maxElasticsearchBackoff {
-				backoff = maxElasticsearchBackoff
-			}
-			return backoff
-		},
+	esClient, err := espoll.NewClient(espoll.ClientConfig{
+		Addresses:     strings.Split(cfg.esURL, ","),
+		Username:      cfg.esUsername,
+		Password:      cfg.esPassword,
+		TLSSkipVerify: cfg.tlsSkipVerify,
 	})
 	if err != nil {
 		return err
 	}
-	esClient := espoll.WrapClient(client)
-	result, err := esClient.SearchIndexMinDocs(ctx,
-		int(cfg.hits), cfg.target, stringMarshaler(cfg.query),
+	opts := []espoll.RequestOption{
 		espoll.WithTimeout(cfg.timeout),
+		espoll.WithSort(cfg.sort...),
+	}
+	if cfg.dumpRequest != nil {
+		opts = append(opts, espoll.WithRequestDump(cfg.dumpRequest))
+	}
+	if cfg.expandWildcards != "" {
+		opts = append(opts, espoll.WithExpandWildcards(cfg.expandWildcards))
+	}
+	if cfg.noRefresh {
+		opts = append(opts, espoll.WithReadConsistency(espoll.ReadConsistencyEventual))
+	}
+	stdout := cfg.stdout
+	if stdout == nil {
+		stdout = os.Stdout
+	}
+	if cfg.countOnly {
+		count, err := esClient.CountIndexMinDocs(ctx,
+			int(cfg.hits), cfg.target, stringMarshaler(cfg.query), opts...,
+		)
+		if err != nil {
+			return fmt.Errorf("count request returned error: %w", err)
+		}
+		_, err = fmt.Fprintln(stdout, count)
+		return err
+	}
+	result, err := esClient.SearchIndexMinDocs(ctx,
+		int(cfg.hits), cfg.target, stringMarshaler(cfg.query), opts...,
 	)
 	if err != nil {
 		return fmt.Errorf("search request returned error: %w", err)
 	}
 
-	if err := json.NewEncoder(os.Stdout).Encode(result); err != nil {
+	return writeResult(stdout, result, cfg.output)
+}
+
+// parseSort validates a list of field:direction sort values.
+func parseSort(values []string) ([]string, error) {
+	for _, v := range values {
+		field, direction, ok := strings.Cut(v, ":")
+		if !ok || field == "" {
+			return nil, fmt.Errorf("invalid sort %q, expected field:direction", v)
+		}
+		switch direction {
+		case "asc", "desc":
+		default:
+			return nil, fmt.Errorf("invalid sort direction %q, expected asc or desc", direction)
+		}
+	}
+	return values, nil
+}
+
+// writeResult writes result to w according to the output mode: either
+// the full result as a single JSON object, or the _source of each hit
+// on its own line.
+func writeResult(w io.Writer, result espoll.SearchResult, output string) error {
+	if output == "hits" {
+		for _, hit := range result.Hits.Hits {
+			// Write the raw _source to preserve field types.
+			var buf bytes.Buffer
+			if err := json.Compact(&buf, hit.RawSource); err != nil {
+				return fmt.Errorf("failed to encode hit %q: %w", hit.ID, err)
+			}
+			buf.WriteByte('\n')
+			if _, err := buf.WriteTo(w); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := json.NewEncoder(w).Encode(result); err != nil {
 		return fmt.Errorf("failed to encode search result: %w", err)
 	}
 	return nil
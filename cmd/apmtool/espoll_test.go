@@ -0,0 +1,261 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/apm-tools/pkg/espoll"
+)
+
+// newTestStdin returns a file holding content, for use as stdin.
+func newTestStdin(t *testing.T, content string) *os.File {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "stdin")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func TestReadQuery(t *testing.T) {
+	queryFile := filepath.Join(t.TempDir(), "query.json")
+	require.NoError(t, os.WriteFile(queryFile, []byte("{\n  \"match_all\": {}\n}\n"), 0644))
+
+	query, err := readQuery("", queryFile, newTestStdin(t, ""))
+	require.NoError(t, err)
+	assert.Equal(t, "{\n  \"match_all\": {}\n}", query)
+
+	query, err = readQuery(`{"match_all":{}}`, "", newTestStdin(t, ""))
+	require.NoError(t, err)
+	assert.Equal(t, `{"match_all":{}}`, query)
+
+	query, err = readQuery("", "", newTestStdin(t, "{\"term\":{}}\n"))
+	require.NoError(t, err)
+	assert.Equal(t, `{"term":{}}`, query)
+
+	_, err = readQuery("", "", newTestStdin(t, ""))
+	assert.EqualError(t, err, "query must be set via --query, --query-file, or stdin")
+
+	_, err = readQuery(`{"match_all":{}}`, queryFile, newTestStdin(t, ""))
+	assert.EqualError(t, err, "query must be set via only one of --query, --query-file, or stdin; got --query, --query-file")
+
+	_, err = readQuery("", queryFile, newTestStdin(t, "{}"))
+	assert.EqualError(t, err, "query must be set via only one of --query, --query-file, or stdin; got --query-file, stdin")
+
+	_, err = readQuery("", filepath.Join(t.TempDir(), "missing.json"), newTestStdin(t, ""))
+	assert.ErrorContains(t, err, "failed to open query file")
+}
+
+func TestWriteResult(t *testing.T) {
+	var result espoll.SearchResult
+	require.NoError(t, json.Unmarshal([]byte(`{
+	  "hits": {
+	    "total": {"value": 2, "relation": "eq"},
+	    "hits": [
+	      {"_index": "a", "_id": "1", "_source": {"big": 12345678901234567890, "s": "x"}, "fields": {}},
+	      {"_index": "a", "_id": "2", "_source": {"f": 1.50}, "fields": {}}
+	    ]
+	  }
+	}`), &result))
+
+	var buf bytes.Buffer
+	require.NoError(t, writeResult(&buf, result, "hits"))
+	assert.Equal(t, `{"big":12345678901234567890,"s":"x"}`+"\n"+`{"f":1.50}`+"\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, writeResult(&buf, result, "result"))
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Contains(t, decoded, "hits")
+	assert.Equal(t, 1, bytes.Count(buf.Bytes(), []byte("\n")))
+}
+
+func TestParseSort(t *testing.T) {
+	sort, err := parseSort([]string{"@timestamp:desc", "trace.id:asc"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"@timestamp:desc", "trace.id:asc"}, sort)
+
+	for _, invalid := range []string{"@timestamp", ":desc", "@timestamp:down"} {
+		_, err := parseSort([]string{invalid})
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestMainSort(t *testing.T) {
+	var sort []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		if r.URL.Path == "/traces-*/_search" {
+			sort = r.URL.Query()["sort"]
+			w.Write([]byte(`{"hits":{"total":{"value":1,"relation":"eq"},"hits":[{"_source":{},"fields":{}}]}}`))
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	err := Main(context.Background(), config{
+		query:   `{"match_all":{}}`,
+		esURL:   srv.URL,
+		target:  "traces-*",
+		timeout: 10 * time.Second,
+		hits:    1,
+		sort:    []string{"@timestamp:desc", "trace.id:asc"},
+		output:  "hits",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"@timestamp:desc,trace.id:asc"}, sort)
+}
+
+func TestMainExpandWildcards(t *testing.T) {
+	var expandWildcards []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		expandWildcards = append(expandWildcards, r.URL.Path+" "+r.URL.Query().Get("expand_wildcards"))
+		if r.URL.Path == "/traces-*/_search" {
+			w.Write([]byte(`{"hits":{"total":{"value":1,"relation":"eq"},"hits":[{"_source":{},"fields":{}}]}}`))
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	cfg := config{
+		query:   `{"match_all":{}}`,
+		esURL:   srv.URL,
+		target:  "traces-*",
+		timeout: 10 * time.Second,
+		hits:    1,
+		output:  "hits",
+
+		expandWildcards: "open,closed",
+	}
+	require.NoError(t, Main(context.Background(), cfg))
+	assert.Equal(t, []string{
+		"/traces-*/_refresh open,closed",
+		"/traces-*/_search open,closed",
+	}, expandWildcards)
+
+	cfg.expandWildcards = "open,invalid"
+	err := Main(context.Background(), cfg)
+	assert.EqualError(t, err, `invalid expand_wildcards "open,invalid", expected a comma-separated list of: open, closed, hidden, none, all`)
+}
+
+func TestMainNoRefresh(t *testing.T) {
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		requests = append(requests, r.URL.Path+" "+r.URL.Query().Get("request_cache"))
+		w.Write([]byte(`{"hits":{"total":{"value":1,"relation":"eq"},"hits":[{"_source":{},"fields":{}}]}}`))
+	}))
+	defer srv.Close()
+
+	cfg := config{
+		query:   `{"match_all":{}}`,
+		esURL:   srv.URL,
+		target:  "traces-*",
+		timeout: 10 * time.Second,
+		hits:    1,
+		output:  "hits",
+
+		noRefresh: true,
+	}
+	require.NoError(t, Main(context.Background(), cfg))
+	assert.Equal(t, []string{"/traces-*/_search true"}, requests)
+}
+
+func TestMainCountOnly(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		paths = append(paths, r.URL.Path)
+		if r.URL.Path == "/traces-*/_count" {
+			w.Write([]byte(`{"count":3}`))
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	var stdout bytes.Buffer
+	cfg := config{
+		query:   `{"match_all":{}}`,
+		esURL:   srv.URL,
+		target:  "traces-*",
+		timeout: 10 * time.Second,
+		hits:    2,
+		output:  "result",
+
+		countOnly: true,
+		stdout:    &stdout,
+	}
+	require.NoError(t, Main(context.Background(), cfg))
+	assert.Equal(t, "3\n", stdout.String())
+	assert.Equal(t, []string{"/traces-*/_refresh", "/traces-*/_count"}, paths)
+
+	// Fewer documents than the minimum match within the timeout.
+	stdout.Reset()
+	cfg.hits = 5
+	cfg.timeout = 100 * time.Millisecond
+	err := Main(context.Background(), cfg)
+	assert.ErrorContains(t, err, "count request returned error")
+	assert.Empty(t, stdout.String())
+}
+
+func TestMainDumpRequest(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		if r.URL.Path == "/traces-*/_search" {
+			w.Write([]byte(`{"hits":{"total":{"value":1,"relation":"eq"},"hits":[{"_source":{},"fields":{}}]}}`))
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	var dump bytes.Buffer
+	err := Main(context.Background(), config{
+		query:       `{"match_all":{}}`,
+		esURL:       srv.URL,
+		esUsername:  "elastic",
+		esPassword:  "hunter2",
+		target:      "traces-*",
+		timeout:     10 * time.Second,
+		hits:        1,
+		output:      "hits",
+		dumpRequest: &dump,
+	})
+	require.NoError(t, err)
+	assert.Contains(t, dump.String(), "POST "+srv.URL+"/traces-*/_search?expand_wildcards=open%2Chidden\n")
+	assert.Contains(t, dump.String(), "\nAuthorization: [REDACTED]\n")
+	assert.Contains(t, dump.String(), `{"query":{"match_all":{}},"fields":["*"]}`)
+	assert.NotContains(t, dump.String(), "hunter2")
+}
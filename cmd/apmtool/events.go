@@ -19,52 +19,102 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/urfave/cli/v3"
+
+	"github.com/elastic/apm-tools/pkg/apmclient"
+	"github.com/elastic/apm-tools/pkg/espoll"
+	"github.com/elastic/apm-tools/pkg/intake"
 )
 
 func (cmd *Commands) sendEventsCommand(ctx context.Context, c *cli.Command) error {
+	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt)
+	defer cancel()
+
+	filenames, err := expandFilePatterns(c.StringSlice("file"))
+	if err != nil {
+		return err
+	}
+	if c.Bool("dry-run") {
+		return validateEvents(c.Root().Writer, filenames)
+	}
+
 	creds, err := cmd.getCredentials(ctx, c)
 	if err != nil {
 		return err
 	}
 
+	if wait := c.Duration("wait"); wait > 0 {
+		client, err := cmd.getClient()
+		if err != nil {
+			return err
+		}
+		if err := waitForAPMServer(ctx, client, creds, wait); err != nil {
+			return err
+		}
+	}
+
+	urlPath := "/intake/v2/events"
+	if c.Bool("rumv2") {
+		urlPath = "/intake/v2/rum/events"
+	}
+
+	client := cmd.apmServerHTTPClient()
+	if c.Bool("dump-request") {
+		client.Transport = espoll.NewRequestDumper(client.Transport, c.Root().ErrWriter)
+	}
+
+	if len(filenames) > 1 {
+		concurrency := int(c.Uint("concurrency"))
+		if concurrency < 1 {
+			return fmt.Errorf("invalid concurrency %d, must be > 0", concurrency)
+		}
+		return cmd.sendEventsFiles(ctx, c.Root().Writer, client, urlPath, creds, filenames, concurrency)
+	}
+
 	var body io.Reader
-	filename := c.String("file")
-	if filename == "" {
+	if len(filenames) == 0 {
 		stat, err := os.Stdin.Stat()
 		if err != nil {
-			log.Fatalf("failed to stat stdin: %s", err.Error())
+			return fmt.Errorf("failed to stat stdin: %w", err)
 		}
 		if stat.Size() == 0 {
-			log.Fatal("empty -file flag and stdin, please set one.")
+			return errors.New("empty --file flag and stdin, please set one")
 		}
 		body = io.NopCloser(os.Stdin)
 	} else {
-		f, err := os.Open(filename)
+		f, err := os.Open(filenames[0])
 		if err != nil {
 			return fmt.Errorf("error opening file: %w", err)
 		}
 		defer f.Close()
 		body = f
 	}
+	resp, err := cmd.sendEvents(ctx, client, urlPath, creds, body)
+	cmd.logger().Info("APM Server response", "body", string(resp))
+	return err
+}
 
-	urlPath := "/intake/v2/events"
-	if c.Bool("rumv2") {
-		urlPath = "/intake/v2/rum/events"
-	}
-	req, err := http.NewRequest(
+// sendEvents posts the ND-JSON events in body to APM Server using
+// client, returning the response body.
+func (cmd *Commands) sendEvents(ctx context.Context, client *http.Client, urlPath string, creds *credentials, body io.Reader) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx,
 		http.MethodPost,
 		cmd.cfg.APMServerURL+urlPath+"?verbose",
 		body,
 	)
 	if err != nil {
-		return fmt.Errorf("error creating HTTP request: %w", err)
+		return nil, fmt.Errorf("error creating HTTP request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/x-ndjson")
 
@@ -75,18 +125,154 @@ func (cmd *Commands) sendEventsCommand(ctx context.Context, c *cli.Command) erro
 		req.Header.Set("Authorization", "ApiKey "+creds.APIKey)
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
-		return fmt.Errorf("error performing HTTP request: %w", err)
+		return nil, fmt.Errorf("error performing HTTP request: %w", err)
 	}
 	defer resp.Body.Close()
-	io.Copy(os.Stderr, resp.Body)
+	respBody, err := io.ReadAll(resp.Body)
 	if resp.StatusCode != http.StatusAccepted {
-		return fmt.Errorf("error sending events; server responded with %q", resp.Status)
+		return respBody, fmt.Errorf("error sending events; server responded with %q", resp.Status)
+	}
+	if err != nil {
+		return respBody, fmt.Errorf("error reading response: %w", err)
+	}
+	return respBody, nil
+}
+
+// sendEventsFiles sends each of the named files in a separate request,
+// using up to concurrency requests at a time, and then writes a summary
+// of the results to w. An error is returned if any file fails to send.
+func (cmd *Commands) sendEventsFiles(
+	ctx context.Context, w io.Writer, client *http.Client,
+	urlPath string, creds *credentials,
+	filenames []string, concurrency int,
+) error {
+	errs := make([]error, len(filenames))
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < min(concurrency, len(filenames)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				resp, err := cmd.sendEventsFile(ctx, client, urlPath, creds, filenames[i])
+				errs[i] = err
+				cmd.logger().Info("APM Server response", "file", filenames[i], "body", string(resp))
+			}
+		}()
+	}
+	for i := range filenames {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+
+	var failed int
+	for i, filename := range filenames {
+		if errs[i] != nil {
+			failed++
+			fmt.Fprintf(w, "FAILED %s: %s\n", filename, errs[i])
+		} else {
+			fmt.Fprintf(w, "OK     %s\n", filename)
+		}
+	}
+	fmt.Fprintf(w, "Sent %d files: %d succeeded, %d failed\n", len(filenames), len(filenames)-failed, failed)
+	if failed > 0 {
+		return fmt.Errorf("failed to send %d of %d files", failed, len(filenames))
 	}
 	return nil
 }
 
+func (cmd *Commands) sendEventsFile(ctx context.Context, client *http.Client, urlPath string, creds *credentials, filename string) ([]byte, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error opening file: %w", err)
+	}
+	defer f.Close()
+	return cmd.sendEvents(ctx, client, urlPath, creds, f)
+}
+
+// validateEvents checks the events in the named files, or stdin if
+// there are none, writing any invalid lines to w without sending
+// the events. An error is returned if any line is invalid.
+func validateEvents(w io.Writer, filenames []string) error {
+	var invalid int
+	validate := func(name string, r io.Reader) {
+		for _, err := range intake.ValidateIntakeNDJSON(r) {
+			invalid++
+			fmt.Fprintf(w, "%s: %s\n", name, err)
+		}
+	}
+	if len(filenames) == 0 {
+		validate("stdin", os.Stdin)
+	}
+	for _, filename := range filenames {
+		f, err := os.Open(filename)
+		if err != nil {
+			return fmt.Errorf("error opening file: %w", err)
+		}
+		validate(filename, f)
+		f.Close()
+	}
+	if invalid > 0 {
+		return fmt.Errorf("found %d invalid event%s", invalid, pluralize(invalid))
+	}
+	fmt.Fprintln(w, "All events are valid")
+	return nil
+}
+
+// expandFilePatterns returns the files matching each of the given glob
+// patterns, in order. Patterns without glob metacharacters are returned
+// as-is, so that missing files are reported when they are opened.
+func expandFilePatterns(patterns []string) ([]string, error) {
+	var filenames []string
+	for _, pattern := range patterns {
+		if !strings.ContainsAny(pattern, `*?[\`) {
+			filenames = append(filenames, pattern)
+			continue
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid file pattern %q: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no files match %q", pattern)
+		}
+		filenames = append(filenames, matches...)
+	}
+	return filenames, nil
+}
+
+// apmServerWaitInterval holds the interval between
+// readiness checks in waitForAPMServer.
+var apmServerWaitInterval = time.Second
+
+// waitForAPMServer polls APM Server until it responds with its build
+// information, or until wait elapses, in which case the last readiness
+// error is returned. Authorization errors are returned immediately.
+func waitForAPMServer(ctx context.Context, client *apmclient.Client, creds *credentials, wait time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+	var lastErr error
+	for {
+		_, err := client.APMServerInfo(ctx, creds.APIKey, creds.SecretToken)
+		if err == nil || errors.Is(err, apmclient.ErrUnauthorized) {
+			return err
+		}
+		// Prefer reporting the server's response over
+		// a request interrupted by the wait elapsing.
+		if lastErr == nil || ctx.Err() == nil {
+			lastErr = err
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("APM Server not ready after %s: %w", wait, lastErr)
+		case <-time.After(apmServerWaitInterval):
+		}
+	}
+}
+
 // NewSendEventCmd returns pointer to a Command that sends events to APM Server
 func NewSendEventCmd(commands *Commands) *cli.Command {
 	return &cli.Command{
@@ -94,16 +280,36 @@ func NewSendEventCmd(commands *Commands) *cli.Command {
 		Usage:  "send events stored in ND-JSON format",
 		Action: commands.sendEventsCommand,
 		Flags: []cli.Flag{
-			&cli.StringFlag{
-				Name:     "file",
-				Aliases:  []string{"f"},
-				Required: true,
-				Usage:    "File containing the payload to send, in ND-JSON format. Payload must be provided via this flag or stdin.",
+			&cli.StringSliceFlag{
+				Name:    "file",
+				Aliases: []string{"f"},
+				Usage:   "File containing the payload to send, in ND-JSON format. May be a glob pattern, and may be repeated to send each file in a separate request. Payload must be provided via this flag or stdin.",
+			},
+			&cli.UintFlag{
+				Name:  "concurrency",
+				Value: 4,
+				Usage: "Maximum number of files to send concurrently",
 			},
 			&cli.BoolFlag{
 				Name:  "rumv2",
 				Usage: "Send events to /intake/v2/rum/events",
 			},
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "Validate the events without sending them, reporting the line numbers of any malformed events",
+			},
+			&cli.BoolFlag{
+				Name:  "dump-request",
+				Usage: "Write each request to stderr before sending it, with authorization redacted",
+			},
+			&cli.DurationFlag{
+				Name:  "wait",
+				Usage: "Wait up to this long for APM Server to be ready before sending events",
+			},
+			newAuthFlag(),
+			newMinTTLFlag(),
+			newYesFlag(),
+			newNoCreateKeyFlag(),
 		},
 	}
 }
@@ -0,0 +1,301 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v3"
+)
+
+func TestSendEventsTimeout(t *testing.T) {
+	setTestCacheDir(t)
+
+	// The server does not respond until the test completes.
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+	require.NoError(t, updateCachedCredentials(srv.URL, &credentials{APIKey: "api_key"}))
+
+	eventsFile := filepath.Join(t.TempDir(), "events.ndjson")
+	require.NoError(t, os.WriteFile(eventsFile, []byte(`{"metadata":{}}`+"\n"), 0644))
+
+	commands := &Commands{httpTimeout: 50 * time.Millisecond}
+	commands.cfg.APMServerURL = srv.URL
+	cmd := &cli.Command{
+		Name:     "apmtool",
+		Commands: []*cli.Command{NewSendEventCmd(commands)},
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Run(context.Background(), []string{"apmtool", "send-events", "-f", eventsFile})
+	}()
+	select {
+	case err := <-done:
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "error performing HTTP request")
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for send-events to return")
+	}
+}
+
+func TestSendEventsWait(t *testing.T) {
+	setTestCacheDir(t)
+	defer func(interval time.Duration) { apmServerWaitInterval = interval }(apmServerWaitInterval)
+	apmServerWaitInterval = 10 * time.Millisecond
+
+	var infoRequests, eventRequests atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "ApiKey api_key", r.Header.Get("Authorization"))
+		switch r.URL.Path {
+		case "/":
+			if infoRequests.Add(1) <= 2 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte(`{"version":"8.14.0"}`))
+		case "/intake/v2/events":
+			eventRequests.Add(1)
+			w.WriteHeader(http.StatusAccepted)
+		}
+	}))
+	defer srv.Close()
+	require.NoError(t, updateCachedCredentials(srv.URL, &credentials{APIKey: "api_key"}))
+
+	eventsFile := filepath.Join(t.TempDir(), "events.ndjson")
+	require.NoError(t, os.WriteFile(eventsFile, []byte(`{"metadata":{}}`+"\n"), 0644))
+
+	commands := &Commands{httpTimeout: 10 * time.Second}
+	commands.cfg.APMServerURL = srv.URL
+	cmd := &cli.Command{
+		Name:     "apmtool",
+		Commands: []*cli.Command{NewSendEventCmd(commands)},
+	}
+	err := cmd.Run(context.Background(), []string{"apmtool", "send-events", "-f", eventsFile, "--wait", "10s"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), infoRequests.Load())
+	assert.Equal(t, int64(1), eventRequests.Load())
+}
+
+func TestSendEventsWaitTimeout(t *testing.T) {
+	setTestCacheDir(t)
+	defer func(interval time.Duration) { apmServerWaitInterval = interval }(apmServerWaitInterval)
+	apmServerWaitInterval = 10 * time.Millisecond
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/", r.URL.Path)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	require.NoError(t, updateCachedCredentials(srv.URL, &credentials{APIKey: "api_key"}))
+
+	commands := &Commands{}
+	commands.cfg.APMServerURL = srv.URL
+	cmd := &cli.Command{
+		Name:     "apmtool",
+		Commands: []*cli.Command{NewSendEventCmd(commands)},
+	}
+	err := cmd.Run(context.Background(), []string{"apmtool", "send-events", "-f", "events.ndjson", "--wait", "100ms"})
+	assert.ErrorContains(t, err, `APM Server not ready after 100ms: error getting APM Server info; server responded with "503 Service Unavailable"`)
+}
+
+func TestSendEventsMultipleFiles(t *testing.T) {
+	setTestCacheDir(t)
+
+	// Block each request until all three are in flight,
+	// ensuring the files are sent concurrently.
+	var inflight sync.WaitGroup
+	inflight.Add(3)
+	var mu sync.Mutex
+	bodies := make(map[string]int)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/intake/v2/events", r.URL.Path)
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		mu.Lock()
+		bodies[string(body)]++
+		mu.Unlock()
+		inflight.Done()
+		inflight.Wait()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+	require.NoError(t, updateCachedCredentials(srv.URL, &credentials{APIKey: "api_key"}))
+
+	dir := t.TempDir()
+	for _, name := range []string{"a.ndjson", "b.ndjson", "c.json"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(name+"\n"), 0644))
+	}
+
+	commands := &Commands{httpTimeout: 10 * time.Second}
+	commands.cfg.APMServerURL = srv.URL
+	var out bytes.Buffer
+	cmd := &cli.Command{
+		Name:     "apmtool",
+		Writer:   &out,
+		Commands: []*cli.Command{NewSendEventCmd(commands)},
+	}
+	err := cmd.Run(context.Background(), []string{
+		"apmtool", "send-events",
+		"-f", filepath.Join(dir, "*.ndjson"),
+		"-f", filepath.Join(dir, "c.json"),
+		"--concurrency", "3",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"a.ndjson\n": 1, "b.ndjson\n": 1, "c.json\n": 1}, bodies)
+	assert.Contains(t, out.String(), "Sent 3 files: 3 succeeded, 0 failed\n")
+}
+
+func TestSendEventsMultipleFilesFailure(t *testing.T) {
+	setTestCacheDir(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+	require.NoError(t, updateCachedCredentials(srv.URL, &credentials{APIKey: "api_key"}))
+
+	eventsFile := filepath.Join(t.TempDir(), "events.ndjson")
+	require.NoError(t, os.WriteFile(eventsFile, []byte(`{"metadata":{}}`+"\n"), 0644))
+	missingFile := filepath.Join(t.TempDir(), "missing.ndjson")
+
+	commands := &Commands{httpTimeout: 10 * time.Second}
+	commands.cfg.APMServerURL = srv.URL
+	var out bytes.Buffer
+	cmd := &cli.Command{
+		Name:     "apmtool",
+		Writer:   &out,
+		Commands: []*cli.Command{NewSendEventCmd(commands)},
+	}
+	err := cmd.Run(context.Background(), []string{"apmtool", "send-events", "-f", eventsFile, "-f", missingFile})
+	assert.EqualError(t, err, "failed to send 1 of 2 files")
+	assert.Contains(t, out.String(), "OK     "+eventsFile+"\n")
+	assert.Contains(t, out.String(), "FAILED "+missingFile+": error opening file")
+	assert.Contains(t, out.String(), "Sent 2 files: 1 succeeded, 1 failed\n")
+}
+
+func TestSendEventsDumpRequest(t *testing.T) {
+	setTestCacheDir(t)
+	var authorization string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+	require.NoError(t, updateCachedCredentials(srv.URL, &credentials{APIKey: "api_key"}))
+
+	eventsFile := filepath.Join(t.TempDir(), "events.ndjson")
+	require.NoError(t, os.WriteFile(eventsFile, []byte(`{"metadata":{}}`+"\n"), 0644))
+
+	commands := &Commands{httpTimeout: 10 * time.Second}
+	commands.cfg.APMServerURL = srv.URL
+	var stderr bytes.Buffer
+	cmd := &cli.Command{
+		Name:      "apmtool",
+		ErrWriter: &stderr,
+		Commands:  []*cli.Command{NewSendEventCmd(commands)},
+	}
+	err := cmd.Run(context.Background(), []string{"apmtool", "send-events", "-f", eventsFile, "--dump-request"})
+	require.NoError(t, err)
+	assert.Equal(t, "ApiKey api_key", authorization)
+	assert.Equal(t, "POST "+srv.URL+"/intake/v2/events?verbose\n"+
+		"Authorization: [REDACTED]\n"+
+		"Content-Type: application/x-ndjson\n"+
+		"\n"+
+		`{"metadata":{}}`+"\n", stderr.String())
+}
+
+func TestSendEventsDryRun(t *testing.T) {
+	dir := t.TempDir()
+	validFile := filepath.Join(dir, "valid.ndjson")
+	invalidFile := filepath.Join(dir, "invalid.ndjson")
+	require.NoError(t, os.WriteFile(validFile, []byte(`{"metadata":{}}`+"\n"+`{"span":{}}`+"\n"), 0644))
+	require.NoError(t, os.WriteFile(invalidFile, []byte(`{"metadata":{}}`+"\n"+`{"spam":{}}`+"\n"), 0644))
+
+	// No APM Server is configured; dry runs must not make any requests.
+	var out bytes.Buffer
+	cmd := &cli.Command{
+		Name:     "apmtool",
+		Writer:   &out,
+		Commands: []*cli.Command{NewSendEventCmd(&Commands{})},
+	}
+	err := cmd.Run(context.Background(), []string{"apmtool", "send-events", "--dry-run", "-f", validFile})
+	require.NoError(t, err)
+	assert.Equal(t, "All events are valid\n", out.String())
+
+	out.Reset()
+	err = cmd.Run(context.Background(), []string{"apmtool", "send-events", "--dry-run", "-f", validFile, "-f", invalidFile})
+	assert.EqualError(t, err, "found 1 invalid event")
+	assert.Equal(t, invalidFile+": line 2: unknown event key \"spam\"\n", out.String())
+}
+
+func TestExpandFilePatterns(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.ndjson", "a.ndjson"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0644))
+	}
+	filenames, err := expandFilePatterns([]string{filepath.Join(dir, "*.ndjson"), "literal.ndjson"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "a.ndjson"),
+		filepath.Join(dir, "b.ndjson"),
+		"literal.ndjson",
+	}, filenames)
+
+	_, err = expandFilePatterns([]string{filepath.Join(dir, "*.json")})
+	assert.ErrorContains(t, err, "no files match")
+}
+
+func TestListSourcemapsContextCancelled(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	commands := &Commands{}
+	commands.cfg.KibanaURL = srv.URL
+	client, err := commands.kibanaClient()
+	require.NoError(t, err)
+	_, err = listSourcemaps(ctx, client)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,65 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+
+	"github.com/urfave/cli/v3"
+)
+
+func (cmd *Commands) logoutCommand(ctx context.Context, c *cli.Command) error {
+	if c.Bool("all") {
+		if err := clearCache(); err != nil {
+			return err
+		}
+		cmd.logger().Info("cleared cache")
+		return nil
+	}
+	if url := c.String("url"); url != "" {
+		if err := removeCachedCredentials(url); err != nil {
+			return err
+		}
+		cmd.logger().Info("removed cached credentials", "url", url)
+		return nil
+	}
+	if err := clearCachedCredentials(); err != nil {
+		return err
+	}
+	cmd.logger().Info("removed all cached credentials")
+	return nil
+}
+
+// NewLogoutCmd returns pointer to a Command that removes cached credentials
+func NewLogoutCmd(commands *Commands) *cli.Command {
+	return &cli.Command{
+		Name:   "logout",
+		Usage:  "remove cached agent credentials",
+		Action: commands.logoutCommand,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "url",
+				Usage: "remove cached credentials only for the given APM Server URL",
+			},
+			&cli.BoolFlag{
+				Name:  "all",
+				Usage: "remove everything in the apmtool cache directory",
+			},
+		},
+	}
+}
@@ -0,0 +1,93 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v3"
+)
+
+func runLogout(t *testing.T, args ...string) {
+	t.Helper()
+	cmd := &cli.Command{
+		Name:     "apmtool",
+		Commands: []*cli.Command{NewLogoutCmd(&Commands{})},
+	}
+	require.NoError(t, cmd.Run(context.Background(), append([]string{"apmtool", "logout"}, args...)))
+}
+
+func TestLogout(t *testing.T) {
+	setTestCacheDir(t)
+	require.NoError(t, updateCachedCredentials("http://a.testing", &credentials{APIKey: "a"}))
+	require.NoError(t, updateCachedCredentials("http://b.testing", &credentials{APIKey: "b"}))
+
+	runLogout(t, "--url", "http://a.testing")
+	_, err := readCachedCredentials("http://a.testing")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	creds, err := readCachedCredentials("http://b.testing")
+	require.NoError(t, err)
+	assert.Equal(t, "b", creds.APIKey)
+
+	runLogout(t)
+	_, err = readCachedCredentials("http://b.testing")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestLogoutAll(t *testing.T) {
+	setTestCacheDir(t)
+	require.NoError(t, updateCachedCredentials("http://a.testing", &credentials{APIKey: "a"}))
+	require.NoError(t, updateCache("other.json", func([]byte) ([]byte, error) {
+		return []byte("{}"), nil
+	}))
+
+	runLogout(t, "--all")
+	entries, err := os.ReadDir(cacheDir)
+	require.NoError(t, err)
+	for _, entry := range entries {
+		assert.Equal(t, ".flock", entry.Name())
+	}
+	_, err = os.Stat(filepath.Join(cacheDir, "credentials.json"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestLogoutLogs(t *testing.T) {
+	setTestCacheDir(t)
+	require.NoError(t, updateCachedCredentials("http://a.testing", &credentials{APIKey: "a"}))
+
+	var buf bytes.Buffer
+	logger, err := newLogger(&buf, "json", false)
+	require.NoError(t, err)
+	cmd := &cli.Command{
+		Name:     "apmtool",
+		Commands: []*cli.Command{NewLogoutCmd(&Commands{log: logger})},
+	}
+	require.NoError(t, cmd.Run(context.Background(), []string{"apmtool", "logout", "--url", "http://a.testing"}))
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "removed cached credentials", record["msg"])
+	assert.Equal(t, "http://a.testing", record["url"])
+}
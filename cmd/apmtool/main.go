@@ -21,6 +21,7 @@ import (
 	"context"
 	"log"
 	"os"
+	"time"
 
 	"github.com/urfave/cli/v3"
 )
@@ -34,6 +35,11 @@ func main() {
 				Usage:   "print debugging messages about progress",
 				Aliases: []string{"v"},
 			},
+			&cli.StringFlag{
+				Name:  "log-format",
+				Usage: "set the format of log messages: text or json",
+				Value: "text",
+			},
 			&cli.StringFlag{
 				Name:        "url",
 				Usage:       "set the Elasticsearch URL",
@@ -41,15 +47,11 @@ func main() {
 				Value:       "",
 				Sources:     cli.EnvVars("ELASTICSEARCH_URL"),
 				Destination: &commands.cfg.ElasticsearchURL,
-				Action: func(ctx context.Context, c *cli.Command, s string) error {
-					return commands.cfg.InferElasticCloudURLs()
-				},
 			},
 			&cli.StringFlag{
 				Name:        "username",
-				Usage:       "set the Elasticsearch username",
+				Usage:       "set the Elasticsearch username (default: \"elastic\")",
 				Category:    "Elasticsearch",
-				Value:       "elastic",
 				Sources:     cli.EnvVars("ELASTICSEARCH_USERNAME"),
 				Destination: &commands.cfg.Username,
 			},
@@ -77,18 +79,75 @@ func main() {
 			},
 			&cli.BoolFlag{
 				Name:        "insecure",
-				Usage:       "skip TLS certificate verification of Elasticsearch and APM server",
+				Usage:       "skip TLS certificate verification of Elasticsearch, Kibana, and APM server. Shorthand for --es-insecure and --apm-insecure.",
 				Value:       false,
 				Sources:     cli.EnvVars("TLS_SKIP_VERIFY"),
 				Destination: &commands.cfg.TLSSkipVerify,
 			},
+			&cli.BoolFlag{
+				Name:        "es-insecure",
+				Usage:       "skip TLS certificate verification of Elasticsearch only",
+				Category:    "Elasticsearch",
+				Destination: &commands.cfg.ESTLSSkipVerify,
+			},
+			&cli.BoolFlag{
+				Name:        "apm-insecure",
+				Usage:       "skip TLS certificate verification of APM Server only",
+				Category:    "APM",
+				Destination: &commands.cfg.APMTLSSkipVerify,
+			},
+			&cli.DurationFlag{
+				Name:        "http-timeout",
+				Usage:       "set the timeout for HTTP requests to APM Server and Kibana. 0 means no timeout.",
+				Value:       60 * time.Second,
+				Destination: &commands.httpTimeout,
+			},
+			&cli.StringFlag{
+				Name:        "config",
+				Usage:       "set the path to a YAML config file. Defaults to ~/.apmtool.yaml if it exists.",
+				Destination: &commands.cfg.ConfigFile,
+			},
+			&cli.StringFlag{
+				Name:        "profile",
+				Usage:       "set the name of the config file profile to use",
+				Value:       "",
+				Destination: &commands.cfg.Profile,
+			},
+		},
+		Before: func(ctx context.Context, c *cli.Command) (context.Context, error) {
+			logger, err := newLogger(c.ErrWriter, c.String("log-format"), c.Bool("verbose"))
+			if err != nil {
+				return ctx, err
+			}
+			commands.log = logger
+			// Flags and environment variables have been applied to
+			// commands.cfg; fill in anything unset from the config file.
+			if err := commands.cfg.Finalize(); err != nil {
+				return ctx, err
+			}
+			if commands.cfg.Username == "" {
+				commands.cfg.Username = "elastic"
+			}
+			return ctx, nil
 		},
 		Commands: []*cli.Command{
 			NewPrintEnvCmd(commands),
+			NewRevokeKeysCmd(commands),
+			NewLogoutCmd(commands),
 			NewSendEventCmd(commands),
 			NewUploadSourcemapCmd(commands),
+			NewListSourcemapsCmd(commands),
+			NewDeleteSourcemapCmd(commands),
+			NewCreateDataViewCmd(commands),
+			NewAgentConfigCmd(commands),
 			NewListServiceCmd(commands),
+			NewWaitServiceCmd(commands),
+			NewGetTraceCmd(commands),
+			NewListErrorsCmd(commands),
+			NewStreamStatsCmd(commands),
+			NewAPMInfoCmd(commands),
 			NewTraceGenCmd(commands),
+			NewBenchmarkCmd(commands),
 			NewESPollCmd(commands),
 		},
 	}
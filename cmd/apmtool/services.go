@@ -19,12 +19,22 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/urfave/cli/v3"
+
+	"github.com/elastic/apm-tools/pkg/apmclient"
 )
 
 func (cmd *Commands) servicesCommand(ctx context.Context, c *cli.Command) error {
+	output := c.String("output")
+	switch output {
+	case "text", "json":
+	default:
+		return fmt.Errorf("invalid output format %q, expected one of: text, json", output)
+	}
 	client, err := cmd.getClient()
 	if err != nil {
 		return err
@@ -33,8 +43,14 @@ func (cmd *Commands) servicesCommand(ctx context.Context, c *cli.Command) error
 	if err != nil {
 		return err
 	}
+	w := c.Root().Writer
+	if output == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(services)
+	}
 	for _, service := range services {
-		fmt.Println(service)
+		fmt.Fprintln(w, service)
 	}
 	return nil
 }
@@ -45,5 +61,66 @@ func NewListServiceCmd(commands *Commands) *cli.Command {
 		Name:   "list-services",
 		Usage:  "list APM services",
 		Action: commands.servicesCommand,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Value:   "text",
+				Usage:   "output format: text or json",
+			},
+		},
+	}
+}
+
+// serviceWaitInterval holds the interval between
+// polls of the service summary in waitServiceCommand.
+var serviceWaitInterval = time.Second
+
+func (cmd *Commands) waitServiceCommand(ctx context.Context, c *cli.Command) error {
+	name := c.String("name")
+	timeout := c.Duration("timeout")
+	client, err := cmd.getClient()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var options []apmclient.Option
+	if since := c.Duration("since"); since > 0 {
+		options = append(options, apmclient.WithTimeRange(time.Now().Add(-since), time.Time{}))
+	}
+	cmd.logger().Debug("waiting for service", "name", name, "timeout", timeout)
+	service, err := client.WaitForService(ctx, name, serviceWaitInterval, options...)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(c.Root().Writer, service)
+	return nil
+}
+
+// NewWaitServiceCmd returns pointer to a Command that waits for an APM service to appear
+func NewWaitServiceCmd(commands *Commands) *cli.Command {
+	return &cli.Command{
+		Name:   "wait-service",
+		Usage:  "wait for an APM service to appear in the service inventory",
+		Action: commands.waitServiceCommand,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "name",
+				Usage:    "name of the service to wait for",
+				Required: true,
+			},
+			&cli.DurationFlag{
+				Name:  "timeout",
+				Value: time.Minute,
+				Usage: "maximum time to wait for the service",
+			},
+			&cli.DurationFlag{
+				Name:  "since",
+				Value: 15 * time.Minute,
+				Usage: "only consider services reported within this duration; 0 considers all",
+			},
+		},
 	}
 }
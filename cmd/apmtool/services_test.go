@@ -0,0 +1,122 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v3"
+
+	"github.com/elastic/apm-tools/pkg/apmclient"
+)
+
+func TestListServicesJSON(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/metrics-apm.service_summary.1m-*/_search", r.URL.Path)
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+		  "hits": {"total": {"value": 2, "relation": "eq"}, "hits": []},
+		  "aggregations": {
+		    "multi_terms#services": {
+		      "buckets": [
+		        {"key": ["frontend", "production", "javascript", "rum-js"], "doc_count": 1},
+		        {"key": ["backend", "", "go", "go"], "doc_count": 1}
+		      ]
+		    }
+		  }
+		}`))
+	}))
+	defer srv.Close()
+
+	commands := &Commands{cfg: apmclient.Config{ElasticsearchURL: srv.URL}}
+	var out bytes.Buffer
+	cmd := &cli.Command{
+		Writer:   &out,
+		Commands: []*cli.Command{NewListServiceCmd(commands)},
+	}
+	err := cmd.Run(context.Background(), []string{"apmtool", "list-services", "-o", "json"})
+	require.NoError(t, err)
+
+	var services []map[string]string
+	require.NoError(t, json.Unmarshal(out.Bytes(), &services))
+	assert.Equal(t, []map[string]string{{
+		"name":        "frontend",
+		"environment": "production",
+		"language":    "javascript",
+		"agent":       "rum-js",
+	}, {
+		"name":        "backend",
+		"environment": "",
+		"language":    "go",
+		"agent":       "go",
+	}}, services)
+}
+
+func TestListServicesInvalidOutput(t *testing.T) {
+	cmd := &cli.Command{
+		Writer:   &bytes.Buffer{},
+		Commands: []*cli.Command{NewListServiceCmd(&Commands{})},
+	}
+	err := cmd.Run(context.Background(), []string{"apmtool", "list-services", "-o", "yaml"})
+	assert.EqualError(t, err, `invalid output format "yaml", expected one of: text, json`)
+}
+
+func TestWaitService(t *testing.T) {
+	defer func(interval time.Duration) { serviceWaitInterval = interval }(serviceWaitInterval)
+	serviceWaitInterval = time.Millisecond
+
+	var polls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		polls++
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		buckets := ""
+		if polls >= 3 {
+			buckets = `{"key": ["frontend", "production", "javascript", "rum-js"], "doc_count": 1}`
+		}
+		fmt.Fprintf(w, `{
+		  "hits": {"total": {"value": 0, "relation": "eq"}, "hits": []},
+		  "aggregations": {"multi_terms#services": {"buckets": [%s]}}
+		}`, buckets)
+	}))
+	defer srv.Close()
+
+	commands := &Commands{cfg: apmclient.Config{ElasticsearchURL: srv.URL}}
+	var out bytes.Buffer
+	cmd := &cli.Command{
+		Writer:   &out,
+		Commands: []*cli.Command{NewWaitServiceCmd(commands)},
+	}
+	err := cmd.Run(context.Background(), []string{"apmtool", "wait-service", "--name", "frontend"})
+	require.NoError(t, err)
+	assert.Equal(t, 3, polls)
+	assert.Equal(t, "{frontend production rum-js javascript}\n", out.String())
+
+	err = cmd.Run(context.Background(), []string{"apmtool", "wait-service", "--name", "backend", "--timeout", "20ms"})
+	assert.EqualError(t, err, `service "backend" not found: context deadline exceeded`)
+}
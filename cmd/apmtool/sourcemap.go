@@ -20,26 +20,22 @@ package main
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"mime/multipart"
 	"net/http"
+	"net/url"
 	"os"
 
 	"github.com/urfave/cli/v3"
+
+	"github.com/elastic/apm-tools/pkg/kibanaclient"
 )
 
 func (cmd *Commands) uploadSourcemapCommand(ctx context.Context, c *cli.Command) error {
-	var data bytes.Buffer
-	mw := multipart.NewWriter(&data)
-	mw.WriteField("service_name", c.String("service-name"))
-	mw.WriteField("service_version", c.String("service-version"))
-	mw.WriteField("bundle_filepath", c.String("bundle-filepath"))
-	sourcemapFileWriter, err := mw.CreateFormFile("sourcemap", "sourcemap.js.map")
-	if err != nil {
-		return err
-	}
+	var sourcemap io.Reader
 	if filename := c.String("file"); filename == "" {
 		stat, err := os.Stdin.Stat()
 		if err != nil {
@@ -48,45 +44,146 @@ func (cmd *Commands) uploadSourcemapCommand(ctx context.Context, c *cli.Command)
 		if stat.Size() == 0 {
 			log.Fatal("empty -file flag and stdin, please set one.")
 		}
-		if _, err := io.Copy(sourcemapFileWriter, os.Stdin); err != nil {
-			return err
-		}
+		sourcemap = os.Stdin
 	} else {
 		f, err := os.Open(filename)
 		if err != nil {
 			return fmt.Errorf("error opening file: %w", err)
 		}
-		if _, err := io.Copy(sourcemapFileWriter, f); err != nil {
-			f.Close()
+		defer f.Close()
+		sourcemap = f
+	}
+	client, err := cmd.kibanaClient()
+	if err != nil {
+		return err
+	}
+	resp, err := uploadSourcemap(ctx, client, sourcemap, sourcemapMetadata{
+		serviceName:    c.String("service-name"),
+		serviceVersion: c.String("service-version"),
+		bundleFilepath: c.String("bundle-filepath"),
+	})
+	if err != nil {
+		return err
+	}
+	cmd.logger().Info("uploaded sourcemap", "response", string(resp))
+	return nil
+}
+
+// sourcemapMetadata holds the fields used to match
+// an uploaded sourcemap against events.
+type sourcemapMetadata struct {
+	serviceName    string
+	serviceVersion string
+	bundleFilepath string
+}
+
+// uploadSourcemap uploads the sourcemap read from r to Kibana, along with
+// its metadata, returning Kibana's response body. The multipart request
+// body is streamed, rather than buffered in memory, so large sourcemaps
+// may be uploaded.
+func uploadSourcemap(ctx context.Context, client *kibanaclient.Client, r io.Reader, metadata sourcemapMetadata) ([]byte, error) {
+	pr, pw := io.Pipe()
+	defer pr.Close()
+	mw := multipart.NewWriter(pw)
+	writeErr := make(chan error, 1)
+	go func() {
+		err := writeSourcemapForm(mw, r, metadata)
+		pw.CloseWithError(err)
+		writeErr <- err
+	}()
+
+	var resp bytes.Buffer
+	err := client.Do(ctx, http.MethodPost, "/api/apm/sourcemaps", kibanaclient.Body{
+		ContentType: mw.FormDataContentType(),
+		Reader:      pr,
+	}, &resp)
+	// Unblock the writer if the request finished
+	// without consuming the whole body.
+	pr.Close()
+	if werr := <-writeErr; werr != nil && !errors.Is(werr, io.ErrClosedPipe) {
+		return nil, fmt.Errorf("error writing sourcemap request body: %w", werr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error uploading sourcemap: %w", err)
+	}
+	return resp.Bytes(), nil
+}
+
+// writeSourcemapForm writes the sourcemap upload form to mw, and closes it.
+func writeSourcemapForm(mw *multipart.Writer, sourcemap io.Reader, metadata sourcemapMetadata) error {
+	for _, field := range [][2]string{
+		{"service_name", metadata.serviceName},
+		{"service_version", metadata.serviceVersion},
+		{"bundle_filepath", metadata.bundleFilepath},
+	} {
+		if err := mw.WriteField(field[0], field[1]); err != nil {
 			return err
 		}
-		f.Close()
 	}
-	if err := mw.Close(); err != nil {
+	sourcemapFileWriter, err := mw.CreateFormFile("sourcemap", "sourcemap.js.map")
+	if err != nil {
 		return err
 	}
+	if _, err := io.Copy(sourcemapFileWriter, sourcemap); err != nil {
+		return err
+	}
+	return mw.Close()
+}
 
-	req, err := http.NewRequest(
-		http.MethodPost,
-		cmd.cfg.KibanaURL+"/api/apm/sourcemaps",
-		bytes.NewReader(data.Bytes()),
-	)
+func (cmd *Commands) listSourcemapsCommand(ctx context.Context, c *cli.Command) error {
+	client, err := cmd.kibanaClient()
 	if err != nil {
-		return fmt.Errorf("error creating HTTP request: %w", err)
+		return err
+	}
+	artifacts, err := listSourcemaps(ctx, client)
+	if err != nil {
+		return err
 	}
-	req.SetBasicAuth(cmd.cfg.Username, cmd.cfg.Password)
-	req.Header.Set("Content-Type", mw.FormDataContentType())
-	req.Header.Set("kbn-xsrf", "1")
+	for _, artifact := range artifacts {
+		fmt.Printf("%s\t%s\t%s\t%s\n",
+			artifact.ID,
+			artifact.Body.ServiceName,
+			artifact.Body.ServiceVersion,
+			artifact.Body.BundleFilepath,
+		)
+	}
+	return nil
+}
 
-	resp, err := http.DefaultClient.Do(req)
+func (cmd *Commands) deleteSourcemapCommand(ctx context.Context, c *cli.Command) error {
+	client, err := cmd.kibanaClient()
 	if err != nil {
-		return fmt.Errorf("error performing HTTP request: %w", err)
+		return err
 	}
-	defer resp.Body.Close()
-	io.Copy(os.Stderr, resp.Body)
-	fmt.Fprintln(os.Stderr)
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("error uploading sourcemap; server responded with %q", resp.Status)
+	return deleteSourcemap(ctx, client, c.String("id"))
+}
+
+// sourcemapArtifact holds a sourcemap stored in Kibana.
+type sourcemapArtifact struct {
+	ID      string `json:"id"`
+	Created string `json:"created"`
+	Body    struct {
+		ServiceName    string `json:"serviceName"`
+		ServiceVersion string `json:"serviceVersion"`
+		BundleFilepath string `json:"bundleFilepath"`
+	} `json:"body"`
+}
+
+// listSourcemaps returns the sourcemaps stored in Kibana.
+func listSourcemaps(ctx context.Context, client *kibanaclient.Client) ([]sourcemapArtifact, error) {
+	var result struct {
+		Artifacts []sourcemapArtifact `json:"artifacts"`
+	}
+	if err := client.Do(ctx, http.MethodGet, "/api/apm/sourcemaps", nil, &result); err != nil {
+		return nil, fmt.Errorf("error listing sourcemaps: %w", err)
+	}
+	return result.Artifacts, nil
+}
+
+// deleteSourcemap deletes the sourcemap with the given ID from Kibana.
+func deleteSourcemap(ctx context.Context, client *kibanaclient.Client, id string) error {
+	if err := client.Do(ctx, http.MethodDelete, "/api/apm/sourcemaps/"+url.PathEscape(id), nil, nil); err != nil {
+		return fmt.Errorf("error deleting sourcemap: %w", err)
 	}
 	return nil
 }
@@ -122,3 +219,28 @@ func NewUploadSourcemapCmd(commands *Commands) *cli.Command {
 		},
 	}
 }
+
+// NewListSourcemapsCmd returns pointer to a Command that lists the source maps stored in Kibana
+func NewListSourcemapsCmd(commands *Commands) *cli.Command {
+	return &cli.Command{
+		Name:   "list-sourcemaps",
+		Usage:  "list source maps stored in Kibana",
+		Action: commands.listSourcemapsCommand,
+	}
+}
+
+// NewDeleteSourcemapCmd returns pointer to a Command that deletes a source map from Kibana
+func NewDeleteSourcemapCmd(commands *Commands) *cli.Command {
+	return &cli.Command{
+		Name:   "delete-sourcemap",
+		Usage:  "delete a source map from Kibana",
+		Action: commands.deleteSourcemapCommand,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "id",
+				Required: true,
+				Usage:    "ID of the source map to delete, as reported by list-sourcemaps.",
+			},
+		},
+	}
+}
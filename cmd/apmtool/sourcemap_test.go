@@ -0,0 +1,232 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v3"
+
+	"github.com/elastic/apm-tools/pkg/apmclient"
+	"github.com/elastic/apm-tools/pkg/kibanaclient"
+)
+
+func TestUploadSourcemapLarge(t *testing.T) {
+	// Write a 32MB sourcemap file.
+	path := filepath.Join(t.TempDir(), "sourcemap.js.map")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	h := sha256.New()
+	chunk := make([]byte, 1<<20)
+	for i := range chunk {
+		chunk[i] = byte('a' + i%26)
+	}
+	for i := 0; i < 32; i++ {
+		_, err := io.MultiWriter(f, h).Write(chunk)
+		require.NoError(t, err)
+	}
+	require.NoError(t, f.Close())
+	expectedSum := h.Sum(nil)
+
+	var fields map[string]string
+	var receivedSum []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/api/apm/sourcemaps", r.URL.Path)
+		assert.Equal(t, "1", r.Header.Get("kbn-xsrf"))
+		username, password, _ := r.BasicAuth()
+		assert.Equal(t, "elastic", username)
+		assert.Equal(t, "changeme", password)
+
+		mr, err := r.MultipartReader()
+		require.NoError(t, err)
+		fields = make(map[string]string)
+		for {
+			part, err := mr.NextPart()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			require.NoError(t, err)
+			if part.FormName() == "sourcemap" {
+				h := sha256.New()
+				_, err := io.Copy(h, part)
+				require.NoError(t, err)
+				receivedSum = h.Sum(nil)
+				continue
+			}
+			value, err := io.ReadAll(part)
+			require.NoError(t, err)
+			fields[part.FormName()] = string(value)
+		}
+	}))
+	defer srv.Close()
+
+	f, err = os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	client, err := kibanaclient.New(apmclient.Config{
+		KibanaURL: srv.URL,
+		Username:  "elastic",
+		Password:  "changeme",
+	})
+	require.NoError(t, err)
+	_, err = uploadSourcemap(context.Background(), client, f, sourcemapMetadata{
+		serviceName:    "service",
+		serviceVersion: "1.0.0",
+		bundleFilepath: "/bundle.js",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"service_name":    "service",
+		"service_version": "1.0.0",
+		"bundle_filepath": "/bundle.js",
+	}, fields)
+	assert.Equal(t, expectedSum, receivedSum)
+}
+
+func TestUploadSourcemapReadError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+	}))
+	defer srv.Close()
+
+	r := io.MultiReader(io.LimitReader(zeroReader{}, 1<<20), errReader{errors.New("read failed")})
+	client, err := kibanaclient.New(apmclient.Config{KibanaURL: srv.URL})
+	require.NoError(t, err)
+	_, err = uploadSourcemap(context.Background(), client, r, sourcemapMetadata{})
+	assert.ErrorContains(t, err, "read failed")
+}
+
+func TestUploadSourcemapErrorResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"message": "invalid sourcemap"}`))
+	}))
+	defer srv.Close()
+
+	client, err := kibanaclient.New(apmclient.Config{KibanaURL: srv.URL})
+	require.NoError(t, err)
+	_, err = uploadSourcemap(context.Background(), client, strings.NewReader("{}"), sourcemapMetadata{})
+	assert.EqualError(t, err, `error uploading sourcemap: Kibana responded with "400 Bad Request": {"message": "invalid sourcemap"}`)
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
+
+func TestListSourcemaps(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assertKibanaRequest(t, r, http.MethodGet, "/api/apm/sourcemaps")
+		w.Write([]byte(`{"artifacts": [{
+		  "id": "apm:service-1.0.0-abc",
+		  "created": "2024-01-01T00:00:00.000Z",
+		  "body": {"serviceName": "service", "serviceVersion": "1.0.0", "bundleFilepath": "/bundle.js"}
+		}]}`))
+	}))
+	defer srv.Close()
+
+	client, err := kibanaclient.New(apmclient.Config{
+		KibanaURL: srv.URL,
+		Username:  "elastic",
+		Password:  "changeme",
+	})
+	require.NoError(t, err)
+	artifacts, err := listSourcemaps(context.Background(), client)
+	require.NoError(t, err)
+	require.Len(t, artifacts, 1)
+	assert.Equal(t, "apm:service-1.0.0-abc", artifacts[0].ID)
+	assert.Equal(t, "service", artifacts[0].Body.ServiceName)
+	assert.Equal(t, "1.0.0", artifacts[0].Body.ServiceVersion)
+	assert.Equal(t, "/bundle.js", artifacts[0].Body.BundleFilepath)
+}
+
+func TestDeleteSourcemap(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assertKibanaRequest(t, r, http.MethodDelete, "/api/apm/sourcemaps/apm:service-1.0.0-abc")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	client, err := kibanaclient.New(apmclient.Config{
+		KibanaURL: srv.URL,
+		Username:  "elastic",
+		Password:  "changeme",
+	})
+	require.NoError(t, err)
+	err = deleteSourcemap(context.Background(), client, "apm:service-1.0.0-abc")
+	require.NoError(t, err)
+}
+
+func TestDeleteSourcemapNotFound(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	client, err := kibanaclient.New(apmclient.Config{KibanaURL: srv.URL})
+	require.NoError(t, err)
+	err = deleteSourcemap(context.Background(), client, "unknown")
+	assert.EqualError(t, err, `error deleting sourcemap: Kibana responded with "404 Not Found": 404 page not found`)
+}
+
+func assertKibanaRequest(t testing.TB, r *http.Request, method, path string) {
+	t.Helper()
+	assert.Equal(t, method, r.Method)
+	assert.Equal(t, path, r.URL.Path)
+	assert.Equal(t, "1", r.Header.Get("kbn-xsrf"))
+	username, password, ok := r.BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "elastic", username)
+	assert.Equal(t, "changeme", password)
+}
+
+func TestListSourcemapsCommandInsecureAPIKey(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "ApiKey abc123", r.Header.Get("Authorization"))
+		w.Write([]byte(`{"artifacts": []}`))
+	}))
+	defer srv.Close()
+
+	commands := &Commands{}
+	commands.cfg.KibanaURL = srv.URL
+	commands.cfg.APIKey = "abc123"
+	commands.cfg.TLSSkipVerify = true
+	cmd := &cli.Command{
+		Name:     "apmtool",
+		Commands: []*cli.Command{NewListSourcemapsCmd(commands)},
+	}
+	err := cmd.Run(context.Background(), []string{"apmtool", "list-sourcemaps"})
+	require.NoError(t, err)
+}
@@ -0,0 +1,69 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/elastic/apm-tools/pkg/apmclient"
+)
+
+func (cmd *Commands) getTraceCommand(ctx context.Context, c *cli.Command) error {
+	traceID := c.Args().First()
+	if traceID == "" {
+		return errors.New("trace ID must be specified")
+	}
+	client, err := cmd.getClient()
+	if err != nil {
+		return err
+	}
+	trace, err := client.GetTrace(ctx, traceID)
+	if err != nil {
+		return err
+	}
+	for _, root := range trace.Roots() {
+		printTraceEvent(trace, root, 0)
+	}
+	return nil
+}
+
+// printTraceEvent prints event and its descendants,
+// indenting each level of the tree.
+func printTraceEvent(trace apmclient.Trace, event apmclient.TraceEvent, depth int) {
+	fmt.Printf("%s%s [%s] %s %s\n",
+		strings.Repeat("  ", depth), event.Name, event.ID, event.Duration, event.Outcome,
+	)
+	for _, child := range trace.Children(event.ID) {
+		printTraceEvent(trace, child, depth+1)
+	}
+}
+
+// NewGetTraceCmd returns pointer to a Command that fetches a trace by ID and prints it as a tree
+func NewGetTraceCmd(commands *Commands) *cli.Command {
+	return &cli.Command{
+		Name:      "get-trace",
+		Usage:     "print the transactions and spans of a trace as a tree",
+		ArgsUsage: "<trace-id>",
+		Action:    commands.getTraceCommand,
+	}
+}
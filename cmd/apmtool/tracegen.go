@@ -19,48 +19,129 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"math/rand"
 	"os"
 	"os/signal"
 
 	"github.com/urfave/cli/v3"
+	"go.elastic.co/apm/v2"
 
 	"github.com/elastic/apm-tools/pkg/tracegen"
 )
 
 func (cmd *Commands) sendTrace(ctx context.Context, c *cli.Command) error {
+	var traceID apm.TraceID
+	if c.IsSet("trace-id") {
+		if c.IsSet("traceparent") {
+			return errors.New("--trace-id and --traceparent are mutually exclusive")
+		}
+		var err error
+		if traceID, err = tracegen.ParseTraceID(c.String("trace-id")); err != nil {
+			return err
+		}
+	}
+
+	count := int(c.Uint("count"))
+	if count < 1 {
+		return fmt.Errorf("invalid count %d, must be > 0", count)
+	}
+	if count > 1 && c.Float("rate") > 0 {
+		return errors.New("--count and --rate are mutually exclusive")
+	}
+
 	creds, err := cmd.getCredentials(ctx, c)
 	if err != nil {
 		return err
 	}
 
-	cfg := tracegen.NewConfig(
+	opts := []tracegen.ConfigOption{
 		tracegen.WithAPMServerURL(cmd.cfg.APMServerURL),
 		tracegen.WithAPIKey(creds.APIKey),
 		tracegen.WithSampleRate(c.Float("sample-rate")),
-		tracegen.WithInsecureConn(cmd.cfg.TLSSkipVerify),
+		tracegen.WithInsecureConn(cmd.cfg.APMServerTLSSkipVerify()),
 		tracegen.WithOTLPProtocol(c.String("otlp-protocol")),
-		tracegen.WithOTLPServiceName(newUniqueServiceName("service", "otlp")),
-		tracegen.WithElasticAPMServiceName(newUniqueServiceName("service", "intake")),
-	)
+		tracegen.WithRate(c.Float("rate")),
+		tracegen.WithDuration(c.Duration("duration")),
+		tracegen.WithFlushTimeout(c.Duration("flush-timeout")),
+		tracegen.WithTraceparent(c.String("traceparent"), c.String("tracestate")),
+	}
+	if c.IsSet("trace-id") {
+		opts = append(opts, tracegen.WithTraceID(traceID))
+	}
+	// With --emit-ids, stdout is reserved for the IDs of each
+	// trace so they can be captured, and the summary goes to stderr.
+	out := c.Root().Writer
+	if c.Bool("emit-ids") {
+		opts = append(opts, tracegen.WithTraceIDsCallback(emitTraceIDs(c.Root().Writer)))
+		out = c.Root().ErrWriter
+	}
+	// newConfig returns the config for a trace, with unique service
+	// names and, unless specified, a new random trace ID.
+	newConfig := func() tracegen.Config {
+		return tracegen.NewConfig(append([]tracegen.ConfigOption{
+			tracegen.WithOTLPServiceName(newUniqueServiceName("service", "otlp")),
+			tracegen.WithElasticAPMServiceName(newUniqueServiceName("service", "intake")),
+		}, opts...)...)
+	}
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Kill, os.Interrupt)
 	defer cancel()
 
-	stats, err := tracegen.SendDistributedTrace(ctx, cfg)
+	var stats tracegen.EventStats
+	if c.Float("rate") > 0 {
+		stats, err = tracegen.GenerateContinuous(ctx, newConfig())
+	} else {
+		stats, err = sendTraces(ctx, count, newConfig, tracegen.SendDistributedTrace)
+	}
 	if err != nil {
 		return fmt.Errorf("error sending distributed trace: %w", err)
 	}
-	fmt.Printf(
-		"Sent %d span%s, %d exception%s, and %d log%s\n",
+	var traces string
+	if count > 1 {
+		traces = fmt.Sprintf(" across %d traces", count)
+	}
+	fmt.Fprintf(out,
+		"Sent %d span%s, %d exception%s, and %d log%s%s\n",
 		stats.SpansSent, pluralize(stats.SpansSent),
 		stats.ExceptionsSent, pluralize(stats.ExceptionsSent),
 		stats.LogsSent, pluralize(stats.LogsSent),
+		traces,
 	)
 
 	return nil
 }
 
+// sendTraces sends count traces with send, each with a config returned
+// by newConfig, returning the aggregated stats. If sending a trace fails,
+// the stats of the traces sent so far are returned along with the error.
+func sendTraces(
+	ctx context.Context, count int,
+	newConfig func() tracegen.Config,
+	send func(context.Context, tracegen.Config) (tracegen.EventStats, error),
+) (tracegen.EventStats, error) {
+	var stats tracegen.EventStats
+	for i := 0; i < count; i++ {
+		traceStats, err := send(ctx, newConfig())
+		if err != nil {
+			return stats, err
+		}
+		stats = stats.Add(traceStats)
+	}
+	return stats, nil
+}
+
+// emitTraceIDs returns a callback for tracegen.WithTraceIDsCallback
+// that writes the IDs of each trace to w as a line of JSON.
+func emitTraceIDs(w io.Writer) func(tracegen.TraceIDs) {
+	enc := json.NewEncoder(w)
+	return func(ids tracegen.TraceIDs) {
+		enc.Encode(ids)
+	}
+}
+
 func pluralize(n int) string {
 	if n == 1 {
 		return ""
@@ -99,6 +180,42 @@ func NewTraceGenCmd(commands *Commands) *cli.Command {
 				Usage: "set OTLP transport protocol to one of: grpc (default), http/protobuf",
 				Value: "grpc",
 			},
+			&cli.FloatFlag{
+				Name:  "rate",
+				Usage: "continuously send traces at this rate per second, until interrupted or --duration elapses",
+			},
+			&cli.UintFlag{
+				Name:  "count",
+				Value: 1,
+				Usage: "number of traces to send, each with unique service names and, unless --trace-id or --traceparent is specified, a new random trace ID. Cannot be used with --rate.",
+			},
+			&cli.DurationFlag{
+				Name:  "duration",
+				Usage: "how long to continuously send traces for when --rate is specified. 0 means until interrupted.",
+			},
+			&cli.DurationFlag{
+				Name:  "flush-timeout",
+				Usage: "how long to wait for each trace to be flushed to the server before failing. 0 means no timeout.",
+			},
+			&cli.StringFlag{
+				Name:  "trace-id",
+				Usage: "use this trace ID (32 hex characters) rather than a random one",
+			},
+			&cli.StringFlag{
+				Name:  "traceparent",
+				Usage: "continue an upstream trace, given its W3C traceparent header (00-<32 hex>-<16 hex>-<2 hex>)",
+			},
+			&cli.StringFlag{
+				Name:  "tracestate",
+				Usage: "W3C tracestate header of the upstream trace, used with --traceparent",
+			},
+			&cli.BoolFlag{
+				Name:  "emit-ids",
+				Usage: "print the trace ID, transaction IDs, and span IDs of each trace sent to stdout as a line of JSON",
+			},
+			newMinTTLFlag(),
+			newYesFlag(),
+			newNoCreateKeyFlag(),
 		},
 	}
 }
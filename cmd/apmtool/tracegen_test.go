@@ -0,0 +1,95 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.elastic.co/apm/v2"
+
+	"github.com/elastic/apm-tools/pkg/tracegen"
+)
+
+func TestSendTracesCount(t *testing.T) {
+	// NewConfig sets these from, and in, the environment.
+	t.Setenv("ELASTIC_APM_SERVER_URL", "")
+	t.Setenv("ELASTIC_APM_API_KEY", "")
+	newConfig := func() tracegen.Config {
+		return tracegen.NewConfig(tracegen.WithAPMServerURL("http://localhost:8200"))
+	}
+
+	traceIDs := make(map[apm.TraceID]bool)
+	stats, err := sendTraces(context.Background(), 5, newConfig,
+		func(ctx context.Context, cfg tracegen.Config) (tracegen.EventStats, error) {
+			traceIDs[cfg.TraceID()] = true
+			return tracegen.EventStats{SpansSent: 3, LogsSent: 1}, nil
+		},
+	)
+	require.NoError(t, err)
+	assert.Len(t, traceIDs, 5)
+	assert.Equal(t, tracegen.EventStats{SpansSent: 15, LogsSent: 5}, stats)
+}
+
+func TestSendTracesError(t *testing.T) {
+	t.Setenv("ELASTIC_APM_SERVER_URL", "")
+	t.Setenv("ELASTIC_APM_API_KEY", "")
+	newConfig := func() tracegen.Config {
+		return tracegen.NewConfig(tracegen.WithAPMServerURL("http://localhost:8200"))
+	}
+
+	var sent int
+	stats, err := sendTraces(context.Background(), 5, newConfig,
+		func(ctx context.Context, cfg tracegen.Config) (tracegen.EventStats, error) {
+			if sent == 2 {
+				return tracegen.EventStats{}, errors.New("connection refused")
+			}
+			sent++
+			return tracegen.EventStats{SpansSent: 3}, nil
+		},
+	)
+	assert.EqualError(t, err, "connection refused")
+	assert.Equal(t, tracegen.EventStats{SpansSent: 6}, stats)
+}
+
+func TestEmitTraceIDs(t *testing.T) {
+	var buf bytes.Buffer
+	emit := emitTraceIDs(&buf)
+	emit(tracegen.TraceIDs{
+		TraceID:        "0102030405060708090a0b0c0d0e0f10",
+		TransactionIDs: []string{"0102030405060708"},
+		SpanIDs:        []string{"1112131415161718", "2122232425262728"},
+	})
+	emit(tracegen.TraceIDs{TraceID: "1102030405060708090a0b0c0d0e0f10"})
+
+	dec := json.NewDecoder(&buf)
+	var ids map[string]any
+	require.NoError(t, dec.Decode(&ids))
+	assert.Equal(t, map[string]any{
+		"trace.id":        "0102030405060708090a0b0c0d0e0f10",
+		"transaction.ids": []any{"0102030405060708"},
+		"span.ids":        []any{"1112131415161718", "2122232425262728"},
+	}, ids)
+	require.NoError(t, dec.Decode(&ids))
+	assert.Equal(t, "1102030405060708090a0b0c0d0e0f10", ids["trace.id"])
+}
@@ -77,6 +77,7 @@ func approval() int {
 		case 'y':
 			approvedPath := strings.Replace(rf, approvaltest.ReceivedSuffix, approvaltest.ApprovedSuffix, 1)
 			os.Rename(rf, approvedPath)
+			os.Remove(strings.TrimSuffix(rf, approvaltest.ReceivedSuffix) + approvaltest.DiffSuffix)
 		}
 	}
 	return 0
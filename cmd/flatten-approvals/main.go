@@ -18,6 +18,7 @@
 package main
 
 import (
+	"encoding/csv"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -25,9 +26,15 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 )
 
-var inplace = flag.Bool("i", false, "modify file in place")
+var (
+	inplace = flag.Bool("i", false, "modify file in place (json format only)")
+	format  = flag.String("format", "json", "output format: json, ndjson, or csv")
+	fields  = flag.String("fields", "", "comma-separated list of fields to output, which may end with '*' to match a prefix; empty means all fields")
+)
 
 func main() {
 	flag.Parse()
@@ -37,6 +44,15 @@ func main() {
 }
 
 func flatten(args []string) error {
+	switch *format {
+	case "json":
+	case "ndjson", "csv":
+		if *inplace {
+			return fmt.Errorf("-i is not supported with -format=%s", *format)
+		}
+	default:
+		return fmt.Errorf("invalid format %q, expected one of: json, ndjson, csv", *format)
+	}
 	var filepaths []string
 	for _, arg := range args {
 		matches, err := filepath.Glob(arg)
@@ -63,9 +79,10 @@ func transform(filepath string) error {
 	}
 	out := make([]map[string][]any, 0, len(input.Events))
 	for _, event := range input.Events {
-		fields := make(map[string][]any)
-		flattenFields("", event, fields)
-		out = append(out, fields)
+		flattened := make(map[string][]any)
+		flattenFields("", event, flattened)
+		selectFields(flattened, *fields)
+		out = append(out, flattened)
 	}
 
 	var w io.Writer = os.Stdout
@@ -77,9 +94,106 @@ func transform(filepath string) error {
 		defer f.Close()
 		w = f
 	}
-	enc := json.NewEncoder(w)
-	enc.SetIndent("", "    ")
-	return enc.Encode(out)
+	return writeEvents(w, *format, out)
+}
+
+// writeEvents writes the flattened events to w in the given format.
+func writeEvents(w io.Writer, format string, events []map[string][]any) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "    ")
+		return enc.Encode(events)
+	case "ndjson":
+		enc := json.NewEncoder(w)
+		for _, event := range events {
+			if err := enc.Encode(event); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "csv":
+		return writeCSV(w, events)
+	}
+	return fmt.Errorf("invalid format %q", format)
+}
+
+// writeCSV writes the flattened events to w as CSV, with a header row
+// holding the sorted union of all field names, and one row per event.
+// Multi-valued fields are joined with ';'.
+func writeCSV(w io.Writer, events []map[string][]any) error {
+	keySet := make(map[string]struct{})
+	for _, event := range events {
+		for k := range event {
+			keySet[k] = struct{}{}
+		}
+	}
+	keys := make([]string, 0, len(keySet))
+	for k := range keySet {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(keys); err != nil {
+		return err
+	}
+	row := make([]string, len(keys))
+	for _, event := range events {
+		for i, k := range keys {
+			values := event[k]
+			formatted := make([]string, len(values))
+			for j, v := range values {
+				formatted[j] = formatCSVValue(v)
+			}
+			row[i] = strings.Join(formatted, ";")
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// formatCSVValue formats a field value for CSV: strings are written
+// as-is, and other values are written in their JSON encoding.
+func formatCSVValue(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// selectFields removes fields from the flattened event that do not match
+// the comma-separated patterns. Patterns ending in '*' match by prefix.
+// If patterns is empty, all fields are kept.
+func selectFields(event map[string][]any, patterns string) {
+	if patterns == "" {
+		return
+	}
+	split := strings.Split(patterns, ",")
+	for k := range event {
+		var matched bool
+		for _, pattern := range split {
+			pattern = strings.TrimSpace(pattern)
+			if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+				matched = strings.HasPrefix(k, prefix)
+			} else {
+				matched = k == pattern
+			}
+			if matched {
+				break
+			}
+		}
+		if !matched {
+			delete(event, k)
+		}
+	}
 }
 
 func flattenFields(k string, v any, out map[string][]any) {
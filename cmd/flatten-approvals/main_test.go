@@ -0,0 +1,118 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testApproved = `{
+  "events": [
+    {"service": {"name": "a", "version": "1.0"}, "labels": ["x", "y"], "count": 1},
+    {"service": {"name": "b"}, "ok": true}
+  ]
+}`
+
+func flattenTestEvents(t *testing.T) []map[string][]any {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.approved.json")
+	require.NoError(t, os.WriteFile(path, []byte(testApproved), 0644))
+
+	origFormat, origInplace := *format, *inplace
+	t.Cleanup(func() { *format, *inplace = origFormat, origInplace })
+	*format, *inplace = "json", true
+	require.NoError(t, flatten([]string{path}))
+
+	var events []map[string][]any
+	require.NoError(t, decodeJSONFile(path, &events))
+	return events
+}
+
+func TestWriteEventsJSON(t *testing.T) {
+	events := flattenTestEvents(t)
+	var buf bytes.Buffer
+	require.NoError(t, writeEvents(&buf, "json", events))
+	assert.JSONEq(t, `[
+	  {"service.name": ["a"], "service.version": ["1.0"], "labels": ["x", "y"], "count": [1]},
+	  {"service.name": ["b"], "ok": [true]}
+	]`, buf.String())
+}
+
+func TestWriteEventsNDJSON(t *testing.T) {
+	events := flattenTestEvents(t)
+	var buf bytes.Buffer
+	require.NoError(t, writeEvents(&buf, "ndjson", events))
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+	assert.JSONEq(t, `{"service.name": ["a"], "service.version": ["1.0"], "labels": ["x", "y"], "count": [1]}`, string(lines[0]))
+	assert.JSONEq(t, `{"service.name": ["b"], "ok": [true]}`, string(lines[1]))
+}
+
+func TestWriteEventsCSV(t *testing.T) {
+	events := flattenTestEvents(t)
+	var buf bytes.Buffer
+	require.NoError(t, writeEvents(&buf, "csv", events))
+	assert.Equal(t, ""+
+		"count,labels,ok,service.name,service.version\n"+
+		"1,x;y,,a,1.0\n"+
+		",,true,b,\n",
+		buf.String(),
+	)
+}
+
+func TestFlattenInvalidFormat(t *testing.T) {
+	origFormat, origInplace := *format, *inplace
+	t.Cleanup(func() { *format, *inplace = origFormat, origInplace })
+
+	*format, *inplace = "xml", false
+	assert.EqualError(t, flatten(nil), `invalid format "xml", expected one of: json, ndjson, csv`)
+
+	*format, *inplace = "csv", true
+	assert.EqualError(t, flatten(nil), "-i is not supported with -format=csv")
+}
+
+func TestFlattenFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.approved.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+	  "events": [{
+	    "trace": {"id": "abc"},
+	    "transaction": {"id": "def", "name": "GET /"},
+	    "transactions": 1,
+	    "service": {"name": "a"}
+	  }]
+	}`), 0644))
+
+	origFormat, origInplace, origFields := *format, *inplace, *fields
+	t.Cleanup(func() { *format, *inplace, *fields = origFormat, origInplace, origFields })
+	*format, *inplace, *fields = "json", true, "trace.id,transaction.*"
+	require.NoError(t, flatten([]string{path}))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{
+	  "trace.id": ["abc"],
+	  "transaction.id": ["def"],
+	  "transaction.name": ["GET /"]
+	}]`, string(data))
+}
@@ -0,0 +1,53 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"github.com/elastic/apm-tools/pkg/approvaltest"
+)
+
+func main() {
+	flag.Parse()
+	if err := sortApprovals(flag.Args()); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// sortApprovals re-sorts, in place, all approved files matching the
+// given glob patterns.
+func sortApprovals(args []string) error {
+	var filepaths []string
+	for _, arg := range args {
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return err
+		}
+		filepaths = append(filepaths, matches...)
+	}
+	for _, filepath := range filepaths {
+		if err := approvaltest.SortApprovedFile(filepath); err != nil {
+			return fmt.Errorf("error sorting %q: %w", filepath, err)
+		}
+	}
+	return nil
+}
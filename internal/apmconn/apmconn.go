@@ -0,0 +1,152 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package apmconn provides the connection settings shared by the
+// tracegen and metricgen packages for sending data to APM Server.
+package apmconn
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
+)
+
+// TLSOptions holds the TLS settings for connecting to APM Server.
+type TLSOptions struct {
+	// InsecureSkipVerify disables verification of the server certificate.
+	InsecureSkipVerify bool
+
+	// CertFile and KeyFile hold the paths of a PEM encoded client
+	// certificate and key, presented for mutual TLS authentication.
+	CertFile string
+	KeyFile  string
+
+	// CAFile holds the path of a PEM encoded CA certificate used to
+	// verify the server certificate, instead of the system roots.
+	CAFile string
+}
+
+// Config returns a tls.Config for the options, loading any configured
+// client certificate and CA certificate.
+func (o TLSOptions) Config() (*tls.Config, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: o.InsecureSkipVerify}
+	if o.CertFile != "" || o.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	if o.CAFile != "" {
+		caCert, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+		}
+		rootCAs := x509.NewCertPool()
+		if !rootCAs.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("failed to load CA certificate: no PEM certificates found in %s", o.CAFile)
+		}
+		tlsConfig.RootCAs = rootCAs
+	}
+	return tlsConfig, nil
+}
+
+// ProxyFunc returns a function for http.Transport.Proxy that uses the
+// proxy at proxyURL, or the environment's proxy if proxyURL is empty.
+func ProxyFunc(proxyURL string) (func(*http.Request) (*url.URL, error), error) {
+	if proxyURL == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse proxy URL: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("invalid proxy URL %q: must be prefixed with http://, https:// or socks5://", proxyURL)
+	}
+	return http.ProxyURL(u), nil
+}
+
+// GRPCOptions holds the settings for gRPC connections to APM Server.
+type GRPCOptions struct {
+	// KeepaliveTime enables keepalive pings, sent after this long
+	// without activity, if positive. This detects connections silently
+	// dropped by intermediaries such as load balancers. gRPC enforces
+	// a minimum of 10 seconds.
+	KeepaliveTime time.Duration
+
+	// KeepaliveTimeout is how long to wait for a keepalive ping
+	// response before closing the connection. gRPC defaults this
+	// to 20 seconds if zero.
+	KeepaliveTimeout time.Duration
+
+	// DialTimeout bounds the time taken to establish each connection,
+	// if positive.
+	DialTimeout time.Duration
+}
+
+// DialOptions returns the options for creating gRPC connections
+// with the given transport credentials.
+func (o GRPCOptions) DialOptions(transportCredentials credentials.TransportCredentials) []grpc.DialOption {
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(transportCredentials),
+		grpc.WithDefaultCallOptions(grpc.UseCompressor("gzip")),
+	}
+	if params, ok := o.keepaliveParams(); ok {
+		opts = append(opts, grpc.WithKeepaliveParams(params))
+	}
+	if params, ok := o.connectParams(); ok {
+		opts = append(opts, grpc.WithConnectParams(params))
+	}
+	return opts
+}
+
+// keepaliveParams returns the keepalive parameters for gRPC
+// connections, and whether keepalive has been configured.
+func (o GRPCOptions) keepaliveParams() (keepalive.ClientParameters, bool) {
+	if o.KeepaliveTime <= 0 {
+		return keepalive.ClientParameters{}, false
+	}
+	return keepalive.ClientParameters{
+		Time:                o.KeepaliveTime,
+		Timeout:             o.KeepaliveTimeout,
+		PermitWithoutStream: true,
+	}, true
+}
+
+// connectParams returns the connection parameters for gRPC
+// connections, and whether a dial timeout has been configured.
+func (o GRPCOptions) connectParams() (grpc.ConnectParams, bool) {
+	if o.DialTimeout <= 0 {
+		return grpc.ConnectParams{}, false
+	}
+	return grpc.ConnectParams{
+		Backoff:           backoff.DefaultConfig,
+		MinConnectTimeout: o.DialTimeout,
+	}, true
+}
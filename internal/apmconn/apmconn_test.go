@@ -0,0 +1,137 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package apmconn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/backoff"
+	grpcinsecure "google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+)
+
+func TestTLSOptionsConfig(t *testing.T) {
+	certFile, keyFile := writeTestCertificate(t)
+	tlsConfig, err := TLSOptions{CertFile: certFile, KeyFile: keyFile, CAFile: certFile}.Config()
+	require.NoError(t, err)
+	assert.False(t, tlsConfig.InsecureSkipVerify)
+	require.Len(t, tlsConfig.Certificates, 1)
+	require.NotNil(t, tlsConfig.RootCAs)
+
+	certPEM, err := os.ReadFile(certFile)
+	require.NoError(t, err)
+	expectedRootCAs := x509.NewCertPool()
+	require.True(t, expectedRootCAs.AppendCertsFromPEM(certPEM))
+	assert.True(t, expectedRootCAs.Equal(tlsConfig.RootCAs))
+
+	tlsConfig, err = TLSOptions{InsecureSkipVerify: true}.Config()
+	require.NoError(t, err)
+	assert.True(t, tlsConfig.InsecureSkipVerify)
+	assert.Empty(t, tlsConfig.Certificates)
+	assert.Nil(t, tlsConfig.RootCAs)
+
+	_, err = TLSOptions{CertFile: certFile, KeyFile: "missing.pem"}.Config()
+	assert.ErrorContains(t, err, "failed to load client certificate")
+	_, err = TLSOptions{CAFile: "missing.pem"}.Config()
+	assert.ErrorContains(t, err, "failed to read CA certificate")
+	_, err = TLSOptions{CAFile: keyFile}.Config()
+	assert.ErrorContains(t, err, "failed to load CA certificate")
+}
+
+func TestProxyFunc(t *testing.T) {
+	_, err := ProxyFunc("ftp://proxy.invalid")
+	assert.EqualError(t, err, `invalid proxy URL "ftp://proxy.invalid": must be prefixed with http://, https:// or socks5://`)
+
+	proxy, err := ProxyFunc("http://proxy.invalid:3128")
+	require.NoError(t, err)
+	req, err := http.NewRequest("GET", "http://apm.invalid:8200", nil)
+	require.NoError(t, err)
+	u, err := proxy(req)
+	require.NoError(t, err)
+	assert.Equal(t, "http://proxy.invalid:3128", u.String())
+
+	t.Setenv("HTTP_PROXY", "http://env-proxy.invalid:3128")
+	proxy, err = ProxyFunc("")
+	require.NoError(t, err)
+	u, err = proxy(req)
+	require.NoError(t, err)
+	assert.Equal(t, "http://env-proxy.invalid:3128", u.String())
+}
+
+func TestGRPCOptionsDialOptions(t *testing.T) {
+	opts := GRPCOptions{KeepaliveTime: 30 * time.Second, KeepaliveTimeout: 5 * time.Second, DialTimeout: 3 * time.Second}
+	keepaliveParams, ok := opts.keepaliveParams()
+	require.True(t, ok)
+	assert.Equal(t, keepalive.ClientParameters{
+		Time:                30 * time.Second,
+		Timeout:             5 * time.Second,
+		PermitWithoutStream: true,
+	}, keepaliveParams)
+	connectParams, ok := opts.connectParams()
+	require.True(t, ok)
+	assert.Equal(t, 3*time.Second, connectParams.MinConnectTimeout)
+	assert.Equal(t, backoff.DefaultConfig, connectParams.Backoff)
+	assert.Len(t, opts.DialOptions(grpcinsecure.NewCredentials()), 4)
+
+	opts = GRPCOptions{}
+	_, ok = opts.keepaliveParams()
+	assert.False(t, ok)
+	_, ok = opts.connectParams()
+	assert.False(t, ok)
+	assert.Len(t, opts.DialOptions(grpcinsecure.NewCredentials()), 2)
+}
+
+// writeTestCertificate writes a self-signed certificate and its key
+// to PEM files, returning their paths.
+func writeTestCertificate(t testing.TB) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "apm-tools"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	return certFile, keyFile
+}
@@ -0,0 +1,139 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package apmclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/security/invalidateapikey"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/security/queryapikeys"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/sortorder"
+)
+
+// apiKeysPageSize holds the number of API Keys requested per
+// page by ListAgentAPIKeys.
+const apiKeysPageSize = 1000
+
+// ListAgentAPIKeys returns the valid (not invalidated) agent API Keys
+// created by CreateAgentAPIKey.
+func (c *Client) ListAgentAPIKeys(ctx context.Context) ([]APIKeyInfo, error) {
+	var out []APIKeyInfo
+	var searchAfter []types.FieldValue
+	for {
+		page, err := c.queryAgentAPIKeys(ctx, searchAfter)
+		if err != nil {
+			return nil, err
+		}
+		keys := page.Get("api_keys").Array()
+		for _, key := range keys {
+			info := APIKeyInfo{
+				ID:   key.Get("id").String(),
+				Name: key.Get("name").String(),
+			}
+			if creation := key.Get("creation"); creation.Exists() {
+				info.Creation = time.UnixMilli(creation.Int()).UTC()
+			}
+			if expiration := key.Get("expiration"); expiration.Exists() {
+				info.Expiration = time.UnixMilli(expiration.Int()).UTC()
+			}
+			if metadata, ok := key.Get("metadata").Value().(map[string]any); ok {
+				info.Metadata = metadata
+			}
+			out = append(out, info)
+		}
+		if len(keys) < apiKeysPageSize {
+			break
+		}
+		searchAfter = nil
+		for _, v := range keys[len(keys)-1].Get("_sort").Array() {
+			searchAfter = append(searchAfter, v.Value())
+		}
+	}
+	return out, nil
+}
+
+// queryAgentAPIKeys returns a page of valid agent API Keys,
+// sorted by creation time, following the given search_after values.
+func (c *Client) queryAgentAPIKeys(ctx context.Context, searchAfter []types.FieldValue) (gjson.Result, error) {
+	size := apiKeysPageSize
+	resp, err := c.es.Security.QueryApiKeys().Request(&queryapikeys.Request{
+		Size:        &size,
+		SearchAfter: searchAfter,
+		Sort: []types.SortCombinations{
+			types.SortOptions{SortOptions: map[string]types.FieldSort{"creation": {Order: &sortorder.Asc}}},
+			types.SortOptions{SortOptions: map[string]types.FieldSort{"_doc": {Order: &sortorder.Asc}}},
+		},
+		Query: &types.ApiKeyQueryContainer{
+			Bool: &types.BoolQuery{
+				Filter: []types.Query{
+					{Term: map[string]types.TermQuery{"metadata.application": {Value: "apm"}}},
+					{Term: map[string]types.TermQuery{"metadata.creator": {Value: "apmclient"}}},
+					{Term: map[string]types.TermQuery{"invalidated": {Value: false}}},
+				},
+			},
+		},
+	}).Perform(ctx)
+	if err != nil {
+		return gjson.Result{}, fmt.Errorf("error querying API Keys: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return gjson.Result{}, fmt.Errorf("error reading API Keys response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return gjson.Result{}, fmt.Errorf(
+			"error querying API Keys: %s: %s", resp.Status,
+			gjson.GetBytes(body, "error.reason").String(),
+		)
+	}
+	return gjson.ParseBytes(body), nil
+}
+
+// InvalidateAgentAPIKeys invalidates the API Keys with the given IDs.
+func (c *Client) InvalidateAgentAPIKeys(ctx context.Context, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	resp, err := c.es.Security.InvalidateApiKey().Request(&invalidateapikey.Request{
+		Ids: ids,
+	}).Do(ctx)
+	if err != nil {
+		return fmt.Errorf("error invalidating API Keys: %w", err)
+	}
+	if resp.ErrorCount > 0 {
+		errs := make([]error, len(resp.ErrorDetails))
+		for i, cause := range resp.ErrorDetails {
+			var reason string
+			if cause.Reason != nil {
+				reason = *cause.Reason
+			}
+			errs[i] = fmt.Errorf("%s: %s", cause.Type, reason)
+		}
+		return fmt.Errorf("error invalidating %d API Keys: %w", resp.ErrorCount, errors.Join(errs...))
+	}
+	return nil
+}
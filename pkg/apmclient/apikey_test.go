@@ -0,0 +1,238 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package apmclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+
+	"github.com/elastic/apm-tools/pkg/apmclient"
+)
+
+func TestInvalidateAgentAPIKeys(t *testing.T) {
+	var body map[string]any
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodDelete, r.Method)
+		assert.Equal(t, "/_security/api_key", r.URL.Path)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		w.Write([]byte(`{
+		  "invalidated_api_keys": ["a", "b"],
+		  "previously_invalidated_api_keys": [],
+		  "error_count": 0
+		}`))
+	})
+	err := client.InvalidateAgentAPIKeys(context.Background(), "a", "b")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"ids": []any{"a", "b"}}, body)
+}
+
+func TestInvalidateAgentAPIKeysError(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{
+		  "invalidated_api_keys": ["a"],
+		  "previously_invalidated_api_keys": [],
+		  "error_count": 1,
+		  "error_details": [{"type": "exception", "reason": "error invalidating b"}]
+		}`))
+	})
+	err := client.InvalidateAgentAPIKeys(context.Background(), "a", "b")
+	assert.EqualError(t, err, "error invalidating 1 API Keys: exception: error invalidating b")
+}
+
+func TestListAgentAPIKeys(t *testing.T) {
+	var body map[string]any
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/_security/_query/api_key", r.URL.Path)
+		body = nil
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		w.Write([]byte(`{
+		  "total": 2,
+		  "count": 2,
+		  "api_keys": [
+		    {"id": "a", "name": "apm-agent", "creation": 1000, "invalidated": false, "metadata": {"application": "apm", "creator": "apmclient"}, "_sort": [1000, 0]},
+		    {"id": "b", "name": "apm-agent", "creation": 2000, "expiration": 3000, "invalidated": false, "metadata": {"application": "apm", "creator": "apmclient"}, "_sort": [2000, 1]}
+		  ]
+		}`))
+	})
+	keys, err := client.ListAgentAPIKeys(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"bool": map[string]any{
+			"filter": []any{
+				map[string]any{"term": map[string]any{"metadata.application": map[string]any{"value": "apm"}}},
+				map[string]any{"term": map[string]any{"metadata.creator": map[string]any{"value": "apmclient"}}},
+				map[string]any{"term": map[string]any{"invalidated": map[string]any{"value": false}}},
+			},
+		},
+	}, body["query"])
+	assert.NotContains(t, body, "search_after")
+	metadata := map[string]any{"application": "apm", "creator": "apmclient"}
+	assert.Equal(t, []apmclient.APIKeyInfo{{
+		ID:       "a",
+		Name:     "apm-agent",
+		Creation: time.UnixMilli(1000).UTC(),
+		Metadata: metadata,
+	}, {
+		ID:         "b",
+		Name:       "apm-agent",
+		Creation:   time.UnixMilli(2000).UTC(),
+		Expiration: time.UnixMilli(3000).UTC(),
+		Metadata:   metadata,
+	}}, keys)
+}
+
+func TestListAgentAPIKeysPaginated(t *testing.T) {
+	const numKeys = 1500
+	var searchAfters [][]any
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Size        int   `json:"size"`
+			SearchAfter []any `json:"search_after"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		searchAfters = append(searchAfters, body.SearchAfter)
+
+		start := 0
+		if len(body.SearchAfter) > 0 {
+			start = int(body.SearchAfter[1].(float64)) + 1
+		}
+		end := min(start+body.Size, numKeys)
+		var apiKeys []map[string]any
+		for i := start; i < end; i++ {
+			apiKeys = append(apiKeys, map[string]any{
+				"id":       fmt.Sprint(i),
+				"name":     "apm-agent",
+				"creation": 1000,
+				"_sort":    []any{1000, i},
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]any{"count": len(apiKeys), "api_keys": apiKeys})
+	})
+	keys, err := client.ListAgentAPIKeys(context.Background())
+	require.NoError(t, err)
+	require.Len(t, keys, numKeys)
+	for i, key := range keys {
+		assert.Equal(t, fmt.Sprint(i), key.ID)
+	}
+	assert.Equal(t, [][]any{nil, {1000.0, 999.0}}, searchAfters)
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	var body map[string]any
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/_security/api_key", r.URL.Path)
+		body = nil
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		w.Write([]byte(`{"id": "a", "name": "apm-agent", "api_key": "secret", "encoded": "ZW5jb2RlZA=="}`))
+	})
+
+	encoded, err := client.CreateAPIKey(context.Background(), apmclient.CreateAPIKeyOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "ZW5jb2RlZA==", encoded)
+	assert.Equal(t, "apm-agent", body["name"])
+	assert.NotContains(t, body, "expiration")
+	assert.Equal(t, map[string]any{
+		"apm": map[string]any{
+			"applications": []any{map[string]any{
+				"application": "apm",
+				"resources":   []any{"*"},
+				"privileges":  []any{"event:write", "config_agent:read"},
+			}},
+		},
+	}, body["role_descriptors"])
+
+	_, err = client.CreateAPIKey(context.Background(), apmclient.CreateAPIKeyOptions{
+		Name:       "sourcemaps",
+		Expiration: time.Hour,
+		RoleDescriptors: map[string]types.RoleDescriptor{
+			"sourcemaps": {
+				Applications: []types.ApplicationPrivileges{{
+					Application: "apm",
+					Resources:   []string{"*"},
+					Privileges:  []string{"sourcemap:write"},
+				}},
+			},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "sourcemaps", body["name"])
+	assert.Equal(t, "1h", body["expiration"])
+	assert.Equal(t, map[string]any{
+		"sourcemaps": map[string]any{
+			"applications": []any{map[string]any{
+				"application": "apm",
+				"resources":   []any{"*"},
+				"privileges":  []any{"sourcemap:write"},
+			}},
+		},
+	}, body["role_descriptors"])
+}
+
+func TestCreateAPIKeyMetadata(t *testing.T) {
+	// The server stores the metadata of created API Keys,
+	// and returns it when they are queried.
+	var created []map[string]any
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/_security/api_key":
+			var body struct {
+				Metadata map[string]any `json:"metadata"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			created = append(created, body.Metadata)
+			w.Write([]byte(`{"id": "a", "name": "apm-agent", "api_key": "secret", "encoded": "ZW5jb2RlZA=="}`))
+		case "/_security/_query/api_key":
+			var apiKeys []map[string]any
+			for i, metadata := range created {
+				apiKeys = append(apiKeys, map[string]any{
+					"id": fmt.Sprint(i), "name": "apm-agent", "creation": 1000,
+					"metadata": metadata, "_sort": []any{1000, i},
+				})
+			}
+			json.NewEncoder(w).Encode(map[string]any{"count": len(apiKeys), "api_keys": apiKeys})
+		}
+	})
+
+	_, err := client.CreateAPIKey(context.Background(), apmclient.CreateAPIKeyOptions{
+		Metadata: map[string]any{
+			"test_run":    "run-123",
+			"labels":      []string{"a", "b"},
+			"application": "overridden",
+		},
+	})
+	require.NoError(t, err)
+
+	keys, err := client.ListAgentAPIKeys(context.Background())
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Equal(t, map[string]any{
+		"application": "apm",
+		"creator":     "apmclient",
+		"test_run":    "run-123",
+		"labels":      []any{"a", "b"},
+	}, keys[0].Metadata)
+}
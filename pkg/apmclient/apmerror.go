@@ -0,0 +1,77 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package apmclient
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/search"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/sortorder"
+)
+
+// RecentErrors returns up to limit of the most recent errors
+// reported by the given service, ordered by descending timestamp.
+func (c *Client) RecentErrors(ctx context.Context, service string, limit int) ([]APMError, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("invalid limit %d, must be > 0", limit)
+	}
+	resp, err := c.es.Search().Index("logs-apm.error-*").Request(&search.Request{
+		Size: &limit,
+		Sort: []types.SortCombinations{
+			types.SortOptions{
+				SortOptions: map[string]types.FieldSort{
+					"@timestamp": {Order: &sortorder.Desc},
+				},
+			},
+		},
+		Query: &types.Query{
+			Bool: &types.BoolQuery{
+				Filter: []types.Query{{
+					Term: map[string]types.TermQuery{
+						"service.name": {Value: service},
+					},
+				}},
+			},
+		},
+	}).Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error searching logs-apm.error-*: %w", err)
+	}
+
+	out := make([]APMError, len(resp.Hits.Hits))
+	for i, hit := range resp.Hits.Hits {
+		source := gjson.ParseBytes(hit.Source_)
+		message := source.Get("error.exception.0.message")
+		if !message.Exists() {
+			message = source.Get("error.log.message")
+		}
+		timestamp, _ := time.Parse(time.RFC3339Nano, source.Get("@timestamp").String())
+		out[i] = APMError{
+			ID:          source.Get("error.id").String(),
+			GroupingKey: source.Get("error.grouping_key").String(),
+			Message:     message.String(),
+			Timestamp:   timestamp,
+		}
+	}
+	return out, nil
+}
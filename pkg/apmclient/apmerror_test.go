@@ -0,0 +1,84 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package apmclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/apm-tools/pkg/apmclient"
+)
+
+func TestRecentErrors(t *testing.T) {
+	var body map[string]any
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/logs-apm.error-*/_search", r.URL.Path)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		w.Write([]byte(`{
+		  "hits": {
+		    "total": {"value": 2, "relation": "eq"},
+		    "hits": [
+		      {"_index": "logs-apm.error-default", "_id": "1", "_source": {
+		        "@timestamp": "2024-05-01T10:00:02.5Z",
+		        "error": {
+		          "id": "err2", "grouping_key": "group1",
+		          "exception": [{"message": "boom", "type": "RuntimeError"}]
+		        }
+		      }},
+		      {"_index": "logs-apm.error-default", "_id": "2", "_source": {
+		        "@timestamp": "2024-05-01T10:00:01Z",
+		        "error": {"id": "err1", "grouping_key": "group2", "log": {"message": "logged"}}
+		      }}
+		    ]
+		  }
+		}`))
+	})
+
+	apmErrors, err := client.RecentErrors(context.Background(), "frontend", 5)
+	require.NoError(t, err)
+	assert.Equal(t, float64(5), body["size"])
+	assert.Equal(t, []any{map[string]any{"@timestamp": map[string]any{"order": "desc"}}}, body["sort"])
+	assert.Equal(t, map[string]any{"bool": map[string]any{"filter": []any{
+		map[string]any{"term": map[string]any{"service.name": map[string]any{"value": "frontend"}}},
+	}}}, body["query"])
+
+	assert.Equal(t, []apmclient.APMError{{
+		ID:          "err2",
+		GroupingKey: "group1",
+		Message:     "boom",
+		Timestamp:   time.Date(2024, 5, 1, 10, 0, 2, 500000000, time.UTC),
+	}, {
+		ID:          "err1",
+		GroupingKey: "group2",
+		Message:     "logged",
+		Timestamp:   time.Date(2024, 5, 1, 10, 0, 1, 0, time.UTC),
+	}}, apmErrors)
+}
+
+func TestRecentErrorsInvalidLimit(t *testing.T) {
+	client, err := apmclient.New(apmclient.Config{ElasticsearchURL: "http://localhost:9200"})
+	require.NoError(t, err)
+	_, err = client.RecentErrors(context.Background(), "frontend", 0)
+	assert.EqualError(t, err, "invalid limit 0, must be > 0")
+}
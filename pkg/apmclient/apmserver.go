@@ -0,0 +1,77 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package apmclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrUnauthorized is returned by APMServerInfo when
+// APM Server rejects or does not receive valid credentials.
+var ErrUnauthorized = errors.New("unauthorized")
+
+// APMServerInfo returns the build information of the configured APM Server,
+// as reported by its root endpoint.
+//
+// The agent API Key or secret token, at most one of which should be
+// non-empty, is used to authenticate with APM Server. These are distinct
+// from any Elasticsearch credentials in Config. APM Server omits build
+// information for unauthenticated requests, so if APM Server responds
+// with 401 Unauthorized, or with no version, APMServerInfo returns an
+// error satisfying errors.Is(err, ErrUnauthorized).
+func (c *Client) APMServerInfo(ctx context.Context, apiKey, secretToken string) (APMServerInfo, error) {
+	if c.apmServerURL == "" {
+		return APMServerInfo{}, errors.New("APM Server URL is not configured")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.apmServerURL, nil)
+	if err != nil {
+		return APMServerInfo{}, fmt.Errorf("error creating HTTP request: %w", err)
+	}
+	switch {
+	case apiKey != "":
+		req.Header.Set("Authorization", "ApiKey "+apiKey)
+	case secretToken != "":
+		req.Header.Set("Authorization", "Bearer "+secretToken)
+	}
+
+	resp, err := c.apmServerHTTPClient.Do(req)
+	if err != nil {
+		return APMServerInfo{}, fmt.Errorf("error connecting to APM Server: %w", err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized:
+		return APMServerInfo{}, fmt.Errorf("error getting APM Server info: %w", ErrUnauthorized)
+	default:
+		return APMServerInfo{}, fmt.Errorf("error getting APM Server info; server responded with %q", resp.Status)
+	}
+
+	var info APMServerInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return APMServerInfo{}, fmt.Errorf("error decoding APM Server info: %w", err)
+	}
+	if info.Version == "" {
+		return APMServerInfo{}, fmt.Errorf("error getting APM Server info; no version in response: %w", ErrUnauthorized)
+	}
+	return info, nil
+}
@@ -0,0 +1,91 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package apmclient_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/apm-tools/pkg/apmclient"
+)
+
+func TestAPMServerInfo(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/", r.URL.Path)
+		switch r.Header.Get("Authorization") {
+		case "ApiKey secret", "Bearer token":
+			w.Write([]byte(`{
+			  "build_date": "2024-11-12T18:43:35Z",
+			  "build_sha": "0123456789abcdef",
+			  "publish_ready": true,
+			  "version": "8.16.1"
+			}`))
+		case "":
+			// APM Server omits build information for anonymous requests.
+			w.Write([]byte(`{"publish_ready": true}`))
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := apmclient.New(apmclient.Config{
+		ElasticsearchURL: "http://localhost:9200",
+		APMServerURL:     srv.URL,
+		APIKey:           "elasticsearch_api_key",
+	})
+	require.NoError(t, err)
+	expected := apmclient.APMServerInfo{
+		BuildDate: time.Date(2024, 11, 12, 18, 43, 35, 0, time.UTC),
+		BuildSHA:  "0123456789abcdef",
+		Version:   "8.16.1",
+	}
+
+	info, err := client.APMServerInfo(context.Background(), "secret", "")
+	require.NoError(t, err)
+	assert.Equal(t, expected, info)
+
+	info, err = client.APMServerInfo(context.Background(), "", "token")
+	require.NoError(t, err)
+	assert.Equal(t, expected, info)
+
+	_, err = client.APMServerInfo(context.Background(), "wrong", "")
+	assert.ErrorIs(t, err, apmclient.ErrUnauthorized)
+
+	// The Elasticsearch API Key must not be sent to APM Server.
+	_, err = client.APMServerInfo(context.Background(), "", "")
+	assert.ErrorIs(t, err, apmclient.ErrUnauthorized)
+}
+
+func TestAPMServerInfoConnectionError(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+
+	client, err := apmclient.New(apmclient.Config{ElasticsearchURL: "http://localhost:9200", APMServerURL: srv.URL})
+	require.NoError(t, err)
+	_, err = client.APMServerInfo(context.Background(), "", "")
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, apmclient.ErrUnauthorized)
+	assert.ErrorContains(t, err, "error connecting to APM Server")
+}
@@ -36,28 +36,44 @@ import (
 
 type Client struct {
 	es *elasticsearch.TypedClient
+
+	apmServerURL        string
+	apmServerHTTPClient *http.Client
 }
 
 // New returns a new Client for querying APM data.
 func New(cfg Config) (*Client, error) {
-	transport := http.DefaultTransport.(*http.Transport).Clone()
-	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: cfg.TLSSkipVerify}
-
 	es, err := elasticsearch.NewTypedClient(elasticsearch.Config{
 		Addresses: []string{cfg.ElasticsearchURL},
 		Username:  cfg.Username,
 		APIKey:    cfg.APIKey,
 		Password:  cfg.Password,
-		Transport: transport,
+		Transport: newTransport(cfg, cfg.ElasticsearchTLSSkipVerify()),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("error creating Elasticsearch client: %w", err)
 	}
 	return &Client{
-		es: es,
+		es:           es,
+		apmServerURL: cfg.APMServerURL,
+		apmServerHTTPClient: &http.Client{
+			Transport: newTransport(cfg, cfg.APMServerTLSSkipVerify()),
+		},
 	}, nil
 }
 
+// newTransport returns cfg.Transport if specified, or otherwise
+// a new transport which skips TLS certificate verification if
+// tlsSkipVerify is true.
+func newTransport(cfg Config, tlsSkipVerify bool) http.RoundTripper {
+	if cfg.Transport != nil {
+		return cfg.Transport
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: tlsSkipVerify}
+	return transport
+}
+
 // GetElasticCloudAPMInput returns the APM configuration as defined
 // in the "elastic-cloud-apm" integration policy,
 func (c *Client) GetElasticCloudAPMInput(ctx context.Context) (gjson.Result, error) {
@@ -97,15 +113,44 @@ func (c *Client) GetElasticCloudAPMInput(ctx context.Context) (gjson.Result, err
 //
 // If expiration is less than or equal to zero, then the API Key never expires.
 func (c *Client) CreateAgentAPIKey(ctx context.Context, expiration time.Duration) (string, error) {
-	name := "apm-agent"
+	return c.CreateAPIKey(ctx, CreateAPIKeyOptions{Expiration: expiration})
+}
+
+// CreateAPIKeyOptions holds options for CreateAPIKey.
+type CreateAPIKeyOptions struct {
+	// Name holds the name of the API Key. Defaults to "apm-agent".
+	Name string
+
+	// Expiration holds the duration after which the API Key expires.
+	// If less than or equal to zero, then the API Key never expires.
+	Expiration time.Duration
+
+	// RoleDescriptors holds the role descriptors of the API Key.
+	//
+	// If unspecified, the API Key is granted the event:write and
+	// config_agent:read APM application privileges required by agents.
+	RoleDescriptors map[string]types.RoleDescriptor
+
+	// Metadata holds additional metadata for the API Key, e.g. to tag
+	// it for later cleanup. The "application" and "creator" metadata
+	// identifying keys listed by ListAgentAPIKeys cannot be overridden.
+	Metadata map[string]any
+}
+
+// CreateAPIKey creates an API Key with the given options, and returns
+// it in the base64-encoded form that agents should provide.
+func (c *Client) CreateAPIKey(ctx context.Context, opts CreateAPIKeyOptions) (string, error) {
+	name := opts.Name
+	if name == "" {
+		name = "apm-agent"
+	}
 	var maybeExpiration types.Duration
-	if expiration > 0 {
-		maybeExpiration = formatDurationElasticsearch(expiration)
+	if opts.Expiration > 0 {
+		maybeExpiration = formatDurationElasticsearch(opts.Expiration)
 	}
-	resp, err := c.es.Security.CreateApiKey().Request(&createapikey.Request{
-		Name:       &name,
-		Expiration: maybeExpiration,
-		RoleDescriptors: map[string]types.RoleDescriptor{
+	roleDescriptors := opts.RoleDescriptors
+	if roleDescriptors == nil {
+		roleDescriptors = map[string]types.RoleDescriptor{
 			"apm": {
 				Applications: []types.ApplicationPrivileges{{
 					Application: "apm",
@@ -113,22 +158,37 @@ func (c *Client) CreateAgentAPIKey(ctx context.Context, expiration time.Duration
 					Privileges:  []string{"event:write", "config_agent:read"},
 				}},
 			},
-		},
-		Metadata: map[string]json.RawMessage{
-			"application": []byte(`"apm"`),
-			"creator":     []byte(`"apmclient"`),
-		},
+		}
+	}
+	metadata := make(map[string]json.RawMessage, len(opts.Metadata)+2)
+	for k, v := range opts.Metadata {
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return "", fmt.Errorf("error encoding API Key metadata %q: %w", k, err)
+		}
+		metadata[k] = encoded
+	}
+	metadata["application"] = []byte(`"apm"`)
+	metadata["creator"] = []byte(`"apmclient"`)
+	resp, err := c.es.Security.CreateApiKey().Request(&createapikey.Request{
+		Name:            &name,
+		Expiration:      maybeExpiration,
+		RoleDescriptors: roleDescriptors,
+		Metadata:        metadata,
 	}).Do(ctx)
 	if err != nil {
-		return "", fmt.Errorf("error creating agent API Key: %w", err)
+		return "", fmt.Errorf("error creating API Key: %w", err)
 	}
 	return resp.Encoded, nil
 }
 
 // ServiceSummary returns ServiceSummary objects by aggregating `service_summary` metric sets.
+//
+// The metric sets aggregated may be restricted with WithTimeRange.
 func (c *Client) ServiceSummary(ctx context.Context, options ...Option) ([]ServiceSummary, error) {
-	// TODO options
+	opts := newOptions(options)
 	req := &search.Request{
+		Query: opts.timeRangeQuery(),
 		Aggregations: map[string]types.Aggregations{
 			"services": {
 				MultiTerms: &types.MultiTermsAggregation{
@@ -151,7 +211,7 @@ func (c *Client) ServiceSummary(ctx context.Context, options ...Option) ([]Servi
 		Index("metrics-apm.service_summary.1m-*").
 		Size(0).Request(req).Do(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("error search service_summmary metrics")
+		return nil, fmt.Errorf("error searching service_summary metrics: %w", err)
 	}
 
 	servicesAggregation := resp.Aggregations["services"].(*types.MultiTermsAggregate)
@@ -168,6 +228,41 @@ func (c *Client) ServiceSummary(ctx context.Context, options ...Option) ([]Servi
 	return out, nil
 }
 
+// WaitForService polls ServiceSummary every interval until a service with
+// the given name is found, returning its summary, or until ctx is done.
+// The options are passed to ServiceSummary, e.g. to set a time range.
+func (c *Client) WaitForService(
+	ctx context.Context,
+	name string,
+	interval time.Duration,
+	options ...Option,
+) (ServiceSummary, error) {
+	var lastErr error
+	for {
+		services, err := c.ServiceSummary(ctx, options...)
+		if err == nil {
+			for _, service := range services {
+				if service.Name == name {
+					return service, nil
+				}
+			}
+		}
+		// Ignore errors from requests interrupted by ctx being done,
+		// preferring to report an earlier search error, if any.
+		if ctx.Err() == nil {
+			lastErr = err
+		}
+		select {
+		case <-ctx.Done():
+			if lastErr != nil {
+				return ServiceSummary{}, fmt.Errorf("service %q not found: %w", name, lastErr)
+			}
+			return ServiceSummary{}, fmt.Errorf("service %q not found: %w", name, ctx.Err())
+		case <-time.After(interval):
+		}
+	}
+}
+
 var elasticsearchTimeUnits = []struct {
 	Duration time.Duration
 	Unit     string
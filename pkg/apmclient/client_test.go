@@ -0,0 +1,191 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package apmclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/apm-tools/pkg/apmclient"
+)
+
+// recordingTransport is an http.RoundTripper that records
+// requests, responding to each with an empty JSON object.
+type recordingTransport struct {
+	mu       sync.Mutex
+	requests []string
+}
+
+func (rt *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.requests = append(rt.requests, req.Method+" "+req.URL.Host+req.URL.Path)
+	header := make(http.Header)
+	header.Set("X-Elastic-Product", "Elasticsearch")
+	header.Set("Content-Type", "application/json")
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader(`{}`)),
+		Request:    req,
+	}, nil
+}
+
+func TestNewTransport(t *testing.T) {
+	var transport recordingTransport
+	client, err := apmclient.New(apmclient.Config{
+		ElasticsearchURL: "http://elasticsearch.invalid:9200",
+		APMServerURL:     "http://apm.invalid:8200",
+		Transport:        &transport,
+	})
+	require.NoError(t, err)
+
+	err = client.InvalidateAgentAPIKeys(context.Background(), "a")
+	require.NoError(t, err)
+	// The empty response has no version, so APMServerInfo
+	// fails; only the request made through the transport matters.
+	_, err = client.APMServerInfo(context.Background(), "", "")
+	require.Error(t, err)
+
+	assert.Equal(t, []string{
+		"DELETE elasticsearch.invalid:9200/_security/api_key",
+		"GET apm.invalid:8200",
+	}, transport.requests)
+}
+
+func TestNewTLSSkipVerify(t *testing.T) {
+	// Serve both Elasticsearch and APM Server with a self-signed certificate.
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/_security/api_key":
+			w.Write([]byte(`{}`))
+		default:
+			w.Write([]byte(`{"version": "8.16.1"}`))
+		}
+	}))
+	defer srv.Close()
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+
+	for name, tc := range map[string]struct {
+		cfg                 apmclient.Config
+		esErr, apmServerErr bool
+	}{
+		"verify": {
+			esErr: true, apmServerErr: true,
+		},
+		"insecure": {
+			cfg: apmclient.Config{TLSSkipVerify: true},
+		},
+		"es_insecure": {
+			cfg:          apmclient.Config{ESTLSSkipVerify: true},
+			apmServerErr: true,
+		},
+		"apm_insecure": {
+			cfg:   apmclient.Config{APMTLSSkipVerify: true},
+			esErr: true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg := tc.cfg
+			cfg.ElasticsearchURL = srv.URL
+			cfg.APMServerURL = srv.URL
+			client, err := apmclient.New(cfg)
+			require.NoError(t, err)
+
+			err = client.InvalidateAgentAPIKeys(context.Background(), "a")
+			if tc.esErr {
+				assert.ErrorContains(t, err, "certificate")
+			} else {
+				assert.NoError(t, err)
+			}
+			_, err = client.APMServerInfo(context.Background(), "secret", "")
+			if tc.apmServerErr {
+				assert.ErrorContains(t, err, "certificate")
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestWaitForService(t *testing.T) {
+	var queries []json.RawMessage
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Query json.RawMessage `json:"query"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		queries = append(queries, body.Query)
+
+		// Only return the requested service on the third poll.
+		buckets := `{"key": ["backend", "", "go", "go"], "doc_count": 1}`
+		if len(queries) >= 3 {
+			buckets += `, {"key": ["frontend", "production", "javascript", "rum-js"], "doc_count": 1}`
+		}
+		fmt.Fprintf(w, `{
+		  "hits": {"total": {"value": 0, "relation": "eq"}, "hits": []},
+		  "aggregations": {"multi_terms#services": {"buckets": [%s]}}
+		}`, buckets)
+	})
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	service, err := client.WaitForService(context.Background(), "frontend", time.Millisecond,
+		apmclient.WithTimeRange(start, time.Time{}),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, apmclient.ServiceSummary{
+		Name:        "frontend",
+		Environment: "production",
+		Language:    "javascript",
+		Agent:       "rum-js",
+	}, service)
+
+	require.Len(t, queries, 3)
+	assert.JSONEq(t, `{"range": {"@timestamp": {
+	  "format": "strict_date_optional_time",
+	  "gte": "2024-01-01T00:00:00Z"
+	}}}`, string(queries[0]))
+}
+
+func TestWaitForServiceTimeout(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{
+		  "hits": {"total": {"value": 0, "relation": "eq"}, "hits": []},
+		  "aggregations": {"multi_terms#services": {"buckets": []}}
+		}`))
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := client.WaitForService(ctx, "frontend", time.Millisecond)
+	assert.EqualError(t, err, `service "frontend" not found: context deadline exceeded`)
+}
@@ -18,13 +18,40 @@
 package apmclient
 
 import (
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
+
+	"gopkg.in/yaml.v3"
 )
 
+// defaultConfigFile holds the name of the config file looked
+// up in the user's home directory if ConfigFile is unspecified.
+const defaultConfigFile = ".apmtool.yaml"
+
+// defaultProfile holds the name of the config file
+// profile used if Profile is unspecified.
+const defaultProfile = "default"
+
 type Config struct {
+	// CloudID holds an Elastic Cloud ID, from which ElasticsearchURL,
+	// KibanaURL, and APMServerURL are derived if they are unspecified.
+	//
+	// APMServerURL can only be derived from cloud IDs that include an
+	// APM Server UUID as a fourth component. Standard cloud IDs, of the
+	// form <host>$<es_uuid>$<kb_uuid>, do not identify APM Server, and
+	// its URL cannot be inferred from the Elasticsearch UUID, so it must
+	// then be specified separately.
+	//
+	// This will be set from $CLOUD_ID if specified.
+	CloudID string
+
 	// ElasticsearchURL holds the Elasticsearch URL.
 	ElasticsearchURL string
 
@@ -58,6 +85,50 @@ type Config struct {
 	// TLS_SKIP_VERIFY env var.
 	// Any value different from "" is considered true.
 	TLSSkipVerify bool
+
+	// ESTLSSkipVerify and APMTLSSkipVerify determine if TLS
+	// certificate verification is skipped for Elasticsearch and
+	// APM Server respectively, e.g. where only one of them uses a
+	// self-signed certificate. Verification is skipped for both,
+	// and for Kibana, if TLSSkipVerify is true.
+	ESTLSSkipVerify  bool
+	APMTLSSkipVerify bool
+
+	// Transport holds an optional http.RoundTripper to use for
+	// requests to Elasticsearch, Kibana, and APM Server, e.g. for
+	// instrumenting requests or for testing.
+	//
+	// If this is specified, TLSSkipVerify, ESTLSSkipVerify, and
+	// APMTLSSkipVerify are ignored.
+	Transport http.RoundTripper
+
+	// ConfigFile holds the path to a YAML config file, holding named
+	// profiles from which unset fields are taken. For example:
+	//
+	//	profiles:
+	//	  default:
+	//	    elasticsearch_url: https://localhost:9200
+	//	    username: elastic
+	//	    password: changeme
+	//	    tls_skip_verify: true
+	//
+	// If this is unspecified, ~/.apmtool.yaml will be used if it exists.
+	ConfigFile string
+
+	// Profile holds the name of the profile to use from ConfigFile.
+	// Defaults to "default".
+	Profile string
+}
+
+// fileConfig holds a profile defined in a config file.
+type fileConfig struct {
+	ElasticsearchURL string `yaml:"elasticsearch_url"`
+	Username         string `yaml:"username"`
+	Password         string `yaml:"password"`
+	APIKey           string `yaml:"api_key"`
+	APMServerURL     string `yaml:"apm_server_url"`
+	KibanaURL        string `yaml:"kibana_url"`
+	TLSSkipVerify    bool   `yaml:"tls_skip_verify"`
 }
 
 // NewConfig returns a Config intialised from environment variables.
@@ -67,6 +138,18 @@ func NewConfig() (Config, error) {
 	return cfg, err
 }
 
+// ElasticsearchTLSSkipVerify reports whether TLS certificate
+// verification is skipped for Elasticsearch.
+func (cfg Config) ElasticsearchTLSSkipVerify() bool {
+	return cfg.TLSSkipVerify || cfg.ESTLSSkipVerify
+}
+
+// APMServerTLSSkipVerify reports whether TLS certificate
+// verification is skipped for APM Server.
+func (cfg Config) APMServerTLSSkipVerify() bool {
+	return cfg.TLSSkipVerify || cfg.APMTLSSkipVerify
+}
+
 // Finalize finalizes cfg by setting unset fields from environment
 // variables:
 //
@@ -76,6 +159,15 @@ func NewConfig() (Config, error) {
 //   - API Key is set from $ELASTICSEARCH_API_KEY
 //   - APMServerURL is set from $ELASTIC_APM_SERVER_URL
 //   - KibanaURL is set from $KIBANA_URL
+//   - CloudID is set from $CLOUD_ID
+//
+// If CloudID is set, then any of ElasticsearchURL, KibanaURL, and
+// APMServerURL still unset are derived from it.
+//
+// Any fields still unset are then set from the selected profile
+// in ConfigFile, if any. That is, explicitly set fields take
+// precedence over environment variables, which take precedence
+// over the config file.
 //
 // If $ELASTIC_APM_SERVER_URL is unspecified, and ElasticsearchURL
 // holds an Elastic Cloud-based URL, then the APM Server URL is
@@ -100,16 +192,155 @@ func (cfg *Config) Finalize() error {
 	if cfg.KibanaURL == "" {
 		cfg.KibanaURL = os.Getenv("KIBANA_URL")
 	}
+	if cfg.CloudID == "" {
+		cfg.CloudID = os.Getenv("CLOUD_ID")
+	}
 	if env := os.Getenv("TLS_SKIP_VERIFY"); !cfg.TLSSkipVerify && env != "" {
 		cfg.TLSSkipVerify = true
 	}
+	if err := cfg.applyCloudID(); err != nil {
+		return err
+	}
+	if err := cfg.loadConfigFile(); err != nil {
+		return err
+	}
 	return cfg.InferElasticCloudURLs()
 }
 
+// loadConfigFile sets unset fields from the selected profile in ConfigFile.
+func (cfg *Config) loadConfigFile() error {
+	path := cfg.ConfigFile
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil
+		}
+		path = filepath.Join(home, defaultConfigFile)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if cfg.ConfigFile == "" && errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("error reading config file: %w", err)
+	}
+	var file struct {
+		Profiles map[string]fileConfig `yaml:"profiles"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("error decoding config file %q: %w", path, err)
+	}
+
+	profileName := cfg.Profile
+	if profileName == "" {
+		profileName = defaultProfile
+	}
+	profile, ok := file.Profiles[profileName]
+	if !ok {
+		if cfg.Profile == "" {
+			return nil
+		}
+		return fmt.Errorf("profile %q not found in config file %q", profileName, path)
+	}
+	for _, field := range []struct {
+		dst *string
+		src string
+	}{
+		{&cfg.ElasticsearchURL, profile.ElasticsearchURL},
+		{&cfg.Username, profile.Username},
+		{&cfg.Password, profile.Password},
+		{&cfg.APIKey, profile.APIKey},
+		{&cfg.APMServerURL, profile.APMServerURL},
+		{&cfg.KibanaURL, profile.KibanaURL},
+	} {
+		if *field.dst == "" {
+			*field.dst = field.src
+		}
+	}
+	if profile.TLSSkipVerify {
+		cfg.TLSSkipVerify = true
+	}
+	return nil
+}
+
+// applyCloudID sets ElasticsearchURL, KibanaURL, and APMServerURL
+// from CloudID, if it is specified, for any of them that are unset.
+func (cfg *Config) applyCloudID() error {
+	if cfg.CloudID == "" {
+		return nil
+	}
+	urls, err := parseCloudID(cfg.CloudID)
+	if err != nil {
+		return err
+	}
+	for _, field := range []struct {
+		dst *string
+		src string
+	}{
+		{&cfg.ElasticsearchURL, urls.elasticsearch},
+		{&cfg.KibanaURL, urls.kibana},
+		{&cfg.APMServerURL, urls.apmServer},
+	} {
+		if *field.dst == "" {
+			*field.dst = field.src
+		}
+	}
+	return nil
+}
+
+// cloudURLs holds the URLs decoded from an Elastic Cloud ID.
+type cloudURLs struct {
+	elasticsearch string
+	kibana        string
+	apmServer     string
+}
+
+// parseCloudID decodes an Elastic Cloud ID, interpreting it the same
+// way as Beats and the APM agents. A cloud ID has the form
+// [<name>:]<base64>, where the base64-decoded value has the form
+// <host>[:<port>]$<es_uuid>[:<port>][$<kb_uuid>[:<port>][$<apm_uuid>[:<port>]]].
+//
+// Each component's URL is https://<uuid>.<host>, with the port
+// defaulting to the host's port, if any. The APM Server URL is
+// only set if the cloud ID includes an APM Server UUID, in which
+// case it is https://<apm_uuid>.apm.<host>.
+func parseCloudID(cloudID string) (cloudURLs, error) {
+	encoded := cloudID
+	if i := strings.LastIndex(cloudID, ":"); i >= 0 {
+		encoded = cloudID[i+1:]
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return cloudURLs{}, fmt.Errorf("error decoding cloud ID: %w", err)
+	}
+	parts := strings.Split(string(decoded), "$")
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return cloudURLs{}, errors.New("invalid cloud ID: expected <host>$<es_uuid>[$<kb_uuid>]")
+	}
+	host, port, _ := strings.Cut(parts[0], ":")
+	componentURL := func(component, label string) string {
+		uuid, componentPort, ok := strings.Cut(component, ":")
+		if !ok {
+			componentPort = port
+		}
+		return "https://" + joinHostPort(uuid+label+"."+host, componentPort)
+	}
+
+	urls := cloudURLs{elasticsearch: componentURL(parts[1], "")}
+	if len(parts) > 2 && parts[2] != "" {
+		urls.kibana = componentURL(parts[2], "")
+	}
+	if len(parts) > 3 && parts[3] != "" {
+		urls.apmServer = componentURL(parts[3], ".apm")
+	}
+	return urls, nil
+}
+
 // InferElasticCloudURLs attempts to infer a value for APMServerURL
 // and KibanaURL (if they are empty), by checking if ElasticsearchURL
 // matches an Elastic Cloud URL pattern, and deriving the other URLs
-// from that.
+// from that. Both classic deployment and serverless project URLs
+// are recognised.
 func (cfg *Config) InferElasticCloudURLs() error {
 	if cfg.ElasticsearchURL == "" {
 		return nil
@@ -118,24 +349,62 @@ func (cfg *Config) InferElasticCloudURLs() error {
 		return nil
 	}
 
-	// If ElasticsearchURL matches https://<alias>.es.<...>
-	// then derive the APM Server URL from that by substituting
-	// "apm" for "es", and Kibana URL by substituing "kb".
 	url, err := url.Parse(cfg.ElasticsearchURL)
 	if err != nil {
 		return fmt.Errorf("error parsing ElasticsearchURL: %w", err)
 	}
-	if alias, remainder, ok := strings.Cut(url.Host, "."); ok {
+	if project, suffix, ok := parseServerlessHost(url.Hostname()); ok {
+		// Serverless project endpoints are all served over HTTPS
+		// on the default port, at the root path, so only the host
+		// is carried over from ElasticsearchURL.
+		if cfg.APMServerURL == "" {
+			cfg.APMServerURL = fmt.Sprintf("https://%s.apm.%s", project, suffix)
+		}
+		if cfg.KibanaURL == "" {
+			cfg.KibanaURL = fmt.Sprintf("https://%s.kb.%s", project, suffix)
+		}
+		return nil
+	}
+
+	// If ElasticsearchURL matches https://<alias>.es.<...>
+	// then derive the APM Server URL from that by substituting
+	// "apm" for "es", and Kibana URL by substituing "kb".
+	host, port := url.Hostname(), url.Port()
+	if alias, remainder, ok := strings.Cut(host, "."); ok {
 		if component, remainder, ok := strings.Cut(remainder, "."); ok && component == "es" {
 			if cfg.APMServerURL == "" {
-				url.Host = fmt.Sprintf("%s.apm.%s", alias, remainder)
+				url.Host = joinHostPort(fmt.Sprintf("%s.apm.%s", alias, remainder), port)
 				cfg.APMServerURL = url.String()
 			}
 			if cfg.KibanaURL == "" {
-				url.Host = fmt.Sprintf("%s.kb.%s", alias, remainder)
+				url.Host = joinHostPort(fmt.Sprintf("%s.kb.%s", alias, remainder), port)
 				cfg.KibanaURL = url.String()
 			}
 		}
 	}
 	return nil
 }
+
+// parseServerlessHost parses an Elastic Cloud serverless project
+// Elasticsearch endpoint, <project>.es.<region>.<csp>.elastic.cloud,
+// returning the project and the <region>.<csp>.elastic.cloud suffix
+// shared by the project's other endpoints.
+func parseServerlessHost(host string) (project, suffix string, ok bool) {
+	labels := strings.Split(host, ".")
+	if len(labels) != 6 || labels[1] != "es" || !strings.HasSuffix(host, ".elastic.cloud") {
+		return "", "", false
+	}
+	for _, label := range labels {
+		if label == "" {
+			return "", "", false
+		}
+	}
+	return labels[0], strings.Join(labels[2:], "."), true
+}
+
+func joinHostPort(host, port string) string {
+	if port == "" {
+		return host
+	}
+	return net.JoinHostPort(host, port)
+}
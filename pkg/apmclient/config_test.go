@@ -0,0 +1,260 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package apmclient_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/apm-tools/pkg/apmclient"
+)
+
+func TestInferElasticCloudURLs(t *testing.T) {
+	for name, tc := range map[string]struct {
+		cfg                  apmclient.Config
+		expectedAPMServerURL string
+		expectedKibanaURL    string
+	}{
+		"classic": {
+			cfg:                  apmclient.Config{ElasticsearchURL: "https://alias.es.us-central1.gcp.cloud.es.io"},
+			expectedAPMServerURL: "https://alias.apm.us-central1.gcp.cloud.es.io",
+			expectedKibanaURL:    "https://alias.kb.us-central1.gcp.cloud.es.io",
+		},
+		"classic_port": {
+			cfg:                  apmclient.Config{ElasticsearchURL: "https://alias.es.us-central1.gcp.cloud.es.io:9243"},
+			expectedAPMServerURL: "https://alias.apm.us-central1.gcp.cloud.es.io:9243",
+			expectedKibanaURL:    "https://alias.kb.us-central1.gcp.cloud.es.io:9243",
+		},
+		"classic_apm_server_url_set": {
+			cfg: apmclient.Config{
+				ElasticsearchURL: "https://alias.es.us-central1.gcp.cloud.es.io",
+				APMServerURL:     "https://apm.example",
+			},
+			expectedAPMServerURL: "https://apm.example",
+			expectedKibanaURL:    "https://alias.kb.us-central1.gcp.cloud.es.io",
+		},
+		"serverless": {
+			cfg:                  apmclient.Config{ElasticsearchURL: "https://project-a1b2c3.es.eu-west-1.aws.elastic.cloud"},
+			expectedAPMServerURL: "https://project-a1b2c3.apm.eu-west-1.aws.elastic.cloud",
+			expectedKibanaURL:    "https://project-a1b2c3.kb.eu-west-1.aws.elastic.cloud",
+		},
+		"serverless_port": {
+			cfg:                  apmclient.Config{ElasticsearchURL: "https://project-a1b2c3.es.eu-west-1.aws.elastic.cloud:443"},
+			expectedAPMServerURL: "https://project-a1b2c3.apm.eu-west-1.aws.elastic.cloud",
+			expectedKibanaURL:    "https://project-a1b2c3.kb.eu-west-1.aws.elastic.cloud",
+		},
+		"serverless_scheme_path": {
+			cfg:                  apmclient.Config{ElasticsearchURL: "http://project-a1b2c3.es.us-east-1.gcp.elastic.cloud/some/path"},
+			expectedAPMServerURL: "https://project-a1b2c3.apm.us-east-1.gcp.elastic.cloud",
+			expectedKibanaURL:    "https://project-a1b2c3.kb.us-east-1.gcp.elastic.cloud",
+		},
+		"serverless_kibana_url_set": {
+			cfg: apmclient.Config{
+				ElasticsearchURL: "https://project-a1b2c3.es.eu-west-1.aws.elastic.cloud",
+				KibanaURL:        "https://kibana.example",
+			},
+			expectedAPMServerURL: "https://project-a1b2c3.apm.eu-west-1.aws.elastic.cloud",
+			expectedKibanaURL:    "https://kibana.example",
+		},
+		"serverless_non_project_host": {
+			cfg: apmclient.Config{ElasticsearchURL: "https://cloud.elastic.cloud"},
+		},
+		"localhost": {
+			cfg: apmclient.Config{ElasticsearchURL: "http://localhost:9200"},
+		},
+		"empty": {},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg := tc.cfg
+			require.NoError(t, cfg.InferElasticCloudURLs())
+			assert.Equal(t, tc.expectedAPMServerURL, cfg.APMServerURL)
+			assert.Equal(t, tc.expectedKibanaURL, cfg.KibanaURL)
+		})
+	}
+}
+
+const testConfigFile = `
+profiles:
+  default:
+    elasticsearch_url: https://file.example:9200
+    username: file_user
+    password: file_password
+    apm_server_url: https://file.example:8200
+  staging:
+    elasticsearch_url: https://staging.example:9200
+    api_key: staging_api_key
+    tls_skip_verify: true
+`
+
+// setConfigEnv clears the environment variables read by Config.Finalize,
+// and points $HOME at an empty directory.
+func setConfigEnv(t *testing.T) {
+	for _, k := range []string{
+		"ELASTICSEARCH_URL",
+		"ELASTICSEARCH_USERNAME",
+		"ELASTICSEARCH_PASSWORD",
+		"ELASTICSEARCH_API_KEY",
+		"ELASTIC_APM_SERVER_URL",
+		"KIBANA_URL",
+		"TLS_SKIP_VERIFY",
+		"CLOUD_ID",
+	} {
+		t.Setenv(k, "")
+	}
+	t.Setenv("HOME", t.TempDir())
+}
+
+func writeConfigFile(t *testing.T, path string) string {
+	require.NoError(t, os.WriteFile(path, []byte(testConfigFile), 0644))
+	return path
+}
+
+func TestFinalizeConfigFilePrecedence(t *testing.T) {
+	setConfigEnv(t)
+	t.Setenv("ELASTICSEARCH_USERNAME", "env_user")
+	t.Setenv("ELASTICSEARCH_PASSWORD", "env_password")
+
+	cfg := apmclient.Config{
+		ConfigFile: writeConfigFile(t, filepath.Join(t.TempDir(), "config.yaml")),
+		Password:   "flag_password",
+	}
+	require.NoError(t, cfg.Finalize())
+	assert.Equal(t, "https://file.example:9200", cfg.ElasticsearchURL)
+	assert.Equal(t, "https://file.example:8200", cfg.APMServerURL)
+	assert.Equal(t, "env_user", cfg.Username)
+	assert.Equal(t, "flag_password", cfg.Password)
+	assert.Empty(t, cfg.APIKey)
+	assert.False(t, cfg.TLSSkipVerify)
+}
+
+func TestFinalizeConfigFileProfile(t *testing.T) {
+	setConfigEnv(t)
+	cfg := apmclient.Config{
+		ConfigFile: writeConfigFile(t, filepath.Join(t.TempDir(), "config.yaml")),
+		Profile:    "staging",
+	}
+	require.NoError(t, cfg.Finalize())
+	assert.Equal(t, "https://staging.example:9200", cfg.ElasticsearchURL)
+	assert.Equal(t, "staging_api_key", cfg.APIKey)
+	assert.Empty(t, cfg.Username)
+	assert.True(t, cfg.TLSSkipVerify)
+
+	cfg = apmclient.Config{ConfigFile: cfg.ConfigFile, Profile: "missing"}
+	err := cfg.Finalize()
+	assert.ErrorContains(t, err, `profile "missing" not found in config file`)
+}
+
+func TestFinalizeConfigFileDefault(t *testing.T) {
+	setConfigEnv(t)
+	cfg := apmclient.Config{}
+	require.NoError(t, cfg.Finalize())
+	assert.Empty(t, cfg.ElasticsearchURL)
+
+	writeConfigFile(t, filepath.Join(os.Getenv("HOME"), ".apmtool.yaml"))
+	cfg = apmclient.Config{}
+	require.NoError(t, cfg.Finalize())
+	assert.Equal(t, "https://file.example:9200", cfg.ElasticsearchURL)
+	assert.Equal(t, "file_user", cfg.Username)
+
+	cfg = apmclient.Config{ConfigFile: filepath.Join(t.TempDir(), "missing.yaml")}
+	assert.Error(t, cfg.Finalize())
+}
+
+func TestFinalizeCloudID(t *testing.T) {
+	for name, tc := range map[string]struct {
+		cfg      apmclient.Config
+		expected apmclient.Config
+	}{
+		"no_apm_server": {
+			// us-east-1.aws.found.io$cec6f261a74bf24ce33bb8811b84294f$c6c2ca6d042249af0cc7d7a9e9625743
+			//
+			// The cloud ID does not identify APM Server, and neither
+			// can InferElasticCloudURLs derive it from the UUID-based
+			// Elasticsearch URL, so APMServerURL is left unset.
+			cfg: apmclient.Config{CloudID: "staging:dXMtZWFzdC0xLmF3cy5mb3VuZC5pbyRjZWM2ZjI2MWE3NGJmMjRjZTMzYmI4ODExYjg0Mjk0ZiRjNmMyY2E2ZDA0MjI0OWFmMGNjN2Q3YTllOTYyNTc0Mw=="},
+			expected: apmclient.Config{
+				ElasticsearchURL: "https://cec6f261a74bf24ce33bb8811b84294f.us-east-1.aws.found.io",
+				KibanaURL:        "https://c6c2ca6d042249af0cc7d7a9e9625743.us-east-1.aws.found.io",
+				APMServerURL:     "",
+			},
+		},
+		"apm_server_port": {
+			// us-central1.gcp.cloud.es.io:9243$es-uuid$kb-uuid$apm-uuid
+			cfg: apmclient.Config{CloudID: "dXMtY2VudHJhbDEuZ2NwLmNsb3VkLmVzLmlvOjkyNDMkZXMtdXVpZCRrYi11dWlkJGFwbS11dWlk"},
+			expected: apmclient.Config{
+				ElasticsearchURL: "https://es-uuid.us-central1.gcp.cloud.es.io:9243",
+				KibanaURL:        "https://kb-uuid.us-central1.gcp.cloud.es.io:9243",
+				APMServerURL:     "https://apm-uuid.apm.us-central1.gcp.cloud.es.io:9243",
+			},
+		},
+		"component_port": {
+			// us-central1.gcp.cloud.es.io$es-uuid:9200$kb-uuid
+			cfg: apmclient.Config{CloudID: "name:dXMtY2VudHJhbDEuZ2NwLmNsb3VkLmVzLmlvJGVzLXV1aWQ6OTIwMCRrYi11dWlk"},
+			expected: apmclient.Config{
+				ElasticsearchURL: "https://es-uuid.us-central1.gcp.cloud.es.io:9200",
+				KibanaURL:        "https://kb-uuid.us-central1.gcp.cloud.es.io",
+			},
+		},
+		"url_set": {
+			cfg: apmclient.Config{
+				CloudID:          "dXMtY2VudHJhbDEuZ2NwLmNsb3VkLmVzLmlvOjkyNDMkZXMtdXVpZCRrYi11dWlkJGFwbS11dWlk",
+				ElasticsearchURL: "https://es.example",
+			},
+			expected: apmclient.Config{
+				ElasticsearchURL: "https://es.example",
+				KibanaURL:        "https://kb-uuid.us-central1.gcp.cloud.es.io:9243",
+				APMServerURL:     "https://apm-uuid.apm.us-central1.gcp.cloud.es.io:9243",
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			setConfigEnv(t)
+			cfg := tc.cfg
+			require.NoError(t, cfg.Finalize())
+			assert.Equal(t, tc.expected.ElasticsearchURL, cfg.ElasticsearchURL)
+			assert.Equal(t, tc.expected.KibanaURL, cfg.KibanaURL)
+			assert.Equal(t, tc.expected.APMServerURL, cfg.APMServerURL)
+		})
+	}
+}
+
+func TestFinalizeCloudIDEnv(t *testing.T) {
+	setConfigEnv(t)
+	t.Setenv("CLOUD_ID", "staging:dXMtZWFzdC0xLmF3cy5mb3VuZC5pbyRjZWM2ZjI2MWE3NGJmMjRjZTMzYmI4ODExYjg0Mjk0ZiRjNmMyY2E2ZDA0MjI0OWFmMGNjN2Q3YTllOTYyNTc0Mw==")
+	cfg, err := apmclient.NewConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "https://cec6f261a74bf24ce33bb8811b84294f.us-east-1.aws.found.io", cfg.ElasticsearchURL)
+	assert.Equal(t, "https://c6c2ca6d042249af0cc7d7a9e9625743.us-east-1.aws.found.io", cfg.KibanaURL)
+}
+
+func TestFinalizeCloudIDInvalid(t *testing.T) {
+	for name, cloudID := range map[string]string{
+		"base64":        "name:not base64!",
+		"no_separators": "bm8tc2VwYXJhdG9ycw==",
+	} {
+		t.Run(name, func(t *testing.T) {
+			setConfigEnv(t)
+			cfg := apmclient.Config{CloudID: cloudID}
+			assert.Error(t, cfg.Finalize())
+		})
+	}
+}
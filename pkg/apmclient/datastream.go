@@ -0,0 +1,53 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package apmclient
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// DataStreamStats returns the stats of the data streams matching
+// pattern, e.g. "traces-apm*", ordered by data stream name.
+//
+// The number of backing indices is taken from the data stream stats
+// API, which does not report document counts, so each data stream's
+// documents are counted separately.
+func (c *Client) DataStreamStats(ctx context.Context, pattern string) ([]DataStreamStat, error) {
+	resp, err := c.es.Indices.DataStreamsStats().Name(pattern).Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting data stream stats for %q: %w", pattern, err)
+	}
+	out := make([]DataStreamStat, len(resp.DataStreams))
+	for i, ds := range resp.DataStreams {
+		count, err := c.es.Count().Index(ds.DataStream).Do(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error counting documents in %q: %w", ds.DataStream, err)
+		}
+		out[i] = DataStreamStat{
+			Name:           ds.DataStream,
+			BackingIndices: ds.BackingIndices,
+			DocCount:       count.Count,
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Name < out[j].Name
+	})
+	return out, nil
+}
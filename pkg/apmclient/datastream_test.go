@@ -0,0 +1,69 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package apmclient_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/apm-tools/pkg/apmclient"
+)
+
+func TestDataStreamStats(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/_data_stream/traces-apm*/_stats":
+			w.Write([]byte(`{
+			  "_shards": {"total": 4, "successful": 4, "failed": 0},
+			  "data_stream_count": 2,
+			  "backing_indices": 3,
+			  "total_store_size_bytes": 2048,
+			  "data_streams": [
+			    {"data_stream": "traces-apm-default", "backing_indices": 2, "store_size_bytes": 1536, "maximum_timestamp": 1700000000000},
+			    {"data_stream": "traces-apm.rum-default", "backing_indices": 1, "store_size_bytes": 512, "maximum_timestamp": 1700000000000}
+			  ]
+			}`))
+		case "/traces-apm-default/_count":
+			w.Write([]byte(`{"count": 42, "_shards": {"total": 2, "successful": 2, "failed": 0}}`))
+		case "/traces-apm.rum-default/_count":
+			w.Write([]byte(`{"count": 7, "_shards": {"total": 1, "successful": 1, "failed": 0}}`))
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	stats, err := client.DataStreamStats(context.Background(), "traces-apm*")
+	require.NoError(t, err)
+	assert.Equal(t, []apmclient.DataStreamStat{
+		{Name: "traces-apm-default", BackingIndices: 2, DocCount: 42},
+		{Name: "traces-apm.rum-default", BackingIndices: 1, DocCount: 7},
+	}, stats)
+}
+
+func TestDataStreamStatsError(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": {"type": "index_not_found_exception", "reason": "no such index [logs-nope*]"}, "status": 404}`))
+	})
+	_, err := client.DataStreamStats(context.Background(), "logs-nope*")
+	assert.ErrorContains(t, err, `error getting data stream stats for "logs-nope*"`)
+}
@@ -17,7 +17,53 @@
 
 package apmclient
 
+import (
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+)
+
 type options struct {
+	start, end time.Time
 }
 
 type Option func(*options)
+
+// WithTimeRange restricts the documents queried to those with a
+// @timestamp between start and end, inclusive. If end is zero,
+// the range is unbounded above.
+func WithTimeRange(start, end time.Time) Option {
+	return func(opts *options) {
+		opts.start = start
+		opts.end = end
+	}
+}
+
+func newOptions(opts []Option) options {
+	var options options
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// timeRangeQuery returns a query filtering @timestamp to the time
+// range set by WithTimeRange, or nil if no time range is set.
+func (opts options) timeRangeQuery() *types.Query {
+	if opts.start.IsZero() && opts.end.IsZero() {
+		return nil
+	}
+	format := "strict_date_optional_time"
+	rangeQuery := types.DateRangeQuery{Format: &format}
+	if !opts.start.IsZero() {
+		gte := opts.start.UTC().Format(time.RFC3339Nano)
+		rangeQuery.Gte = &gte
+	}
+	if !opts.end.IsZero() {
+		lte := opts.end.UTC().Format(time.RFC3339Nano)
+		rangeQuery.Lte = &lte
+	}
+	return &types.Query{
+		Range: map[string]types.RangeQuery{"@timestamp": rangeQuery},
+	}
+}
@@ -0,0 +1,94 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package apmclient
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/search"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/fieldtype"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/sortorder"
+)
+
+// maxTraceEvents holds the maximum number of transactions
+// and spans returned by GetTrace.
+const maxTraceEvents = 1000
+
+// GetTrace returns the transactions and spans of the trace with the
+// given ID, ordered by timestamp and then span ID.
+func (c *Client) GetTrace(ctx context.Context, traceID string) (Trace, error) {
+	size := maxTraceEvents
+	resp, err := c.es.Search().Index("traces-apm*").Request(&search.Request{
+		Size: &size,
+		Sort: []types.SortCombinations{
+			types.SortOptions{
+				SortOptions: map[string]types.FieldSort{
+					"@timestamp": {Order: &sortorder.Asc},
+				},
+			},
+			types.SortOptions{
+				SortOptions: map[string]types.FieldSort{
+					"span.id": {
+						Order:        &sortorder.Asc,
+						Missing:      "_first",
+						UnmappedType: &fieldtype.Keyword,
+					},
+				},
+			},
+		},
+		Query: &types.Query{
+			Term: map[string]types.TermQuery{
+				"trace.id": {Value: traceID},
+			},
+		},
+	}).Do(ctx)
+	if err != nil {
+		return Trace{}, fmt.Errorf("error searching traces-apm*: %w", err)
+	}
+
+	trace := Trace{ID: traceID}
+	for _, hit := range resp.Hits.Hits {
+		source := gjson.ParseBytes(hit.Source_)
+		event := TraceEvent{
+			ParentID:  source.Get("parent.id").String(),
+			Outcome:   source.Get("event.outcome").String(),
+			Timestamp: time.UnixMicro(source.Get("timestamp.us").Int()).UTC(),
+		}
+		switch processorEvent := source.Get("processor.event").String(); processorEvent {
+		case "transaction":
+			event.ID = source.Get("transaction.id").String()
+			event.Name = source.Get("transaction.name").String()
+			event.Duration = time.Duration(source.Get("transaction.duration.us").Int()) * time.Microsecond
+			trace.Transactions = append(trace.Transactions, event)
+		case "span":
+			event.ID = source.Get("span.id").String()
+			event.Name = source.Get("span.name").String()
+			event.Duration = time.Duration(source.Get("span.duration.us").Int()) * time.Microsecond
+			trace.Spans = append(trace.Spans, event)
+		}
+	}
+	if len(trace.Transactions) == 0 && len(trace.Spans) == 0 {
+		return Trace{}, fmt.Errorf("trace %q not found", traceID)
+	}
+	return trace, nil
+}
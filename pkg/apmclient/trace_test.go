@@ -0,0 +1,138 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package apmclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/apm-tools/pkg/apmclient"
+)
+
+func TestGetTrace(t *testing.T) {
+	var body map[string]any
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/traces-apm*/_search", r.URL.Path)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		w.Write([]byte(`{
+		  "hits": {
+		    "total": {"value": 4, "relation": "eq"},
+		    "hits": [
+		      {"_index": "traces-apm-default", "_id": "1", "_source": {
+		        "processor": {"event": "transaction"},
+		        "timestamp": {"us": 1000000},
+		        "transaction": {"id": "tx1", "name": "GET /", "duration": {"us": 5000}},
+		        "event": {"outcome": "success"}
+		      }},
+		      {"_index": "traces-apm-default", "_id": "2", "_source": {
+		        "processor": {"event": "span"},
+		        "timestamp": {"us": 1001000},
+		        "parent": {"id": "tx1"},
+		        "span": {"id": "span1", "name": "SELECT", "duration": {"us": 1000}},
+		        "event": {"outcome": "success"}
+		      }},
+		      {"_index": "traces-apm-default", "_id": "3", "_source": {
+		        "processor": {"event": "transaction"},
+		        "timestamp": {"us": 1002000},
+		        "parent": {"id": "span2"},
+		        "transaction": {"id": "tx2", "name": "POST /backend", "duration": {"us": 2000}},
+		        "event": {"outcome": "failure"}
+		      }},
+		      {"_index": "traces-apm-default", "_id": "4", "_source": {
+		        "processor": {"event": "span"},
+		        "timestamp": {"us": 1002000},
+		        "parent": {"id": "tx1"},
+		        "span": {"id": "span2", "name": "POST", "duration": {"us": 2500}},
+		        "event": {"outcome": "failure"}
+		      }}
+		    ]
+		  }
+		}`))
+	})
+
+	trace, err := client.GetTrace(context.Background(), "abc")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"term": map[string]any{"trace.id": map[string]any{"value": "abc"}}}, body["query"])
+
+	assert.Equal(t, "abc", trace.ID)
+	require.Len(t, trace.Transactions, 2)
+	require.Len(t, trace.Spans, 2)
+	assert.Equal(t, apmclient.TraceEvent{
+		ID:        "tx1",
+		Name:      "GET /",
+		Outcome:   "success",
+		Timestamp: time.UnixMicro(1000000).UTC(),
+		Duration:  5 * time.Millisecond,
+	}, trace.Transactions[0])
+	assert.Equal(t, apmclient.TraceEvent{
+		ID:        "span2",
+		ParentID:  "tx1",
+		Name:      "POST",
+		Outcome:   "failure",
+		Timestamp: time.UnixMicro(1002000).UTC(),
+		Duration:  2500 * time.Microsecond,
+	}, trace.Spans[1])
+
+	// Reconstruct the tree: tx1 -> (span1, span2 -> tx2)
+	roots := trace.Roots()
+	require.Len(t, roots, 1)
+	assert.Equal(t, "tx1", roots[0].ID)
+	assert.Equal(t, []string{"span1", "span2"}, eventIDs(trace.Children("tx1")))
+	assert.Equal(t, []string{"tx2"}, eventIDs(trace.Children("span2")))
+	assert.Empty(t, trace.Children("span1"))
+	assert.Empty(t, trace.Children("tx2"))
+}
+
+func TestGetTraceNotFound(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"hits": {"total": {"value": 0, "relation": "eq"}, "hits": []}}`))
+	})
+	_, err := client.GetTrace(context.Background(), "abc")
+	assert.EqualError(t, err, `trace "abc" not found`)
+}
+
+func eventIDs(events []apmclient.TraceEvent) []string {
+	ids := make([]string, len(events))
+	for i, event := range events {
+		ids[i] = event.ID
+	}
+	return ids
+}
+
+// newTestClient returns an apmclient.Client which sends
+// Elasticsearch requests to handler.
+func newTestClient(t testing.TB, handler http.HandlerFunc) *apmclient.Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := apmclient.New(apmclient.Config{ElasticsearchURL: srv.URL})
+	require.NoError(t, err)
+	return client
+}
@@ -17,13 +17,108 @@
 
 package apmclient
 
+import (
+	"sort"
+	"time"
+)
+
 type APIKey struct {
 	Encoded string
 }
 
+// APMServerInfo holds the build information reported by APM Server.
+type APMServerInfo struct {
+	BuildDate time.Time `json:"build_date"`
+	BuildSHA  string    `json:"build_sha"`
+	Version   string    `json:"version"`
+}
+
 type ServiceSummary struct {
-	Name        string
-	Environment string
-	Agent       string
-	Language    string
+	Name        string `json:"name"`
+	Environment string `json:"environment"`
+	Agent       string `json:"agent"`
+	Language    string `json:"language"`
+}
+
+// DataStreamStat holds the stats of a data stream.
+type DataStreamStat struct {
+	Name           string `json:"name"`
+	BackingIndices int    `json:"backing_indices"`
+	DocCount       int64  `json:"doc_count"`
+}
+
+// APMError holds an error reported by an APM agent.
+type APMError struct {
+	ID          string    `json:"id"`
+	GroupingKey string    `json:"grouping_key"`
+	Message     string    `json:"message"`
+	Timestamp   time.Time `json:"@timestamp"`
+}
+
+// Trace holds the transactions and spans of a trace,
+// each ordered by timestamp and then span ID.
+type Trace struct {
+	ID           string
+	Transactions []TraceEvent
+	Spans        []TraceEvent
+}
+
+// TraceEvent holds a transaction or span within a Trace.
+type TraceEvent struct {
+	ID        string
+	ParentID  string
+	Name      string
+	Outcome   string
+	Timestamp time.Time
+	Duration  time.Duration
+}
+
+// Roots returns the transactions and spans in the trace which have no
+// parent, or whose parent is not in the trace, ordered by timestamp.
+func (t Trace) Roots() []TraceEvent {
+	ids := make(map[string]bool)
+	for _, events := range [][]TraceEvent{t.Transactions, t.Spans} {
+		for _, event := range events {
+			ids[event.ID] = true
+		}
+	}
+	return t.filter(func(event TraceEvent) bool { return !ids[event.ParentID] })
+}
+
+// Children returns the transactions and spans in the trace whose
+// parent has the given ID, ordered by timestamp.
+func (t Trace) Children(parentID string) []TraceEvent {
+	return t.filter(func(event TraceEvent) bool { return event.ParentID == parentID })
+}
+
+func (t Trace) filter(f func(TraceEvent) bool) []TraceEvent {
+	var out []TraceEvent
+	for _, events := range [][]TraceEvent{t.Transactions, t.Spans} {
+		for _, event := range events {
+			if f(event) {
+				out = append(out, event)
+			}
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Timestamp.Before(out[j].Timestamp)
+	})
+	return out
+}
+
+// APIKeyInfo holds information about an agent API Key.
+type APIKeyInfo struct {
+	ID   string
+	Name string
+
+	// Creation holds the time at which the API Key was created.
+	Creation time.Time
+
+	// Expiration holds the time at which the API Key expires,
+	// or the zero value if the API Key never expires.
+	Expiration time.Time
+
+	// Metadata holds the API Key's metadata, including any
+	// specified in CreateAPIKeyOptions.Metadata.
+	Metadata map[string]any
 }
@@ -23,8 +23,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -41,6 +43,10 @@ const (
 
 	// ReceivedSuffix signals a file has changed and not yet been approved.
 	ReceivedSuffix = ".received.json"
+
+	// DiffSuffix signals a file holds the differences between
+	// approved and received files, as a list of DiffEntry.
+	DiffSuffix = ".diff.json"
 )
 
 // ApproveEvents compares the _source of the search hits with the
@@ -58,12 +64,52 @@ func ApproveEvents(t testing.TB, name string, hits []espoll.SearchHit, dynamic .
 	for i, hit := range hits {
 		sources[i] = hit.RawSource
 	}
-	rewriteDynamic(t, sources, false, dynamic...)
+	ApproveEventDocs(t, name, sources, dynamic...)
+}
+
+// ApproveEventDocsOptions holds options for ApproveEventDocsWithOptions.
+type ApproveEventDocsOptions struct {
+	// Dynamic holds the paths of fields whose values are replaced
+	// with a static string for comparison, in addition to the
+	// server-generated fields that are always treated as dynamic.
+	Dynamic []string
+
+	// IgnoreFields holds the paths of fields which are removed
+	// from the event documents before comparison.
+	IgnoreFields []string
+
+	// WriteDiff controls whether a "<name>.diff.json" file is written
+	// alongside the received file when the events differ, holding the
+	// differences as a list of DiffEntry.
+	WriteDiff bool
+}
+
+// ApproveEventDocs compares the given event documents with the
+// contents of the file in "approvals/<name>.approved.json".
+//
+// Dynamic fields (@timestamp, observer.id, etc.) are replaced
+// with a static string for comparison.
+//
+// If the events differ, then the test will fail.
+func ApproveEventDocs(t testing.TB, name string, eventDocs [][]byte, dynamic ...string) {
+	t.Helper()
+	ApproveEventDocsWithOptions(t, name, eventDocs, ApproveEventDocsOptions{Dynamic: dynamic})
+}
+
+// ApproveEventDocsWithOptions is like ApproveEventDocs, but
+// accepts options for controlling the comparison.
+func ApproveEventDocsWithOptions(t testing.TB, name string, eventDocs [][]byte, opts ApproveEventDocsOptions) {
+	t.Helper()
+
+	docs := make([][]byte, len(eventDocs))
+	copy(docs, eventDocs)
+	deleteIgnored(t, docs, opts.IgnoreFields...)
 	// Rewrite dynamic fields and sort them for repeatable diffs.
-	sort.Slice(sources, func(i, j int) bool {
-		return compareDocumentFields(sources[i], sources[j]) < 0
+	rewriteDynamic(t, docs, false, opts.Dynamic...)
+	sort.Slice(docs, func(i, j int) bool {
+		return compareDocumentFields(docs[i], docs[j]) < 0
 	})
-	approveEventDocs(t, filepath.Join("approvals", name), sources)
+	approveEventDocs(t, filepath.Join("approvals", name), docs, opts.WriteDiff)
 }
 
 // ApproveFields compares the fields of the search hits with the
@@ -114,24 +160,101 @@ func rewriteDynamic(t testing.TB, srcs [][]byte, flattenedKeys bool, dynamic ...
 	}, dynamic...)
 
 	for i := range srcs {
-		for _, field := range dynamic {
-			if flattenedKeys {
-				field = strings.ReplaceAll(field, ".", "\\.")
+		for _, pattern := range dynamic {
+			for _, field := range expandField(srcs[i], pattern, flattenedKeys) {
+				existing := gjson.GetBytes(srcs[i], field)
+				if !existing.Exists() {
+					continue
+				}
+
+				var v interface{}
+				if existing.IsArray() {
+					v = []any{"dynamic"}
+				} else {
+					v = "dynamic"
+				}
+
+				var err error
+				srcs[i], err = sjson.SetBytes(srcs[i], field, v)
+				if err != nil {
+					t.Fatal(err)
+				}
 			}
-			existing := gjson.GetBytes(srcs[i], field)
-			if !existing.Exists() {
-				continue
+		}
+	}
+}
+
+// expandField returns the gjson paths in src matching the given field
+// pattern. Each dot-separated component of the pattern may be a glob
+// (e.g. "*"), matching object keys or array indices, such that
+// "links.*.trace.id" matches "links.0.trace.id", "links.1.trace.id", etc.
+//
+// If flattenedKeys is true, the pattern is matched against the
+// top-level keys of src, which are themselves dotted field names.
+func expandField(src []byte, pattern string, flattenedKeys bool) []string {
+	if !strings.ContainsAny(pattern, "*?[") {
+		if flattenedKeys {
+			pattern = strings.ReplaceAll(pattern, ".", "\\.")
+		}
+		return []string{pattern}
+	}
+	if flattenedKeys {
+		return matchKeys(gjson.ParseBytes(src), "", pattern)
+	}
+	paths := []string{""}
+	for _, component := range strings.Split(pattern, ".") {
+		var next []string
+		for _, prefix := range paths {
+			value := gjson.ParseBytes(src)
+			if prefix != "" {
+				value = gjson.GetBytes(src, prefix)
 			}
+			next = append(next, matchKeys(value, prefix, component)...)
+		}
+		paths = next
+	}
+	return paths
+}
 
-			var v interface{}
-			if existing.IsArray() {
-				v = []any{"dynamic"}
-			} else {
-				v = "dynamic"
+// matchKeys returns the paths of the object keys or array indices
+// of value which match pattern, prefixed with prefix.
+func matchKeys(value gjson.Result, prefix, pattern string) []string {
+	var paths []string
+	var index int
+	value.ForEach(func(key, _ gjson.Result) bool {
+		k := key.String()
+		if value.IsArray() {
+			k = strconv.Itoa(index)
+			index++
+		}
+		if ok, _ := path.Match(pattern, k); ok {
+			k = gjsonPathEscaper.Replace(k)
+			if prefix != "" {
+				k = prefix + "." + k
 			}
+			paths = append(paths, k)
+		}
+		return true
+	})
+	return paths
+}
+
+// gjsonPathEscaper escapes gjson path special characters in object keys.
+var gjsonPathEscaper = strings.NewReplacer(
+	".", "\\.", "*", "\\*", "?", "\\?", "|", "\\|", "#", "\\#", "@", "\\@",
+)
+
+// deleteIgnored removes all ignored fields from srcs.
+func deleteIgnored(t testing.TB, srcs [][]byte, ignored ...string) {
+	t.Helper()
 
+	for i := range srcs {
+		for _, field := range ignored {
+			if !gjson.GetBytes(srcs[i], field).Exists() {
+				continue
+			}
 			var err error
-			srcs[i], err = sjson.SetBytes(srcs[i], field, v)
+			srcs[i], err = sjson.DeleteBytes(srcs[i], field)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -146,7 +269,7 @@ func rewriteDynamic(t testing.TB, srcs [][]byte, flattenedKeys bool, dynamic ...
 // will be replaced with a static string for comparison.
 //
 // If the events differ, then the test will fail.
-func approveEventDocs(t testing.TB, name string, eventDocs [][]byte) {
+func approveEventDocs(t testing.TB, name string, eventDocs [][]byte, writeDiff bool) {
 	t.Helper()
 
 	events := make([]interface{}, len(eventDocs))
@@ -160,7 +283,7 @@ func approveEventDocs(t testing.TB, name string, eventDocs [][]byte) {
 	}
 
 	received := map[string]interface{}{"events": events}
-	approve(t, name, received)
+	approve(t, name, received, writeDiff)
 }
 
 func approveFields(t testing.TB, name string, docs [][]byte) {
@@ -177,14 +300,15 @@ func approveFields(t testing.TB, name string, docs [][]byte) {
 		decodedDocs[i] = fields
 	}
 
-	approve(t, name, decodedDocs)
+	approve(t, name, decodedDocs, false)
 }
 
 // approve compares the given value with the contents of the file
 // "<name>.approved.json".
 //
-// If the value differs, then the test will fail.
-func approve(t testing.TB, name string, received interface{}) {
+// If the value differs, then the test will fail. If writeDiff is true,
+// the differences are also written to "<name>.diff.json".
+func approve(t testing.TB, name string, received interface{}, writeDiff bool) {
 	t.Helper()
 
 	var approved interface{}
@@ -195,12 +319,18 @@ func approve(t testing.TB, name string, received interface{}) {
 		if err := writeReceived(name, received); err != nil {
 			t.Fatalf("failed to write received file: %v", err)
 		}
+		if writeDiff {
+			if err := writeDiffFile(name, diffJSON("", approved, received)); err != nil {
+				t.Fatalf("failed to write diff file: %v", err)
+			}
+		}
 		t.Fatalf("%s\n%s\n\n", diff,
 			"Test failed. Run `make check-approvals` to verify the diff.",
 		)
 	} else {
-		// Remove an old *.received.json file if it exists, ignore errors
+		// Remove old *.received.json and *.diff.json files if they exist, ignore errors
 		_ = removeReceived(name)
+		_ = os.Remove(name + DiffSuffix)
 	}
 }
 
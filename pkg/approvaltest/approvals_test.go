@@ -0,0 +1,157 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package approvaltest_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/apm-tools/pkg/approvaltest"
+)
+
+func TestApproveEventDocsIgnoreFields(t *testing.T) {
+	dir := chdirTemp(t)
+	writeApproved(t, dir, "ignore", `{"events": [{"agent": {"name": "go"}, "service": {"name": "svc"}}]}`)
+
+	docs := [][]byte{
+		[]byte(`{"agent": {"name": "go", "ephemeral_id": "abc"}, "service": {"name": "svc"}, "url": {"port": 1234}}`),
+	}
+	opts := approvaltest.ApproveEventDocsOptions{
+		IgnoreFields: []string{"agent.ephemeral_id", "url"},
+	}
+	rt := &recordingT{TB: t}
+	approvaltest.ApproveEventDocsWithOptions(rt, "ignore", docs, opts)
+	assert.False(t, rt.failed, rt.msg)
+	assert.NoFileExists(t, filepath.Join(dir, "approvals", "ignore"+approvaltest.ReceivedSuffix))
+
+	// When the events differ, ignored fields must not
+	// be written to the received file either.
+	docs[0] = []byte(`{"agent": {"name": "java", "ephemeral_id": "abc"}, "service": {"name": "svc"}}`)
+	rt = &recordingT{TB: t}
+	approvaltest.ApproveEventDocsWithOptions(rt, "ignore", docs, opts)
+	assert.True(t, rt.failed)
+	received, err := os.ReadFile(filepath.Join(dir, "approvals", "ignore"+approvaltest.ReceivedSuffix))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"events": [{"agent": {"name": "java"}, "service": {"name": "svc"}}]}`, string(received))
+}
+
+func TestApproveEventDocsDynamicWildcard(t *testing.T) {
+	dir := chdirTemp(t)
+	writeApproved(t, dir, "wildcard", `{"events": [{
+		"trace": {"id": "abc"},
+		"links": [
+			{"span": {"id": "1"}, "trace": {"id": "dynamic"}},
+			{"span": {"id": "2"}, "trace": {"id": "dynamic"}}
+		]
+	}]}`)
+
+	docs := [][]byte{[]byte(`{
+		"trace": {"id": "abc"},
+		"links": [
+			{"span": {"id": "1"}, "trace": {"id": "def"}},
+			{"span": {"id": "2"}, "trace": {"id": "ghi"}}
+		]
+	}`)}
+	rt := &recordingT{TB: t}
+	approvaltest.ApproveEventDocs(rt, "wildcard", docs, "links.*.trace.id")
+	assert.False(t, rt.failed, rt.msg)
+
+	// Exact paths still only match the specified field.
+	rt = &recordingT{TB: t}
+	approvaltest.ApproveEventDocs(rt, "wildcard", docs, "links.0.trace.id")
+	assert.True(t, rt.failed)
+	received, err := os.ReadFile(filepath.Join(dir, "approvals", "wildcard"+approvaltest.ReceivedSuffix))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"events": [{
+		"trace": {"id": "abc"},
+		"links": [
+			{"span": {"id": "1"}, "trace": {"id": "dynamic"}},
+			{"span": {"id": "2"}, "trace": {"id": "ghi"}}
+		]
+	}]}`, string(received))
+}
+
+func TestApproveEventDocsWriteDiff(t *testing.T) {
+	dir := chdirTemp(t)
+	writeApproved(t, dir, "diff", `{"events": [{"service": {"name": "svc", "version": "1.0"}, "labels": ["a"]}]}`)
+
+	docs := [][]byte{[]byte(`{"service": {"name": "svc", "version": "2.0"}, "labels": ["a", "b"]}`)}
+	rt := &recordingT{TB: t}
+	approvaltest.ApproveEventDocsWithOptions(rt, "diff", docs, approvaltest.ApproveEventDocsOptions{WriteDiff: true})
+	assert.True(t, rt.failed)
+	assert.FileExists(t, filepath.Join(dir, "approvals", "diff"+approvaltest.ReceivedSuffix))
+
+	data, err := os.ReadFile(filepath.Join(dir, "approvals", "diff"+approvaltest.DiffSuffix))
+	require.NoError(t, err)
+	var diff []approvaltest.DiffEntry
+	require.NoError(t, json.Unmarshal(data, &diff))
+	assert.Equal(t, []approvaltest.DiffEntry{
+		{Path: "events.0.labels.1", Approved: nil, Received: "b"},
+		{Path: "events.0.service.version", Approved: "1.0", Received: "2.0"},
+	}, diff)
+
+	// Once the events match, the diff file is removed.
+	writeApproved(t, dir, "diff", `{"events": [{"service": {"name": "svc", "version": "2.0"}, "labels": ["a", "b"]}]}`)
+	rt = &recordingT{TB: t}
+	approvaltest.ApproveEventDocsWithOptions(rt, "diff", docs, approvaltest.ApproveEventDocsOptions{WriteDiff: true})
+	assert.False(t, rt.failed, rt.msg)
+	assert.NoFileExists(t, filepath.Join(dir, "approvals", "diff"+approvaltest.DiffSuffix))
+}
+
+// recordingT is a testing.TB which records, rather than reports, fatal errors.
+type recordingT struct {
+	testing.TB
+	failed bool
+	msg    string
+}
+
+func (t *recordingT) Fatal(args ...any) {
+	t.failed = true
+	t.msg = fmt.Sprint(args...)
+}
+
+func (t *recordingT) Fatalf(format string, args ...any) {
+	t.failed = true
+	t.msg = fmt.Sprintf(format, args...)
+}
+
+// chdirTemp changes the working directory to a temporary
+// directory for the duration of the test, and returns it.
+func chdirTemp(t testing.TB) string {
+	t.Helper()
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() { os.Chdir(wd) })
+	return dir
+}
+
+// writeApproved writes content to "approvals/<name>.approved.json" in dir.
+func writeApproved(t testing.TB, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, "approvals", name+approvaltest.ApprovedSuffix)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
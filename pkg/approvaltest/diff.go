@@ -0,0 +1,111 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package approvaltest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// DiffEntry describes a difference between an approved and received value.
+type DiffEntry struct {
+	// Path holds the dot-separated path of the differing value,
+	// with array elements identified by their index.
+	Path string `json:"path"`
+
+	// Approved holds the approved value, or nil if the
+	// value is only present in the received value.
+	Approved any `json:"approved"`
+
+	// Received holds the received value, or nil if the
+	// value is only present in the approved value.
+	Received any `json:"received"`
+}
+
+// diffJSON walks the decoded JSON values approved and received,
+// returning an entry for each differing value, ordered by path.
+func diffJSON(path string, approved, received any) []DiffEntry {
+	switch approved := approved.(type) {
+	case map[string]any:
+		received, ok := received.(map[string]any)
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(approved)+len(received))
+		for k := range approved {
+			keys = append(keys, k)
+		}
+		for k := range received {
+			if _, ok := approved[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		var out []DiffEntry
+		for _, k := range keys {
+			out = append(out, diffJSON(joinDiffPath(path, k), approved[k], received[k])...)
+		}
+		return out
+	case []any:
+		received, ok := received.([]any)
+		if !ok {
+			break
+		}
+		var out []DiffEntry
+		for i := 0; i < max(len(approved), len(received)); i++ {
+			var a, r any
+			if i < len(approved) {
+				a = approved[i]
+			}
+			if i < len(received) {
+				r = received[i]
+			}
+			out = append(out, diffJSON(joinDiffPath(path, strconv.Itoa(i)), a, r)...)
+		}
+		return out
+	}
+	if reflect.DeepEqual(approved, received) {
+		return nil
+	}
+	return []DiffEntry{{Path: path, Approved: approved, Received: received}}
+}
+
+func joinDiffPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func writeDiffFile(name string, diff []DiffEntry) error {
+	if diff == nil {
+		diff = []DiffEntry{}
+	}
+	data, err := json.MarshalIndent(diff, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to encode diff file for %s: %w", name, err)
+	}
+	if err := os.WriteFile(name+DiffSuffix, data, 0644); err != nil {
+		return fmt.Errorf("failed to write diff file for %s: %w", name, err)
+	}
+	return nil
+}
@@ -20,45 +20,16 @@ package approvaltest
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
 	"strings"
 
 	"github.com/tidwall/gjson"
-)
-
-type eventType int
 
-const (
-	undefinedEventType eventType = iota
-	errorEventType
-	logEventType
-	metricEventType
-	spanEventType
-	transactionEventType
+	"github.com/elastic/apm-tools/pkg/espoll"
 )
 
-// eventType derives the event type from various fields.
-func getEventType(fields json.RawMessage) eventType {
-	datastreamType := gjson.GetBytes(fields, `data_stream\.type.0`)
-	datastreamDataset := gjson.GetBytes(fields, `data_stream\.dataset.0`)
-	switch datastreamType.Str {
-	case "logs":
-		if datastreamDataset.Str == "apm.error" {
-			return errorEventType
-		}
-		return logEventType
-	case "metrics":
-		return metricEventType
-	case "traces":
-		if gjson.GetBytes(fields, `span\.type`).Exists() {
-			return spanEventType
-		}
-		if gjson.GetBytes(fields, `transaction\.type`).Exists() {
-			return transactionEventType
-		}
-	}
-	return undefinedEventType
-}
-
 var docSortFields = []string{
 	"trace.id",
 	"transaction.id",
@@ -79,10 +50,14 @@ func compareDocumentFields(i, j json.RawMessage) int {
 	// NOTE(axw) we should remove this event type derivation and comparison
 	// in the future, and sort purely on fields. We're doing this to avoid
 	// reordering all the approval files while removing `processor.event`.
-	// If/when we change sort order, we should add a tool for re-sorting
-	// *.approved.json files.
-	if n := getEventType(i) - getEventType(j); n != 0 {
-		return int(n)
+	// If/when we change sort order, use SortApprovedFile (or the
+	// sort-approvals command) to re-sort *.approved.json files.
+	//
+	// Event types are compared by name, which orders documents of
+	// undetermined type first, then errors, logs, metrics, spans,
+	// and transactions.
+	if n := strings.Compare(espoll.FieldsEventType(i), espoll.FieldsEventType(j)); n != 0 {
+		return n
 	}
 	for _, field := range docSortFields {
 		path := strings.ReplaceAll(field, ".", "\\.")
@@ -105,3 +80,50 @@ func compareDocumentFields(i, j json.RawMessage) int {
 	// All sort fields are equivalent, so compare bytes.
 	return bytes.Compare(i, j)
 }
+
+// SortApprovedFile sorts the documents in the approved file at path,
+// in the same order used when approving them, and writes the result
+// back to the file.
+//
+// The file may hold either an object with an "events" array, as written
+// by ApproveEvents, or an array of documents, as written by ApproveFields.
+func SortApprovedFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read approved file: %w", err)
+	}
+
+	var events struct {
+		Events []json.RawMessage `json:"events"`
+	}
+	var docs []json.RawMessage
+	var out any
+	if err := json.Unmarshal(data, &docs); err == nil {
+		out = docs
+	} else if err := json.Unmarshal(data, &events); err == nil && events.Events != nil {
+		docs = events.Events
+		out = events
+	} else {
+		return fmt.Errorf("failed to decode approved file %q: expected an array or an object with an events array", path)
+	}
+	sort.SliceStable(docs, func(i, j int) bool {
+		return compareDocumentFields(docs[i], docs[j]) < 0
+	})
+
+	// Decode and re-encode the sorted documents, so
+	// they are written the same way as received files.
+	var v any
+	if data, err = json.Marshal(out); err != nil {
+		return fmt.Errorf("failed to encode approved file %q: %w", path, err)
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return fmt.Errorf("failed to decode approved file %q: %w", path, err)
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "    ")
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("failed to encode approved file %q: %w", path, err)
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
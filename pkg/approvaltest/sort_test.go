@@ -0,0 +1,72 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package approvaltest_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/apm-tools/pkg/approvaltest"
+)
+
+func TestSortApprovedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test"+approvaltest.ApprovedSuffix)
+	require.NoError(t, os.WriteFile(path, []byte(`[
+	{"data_stream.type": ["traces"], "transaction.type": ["request"], "trace.id": ["b"]},
+	{"data_stream.type": ["traces"], "span.type": ["db"], "trace.id": ["a"]},
+	{"data_stream.type": ["metrics"], "metricset.interval": ["1m"]},
+	{"data_stream.type": ["logs"], "data_stream.dataset": ["apm.error"], "error.id": ["e"]},
+	{"data_stream.type": ["traces"], "transaction.type": ["request"], "trace.id": ["a"]},
+	{"processor.event": ["transaction"], "trace.id": ["z"]}
+]`), 0644))
+
+	require.NoError(t, approvaltest.SortApprovedFile(path))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	// Documents without data stream fields have an undetermined
+	// event type, and are sorted first regardless of processor.event.
+	assert.JSONEq(t, `[
+	{"processor.event": ["transaction"], "trace.id": ["z"]},
+	{"data_stream.type": ["logs"], "data_stream.dataset": ["apm.error"], "error.id": ["e"]},
+	{"data_stream.type": ["metrics"], "metricset.interval": ["1m"]},
+	{"data_stream.type": ["traces"], "span.type": ["db"], "trace.id": ["a"]},
+	{"data_stream.type": ["traces"], "transaction.type": ["request"], "trace.id": ["a"]},
+	{"data_stream.type": ["traces"], "transaction.type": ["request"], "trace.id": ["b"]}
+]`, string(data))
+
+	// Sorting is idempotent, and uses the same indentation as received files.
+	require.NoError(t, approvaltest.SortApprovedFile(path))
+	resorted, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, string(data), string(resorted))
+	assert.Contains(t, string(data), "\n    {\n        \"data_stream.dataset\"")
+}
+
+func TestSortApprovedFileEvents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test"+approvaltest.ApprovedSuffix)
+	require.NoError(t, os.WriteFile(path, []byte(`{"events": [{"b": 1}, {"a": 1}]}`), 0644))
+
+	require.NoError(t, approvaltest.SortApprovedFile(path))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"events": [{"a": 1}, {"b": 1}]}`, string(data))
+}
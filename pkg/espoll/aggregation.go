@@ -0,0 +1,127 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package espoll
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// TermsBucket holds a bucket of a terms aggregation.
+type TermsBucket struct {
+	Key         any
+	KeyAsString string
+	DocCount    int64
+
+	// Sub holds any sub-aggregations of the bucket, keyed by name.
+	Sub map[string]json.RawMessage
+}
+
+func (b *TermsBucket) UnmarshalJSON(data []byte) error {
+	sub, err := decodeBucket(data, &b.Key, &b.KeyAsString, &b.DocCount)
+	if err != nil {
+		return err
+	}
+	b.Sub = sub
+	return nil
+}
+
+// DateHistogramBucket holds a bucket of a date_histogram aggregation.
+type DateHistogramBucket struct {
+	// Key holds the bucket key in milliseconds since the Unix epoch.
+	Key         int64
+	KeyAsString string
+	DocCount    int64
+
+	// Sub holds any sub-aggregations of the bucket, keyed by name.
+	Sub map[string]json.RawMessage
+}
+
+func (b *DateHistogramBucket) UnmarshalJSON(data []byte) error {
+	sub, err := decodeBucket(data, &b.Key, &b.KeyAsString, &b.DocCount)
+	if err != nil {
+		return err
+	}
+	b.Sub = sub
+	return nil
+}
+
+// TermsAggregation decodes and returns the buckets of the named
+// terms aggregation.
+func (r SearchResult) TermsAggregation(name string) ([]TermsBucket, error) {
+	var agg struct {
+		SumOtherDocCount *int64        `json:"sum_other_doc_count"`
+		Buckets          []TermsBucket `json:"buckets"`
+	}
+	if err := r.decodeAggregation(name, &agg); err != nil {
+		return nil, err
+	}
+	if agg.Buckets == nil || agg.SumOtherDocCount == nil {
+		return nil, fmt.Errorf("aggregation %q is not a terms aggregation", name)
+	}
+	return agg.Buckets, nil
+}
+
+// DateHistogram decodes and returns the buckets of the named
+// date_histogram aggregation.
+func (r SearchResult) DateHistogram(name string) ([]DateHistogramBucket, error) {
+	var agg struct {
+		SumOtherDocCount *int64                `json:"sum_other_doc_count"`
+		Buckets          []DateHistogramBucket `json:"buckets"`
+	}
+	if err := r.decodeAggregation(name, &agg); err != nil {
+		return nil, err
+	}
+	if agg.Buckets == nil || agg.SumOtherDocCount != nil {
+		return nil, fmt.Errorf("aggregation %q is not a date_histogram aggregation", name)
+	}
+	return agg.Buckets, nil
+}
+
+func (r SearchResult) decodeAggregation(name string, out any) error {
+	raw, ok := r.Aggregations[name]
+	if !ok {
+		return fmt.Errorf("aggregation %q not found", name)
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("error decoding aggregation %q: %w", name, err)
+	}
+	return nil
+}
+
+// decodeBucket decodes the common fields of an aggregation bucket,
+// returning the remaining fields as sub-aggregations.
+func decodeBucket(data []byte, key any, keyAsString *string, docCount *int64) (map[string]json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for name, out := range map[string]any{
+		"key":           key,
+		"key_as_string": keyAsString,
+		"doc_count":     docCount,
+	} {
+		if raw, ok := fields[name]; ok {
+			if err := json.Unmarshal(raw, out); err != nil {
+				return nil, fmt.Errorf("error decoding bucket %s: %w", name, err)
+			}
+			delete(fields, name)
+		}
+	}
+	return fields, nil
+}
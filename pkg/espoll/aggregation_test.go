@@ -0,0 +1,153 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package espoll_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/apm-tools/pkg/espoll"
+)
+
+const aggregationsResponse = `{
+  "hits": {"total": {"value": 3, "relation": "eq"}, "hits": []},
+  "aggregations": {
+    "services": {
+      "doc_count_error_upper_bound": 0,
+      "sum_other_doc_count": 0,
+      "buckets": [
+        {"key": "frontend", "doc_count": 2, "max_duration": {"value": 150.0}},
+        {"key": "backend", "doc_count": 1, "max_duration": {"value": 50.0}}
+      ]
+    },
+    "over_time": {
+      "buckets": [
+        {
+          "key_as_string": "2024-01-01T00:00:00.000Z",
+          "key": 1704067200000,
+          "doc_count": 3,
+          "services": {
+            "doc_count_error_upper_bound": 0,
+            "sum_other_doc_count": 0,
+            "buckets": [{"key": "frontend", "doc_count": 2}, {"key": "backend", "doc_count": 1}]
+          }
+        },
+        {"key_as_string": "2024-01-01T00:01:00.000Z", "key": 1704067260000, "doc_count": 0}
+      ]
+    }
+  }
+}`
+
+func TestTermsAggregation(t *testing.T) {
+	var result espoll.SearchResult
+	require.NoError(t, json.Unmarshal([]byte(aggregationsResponse), &result))
+
+	buckets, err := result.TermsAggregation("services")
+	require.NoError(t, err)
+	require.Len(t, buckets, 2)
+	assert.Equal(t, "frontend", buckets[0].Key)
+	assert.Equal(t, int64(2), buckets[0].DocCount)
+	assert.Equal(t, "backend", buckets[1].Key)
+	assert.Equal(t, int64(1), buckets[1].DocCount)
+	assert.JSONEq(t, `{"value": 150.0}`, string(buckets[0].Sub["max_duration"]))
+
+	_, err = result.TermsAggregation("over_time")
+	assert.EqualError(t, err, `aggregation "over_time" is not a terms aggregation`)
+	_, err = result.TermsAggregation("missing")
+	assert.EqualError(t, err, `aggregation "missing" not found`)
+}
+
+func TestDateHistogram(t *testing.T) {
+	var result espoll.SearchResult
+	require.NoError(t, json.Unmarshal([]byte(aggregationsResponse), &result))
+
+	buckets, err := result.DateHistogram("over_time")
+	require.NoError(t, err)
+	require.Len(t, buckets, 2)
+	assert.Equal(t, int64(1704067200000), buckets[0].Key)
+	assert.Equal(t, "2024-01-01T00:00:00.000Z", buckets[0].KeyAsString)
+	assert.Equal(t, int64(3), buckets[0].DocCount)
+	assert.Equal(t, int64(0), buckets[1].DocCount)
+	assert.Empty(t, buckets[1].Sub)
+
+	// Decode the nested terms sub-aggregation.
+	sub := espoll.SearchResult{Aggregations: buckets[0].Sub}
+	services, err := sub.TermsAggregation("services")
+	require.NoError(t, err)
+	require.Len(t, services, 2)
+	assert.Equal(t, "frontend", services[0].Key)
+	assert.Equal(t, int64(2), services[0].DocCount)
+
+	_, err = result.DateHistogram("services")
+	assert.Error(t, err)
+	_, err = result.DateHistogram("missing")
+	assert.EqualError(t, err, `aggregation "missing" not found`)
+}
+
+func TestSearchUntilAgg(t *testing.T) {
+	var requests int
+	var body map[string]any
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, "0", r.URL.Query().Get("size"))
+		body = nil
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		fmt.Fprintf(w, `{
+		  "hits": {"total": {"value": %d, "relation": "eq"}, "hits": []},
+		  "aggregations": {"total_duration": {"value": %d}}
+		}`, requests, requests*100)
+	})
+
+	aggs := map[string]any{"total_duration": map[string]any{"sum": map[string]any{"field": "duration"}}}
+	totalDuration := func(result espoll.SearchResult) float64 {
+		var agg struct {
+			Value float64 `json:"value"`
+		}
+		json.Unmarshal(result.Aggregations["total_duration"], &agg)
+		return agg.Value
+	}
+	result, err := client.SearchUntilAgg(context.Background(), "traces-*", nil, aggs,
+		func(result espoll.SearchResult) bool { return totalDuration(result) >= 300 },
+		espoll.WithRefresh(false),
+		espoll.WithPollInterval(time.Millisecond),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, 3, requests)
+	assert.Equal(t, float64(300), totalDuration(result))
+	assert.Equal(t, map[string]any{"total_duration": map[string]any{"sum": map[string]any{"field": "duration"}}}, body["aggs"])
+}
+
+func TestSearchUntilAggTimeout(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"hits": {"total": {"value": 0, "relation": "eq"}, "hits": []}, "aggregations": {}}`))
+	})
+	_, err := client.SearchUntilAgg(context.Background(), "traces-*", nil, nil,
+		func(espoll.SearchResult) bool { return false },
+		espoll.WithRefresh(false),
+		espoll.WithPollInterval(time.Millisecond),
+		espoll.WithTimeout(50*time.Millisecond),
+	)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
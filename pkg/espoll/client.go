@@ -22,8 +22,13 @@ package espoll
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"fmt"
 	"io"
+	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/elastic/go-elasticsearch/v8"
@@ -33,11 +38,181 @@ import (
 // Client wraps an Elasticsearch client
 type Client struct {
 	*elasticsearch.Client
+
+	// httpDump reports whether the Elasticsearch client's HTTP
+	// transport writes requests marked by WithRequestDump.
+	httpDump bool
 }
 
 // WrapClient wraps an Elasticsearch client and returns an espoll.Client
 func WrapClient(c *elasticsearch.Client) *Client { return &Client{Client: c} }
 
+// ClientConfig holds configuration for NewClient.
+type ClientConfig struct {
+	// Addresses holds the Elasticsearch URLs.
+	Addresses []string
+
+	// Username holds the Elasticsearch username for basic auth.
+	Username string
+
+	// Password holds the Elasticsearch password for basic auth.
+	Password string
+
+	// APIKey holds an Elasticsearch API Key.
+	APIKey string
+
+	// TLSSkipVerify determines if TLS certificate
+	// verification is skipped or not. Default to false.
+	TLSSkipVerify bool
+
+	// MaxRetries holds the maximum number of times a request will be
+	// retried by the Elasticsearch client. If nil, defaults to 5.
+	// If zero, requests will not be retried.
+	MaxRetries *int
+
+	// MaxBackoff holds the maximum time to wait between retries, which
+	// otherwise increases exponentially from 500ms. Defaults to 10s.
+	//
+	// Requests rejected with 429 Too Many Requests are also retried up
+	// to MaxRetries times, waiting for the duration specified by the
+	// Retry-After response header if any, capped at MaxBackoff.
+	MaxBackoff time.Duration
+}
+
+// NewClient returns a new espoll.Client, wrapping an Elasticsearch
+// client created with the given configuration.
+func NewClient(cfg ClientConfig) (*Client, error) {
+	maxRetries := 5
+	if cfg.MaxRetries != nil {
+		maxRetries = *cfg.MaxRetries
+	}
+	if cfg.MaxBackoff == 0 {
+		cfg.MaxBackoff = 10 * time.Second
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: cfg.TLSSkipVerify}
+
+	// The Elasticsearch client's RetryBackoff has no access to the
+	// response, so 429 responses are retried by the HTTP transport,
+	// where the Retry-After header can be honoured.
+	// Requests marked by WithRequestDump are written before any retries,
+	// with the full URL and the headers added by the Elasticsearch client.
+	var rt http.RoundTripper = &requestDumper{rt: transport}
+	if maxRetries > 0 {
+		rt = &retryAfterTransport{
+			rt:         rt,
+			maxRetries: maxRetries,
+			backoff:    retryBackoff(cfg.MaxBackoff),
+			maxBackoff: cfg.MaxBackoff,
+			wait:       waitContext,
+		}
+	}
+
+	client, err := elasticsearch.NewClient(elasticsearch.Config{
+		Addresses:    cfg.Addresses,
+		Username:     cfg.Username,
+		Password:     cfg.Password,
+		APIKey:       cfg.APIKey,
+		Transport:    rt,
+		MaxRetries:   maxRetries,
+		DisableRetry: maxRetries <= 0,
+		RetryBackoff: retryBackoff(cfg.MaxBackoff),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating Elasticsearch client: %w", err)
+	}
+	return &Client{Client: client, httpDump: true}, nil
+}
+
+// retryBackoff returns a function which returns an exponentially
+// increasing backoff for each retry attempt, capped at max.
+func retryBackoff(max time.Duration) func(attempt int) time.Duration {
+	return func(attempt int) time.Duration {
+		backoff := (500 * time.Millisecond) * (1 << (attempt - 1))
+		if backoff > max || backoff <= 0 {
+			backoff = max
+		}
+		return backoff
+	}
+}
+
+// retryAfterTransport is an http.RoundTripper which retries requests
+// rejected with 429 Too Many Requests, waiting for the duration given
+// by the Retry-After header, or otherwise backoff, capped at maxBackoff.
+type retryAfterTransport struct {
+	rt         http.RoundTripper
+	maxRetries int
+	backoff    func(attempt int) time.Duration
+	maxBackoff time.Duration
+
+	// wait waits for d to elapse, or returns ctx.Err() if ctx is done first.
+	wait func(ctx context.Context, d time.Duration) error
+}
+
+func (t *retryAfterTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := t.rt.RoundTrip(req)
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests || attempt > t.maxRetries {
+			return resp, err
+		}
+		hasBody := req.Body != nil && req.Body != http.NoBody
+		if hasBody && req.GetBody == nil {
+			// The body cannot be replayed.
+			return resp, nil
+		}
+		delay, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		if !ok {
+			delay = t.backoff(attempt)
+		}
+		if delay > t.maxBackoff {
+			delay = t.maxBackoff
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if err := t.wait(req.Context(), delay); err != nil {
+			return nil, err
+		}
+		if hasBody {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, fmt.Errorf("cannot get request body: %w", err)
+			}
+		}
+	}
+}
+
+// parseRetryAfter parses the value of a Retry-After header, which may be
+// a number of seconds or an HTTP-date, returning the duration to wait
+// relative to now, and whether value is valid.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	t, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	return max(t.Sub(now), 0), true
+}
+
+// waitContext waits for d to elapse, or returns ctx.Err()
+// if ctx is done first.
+func waitContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
 type Request interface {
 	Do(ctx context.Context, transport esapi.Transport) (*esapi.Response, error)
 }
@@ -49,18 +224,9 @@ func (es *Client) Do(
 	out any,
 	opts ...RequestOption,
 ) (*esapi.Response, error) {
-	requestOptions := requestOptions{
-		// Set the timeout to something high to account for Elasticsearch
-		// cluster and index/shard initialisation. Under normal conditions
-		// this timeout should never be reached.
-		timeout:  time.Minute,
-		interval: 100 * time.Millisecond,
-	}
-	for _, opt := range opts {
-		opt(&requestOptions)
-	}
-	var timeoutC, tickerC <-chan time.Time
-	var transport esapi.Transport = es
+	requestOptions := newRequestOptions(opts)
+	var timeoutC <-chan time.Time
+	transport := requestOptions.wrapTransport(es, es.httpDump)
 	if requestOptions.cond != nil {
 		// A return condition has been specified, which means we
 		// might retry the request. Wrap the transport with a
@@ -74,14 +240,8 @@ func (es *Client) Do(
 	}
 
 	var resp *esapi.Response
+	var pollTimer *time.Timer
 	for {
-		if tickerC != nil {
-			select {
-			case <-timeoutC:
-				return nil, context.DeadlineExceeded
-			case <-tickerC:
-			}
-		}
 		var err error
 		resp, err = req.Do(ctx, transport)
 		if err != nil {
@@ -89,7 +249,7 @@ func (es *Client) Do(
 		}
 		defer resp.Body.Close()
 		if resp.IsError() {
-			return nil, &Error{StatusCode: resp.StatusCode, Message: resp.String()}
+			return nil, newESError(resp)
 		}
 		body, err := io.ReadAll(resp.Body)
 		if err != nil {
@@ -104,46 +264,390 @@ func (es *Client) Do(
 		if requestOptions.cond == nil || requestOptions.cond(resp) {
 			break
 		}
-		if tickerC == nil {
-			// First time around, start a ticker for retrying.
-			ticker := time.NewTicker(requestOptions.interval)
-			defer ticker.Stop()
-			tickerC = ticker.C
+		// Wait for the poll interval after each attempt, rather than
+		// at a fixed rate, so slow requests are not retried immediately.
+		if pollTimer == nil {
+			pollTimer = time.NewTimer(requestOptions.interval)
+			defer pollTimer.Stop()
+		} else {
+			pollTimer.Reset(requestOptions.interval)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timeoutC:
+			return nil, context.DeadlineExceeded
+		case <-pollTimer.C:
 		}
 	}
 	return resp, nil
 }
 
+// decodeResponseBody decodes the body of resp into out, restoring the
+// body so it may be read again.
+func decodeResponseBody(resp *esapi.Response, out any) error {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return json.Unmarshal(body, out)
+}
+
 // RequestOption modifies certain parameters for an esapi.Request.
 type RequestOption func(*requestOptions)
 
-type Error struct {
+// ESError is returned by Client.Do when Elasticsearch responds with
+// an error status code.
+type ESError struct {
+	// StatusCode holds the HTTP status code of the response.
 	StatusCode int
-	Message    string
+
+	// Type holds the error.type field of the response body, if any.
+	Type string
+
+	// Reason holds the error.reason field of the response body, if any.
+	Reason string
+
+	// Message holds the response status and body.
+	Message string
+}
+
+// Error is an alias of ESError.
+//
+// Deprecated: use ESError.
+type Error = ESError
+
+func (e *ESError) Error() string {
+	if e.Type == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("elasticsearch returned status %d: %s: %s", e.StatusCode, e.Type, e.Reason)
 }
 
-func (e *Error) Error() string {
-	return e.Message
+// newESError returns an ESError for resp, decoding the error type
+// and reason from the response body if possible.
+func newESError(resp *esapi.Response) *ESError {
+	esErr := &ESError{StatusCode: resp.StatusCode}
+	body, err := io.ReadAll(resp.Body)
+	if err == nil {
+		var errorBody struct {
+			Error struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(body, &errorBody) == nil {
+			esErr.Type = errorBody.Error.Type
+			esErr.Reason = errorBody.Error.Reason
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	esErr.Message = resp.String()
+	return esErr
 }
 
 type requestOptions struct {
 	timeout  time.Duration
 	interval time.Duration
 	cond     ConditionFunc
+
+	header      http.Header
+	dump        io.Writer
+	compression *bool
+
+	// Search options.
+	sort           []string
+	pageSize       int
+	pageFunc       func([]SearchHit) error
+	pitKeepAlive   time.Duration
+	refresh        bool
+	refreshTarget  string
+	consistency    ReadConsistency
+	preference     string
+	routing        []string
+	exactTotalHits bool
+
+	expandWildcards string
+}
+
+// wrapTransport returns t wrapped to add any headers set by WithHeader,
+// to dump the first request if WithRequestDump is specified, and to
+// compress request bodies according to WithCompression. If httpDump
+// is true, the request is dumped by the client's HTTP transport.
+func (opts requestOptions) wrapTransport(t esapi.Transport, httpDump bool) esapi.Transport {
+	if opts.compression == nil || *opts.compression {
+		t = &bodyCompressor{t: t, always: opts.compression != nil}
+	}
+	if opts.dump != nil {
+		t = &requestDumpMarker{t: t, w: opts.dump, httpDump: httpDump}
+	}
+	if len(opts.header) != 0 {
+		t = &headerSetter{t: t, header: opts.header}
+	}
+	return t
+}
+
+func newRequestOptions(opts []RequestOption) requestOptions {
+	options := requestOptions{
+		// Set the timeout to something high to account for Elasticsearch
+		// cluster and index/shard initialisation. Under normal conditions
+		// this timeout should never be reached.
+		timeout:  time.Minute,
+		interval: 500 * time.Millisecond,
+		pageSize: 10,
+		refresh:  true,
+
+		exactTotalHits: true,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
 }
 
-// WithTimeout sets the timeout in an Elasticsearch request.
+// WithTimeout sets the maximum time to wait for a request's condition
+// to be satisfied, across all attempts. Defaults to 1 minute.
+//
+// The timeout only applies to requests with a condition, such as
+// those made by Client.SearchIndexMinDocs; use the context to bound
+// individual requests.
 func WithTimeout(d time.Duration) RequestOption {
 	return func(opts *requestOptions) {
 		opts.timeout = d
 	}
 }
 
+// WithHeader adds a header to the Elasticsearch requests, such as
+// X-Opaque-Id for correlation, or headers for routing through proxies.
+// Repeated calls accumulate headers, including multiple values for the
+// same key, which replace any value set by the Elasticsearch client.
+func WithHeader(key, value string) RequestOption {
+	return func(opts *requestOptions) {
+		if opts.header == nil {
+			opts.header = make(http.Header)
+		}
+		opts.header.Add(key, value)
+	}
+}
+
+// WithRequestDump writes each Elasticsearch request to w before it is
+// first sent, including the method, URL, headers, and body, for debugging.
+// Requests repeated while polling or retried are not written again. The
+// values of Authorization headers are redacted. See NewRequestDumper.
+//
+// For clients created with WrapClient, the request is written as passed
+// to the Elasticsearch client: with a URL path rather than the full URL,
+// and without the headers added by the Elasticsearch client itself, such
+// as those for authentication.
+func WithRequestDump(w io.Writer) RequestOption {
+	return func(opts *requestOptions) {
+		opts.dump = w
+	}
+}
+
+// WithCompression sets whether request bodies, such as search queries,
+// are gzip-encoded. By default, bodies of at least 1 KiB are compressed.
+//
+// If true, all request bodies are compressed; if false, none are.
+func WithCompression(compress bool) RequestOption {
+	return func(opts *requestOptions) {
+		opts.compression = &compress
+	}
+}
+
+// WithPollInterval sets the time to wait between attempts of a request
+// whose condition is not satisfied. Defaults to 500ms.
+//
+// The overall time spent polling is capped by WithTimeout.
+func WithPollInterval(d time.Duration) RequestOption {
+	return func(opts *requestOptions) {
+		opts.interval = d
+	}
+}
+
 // WithInterval sets the poll interval in an Elasticsearch request.
+//
+// Deprecated: use WithPollInterval.
 func WithInterval(d time.Duration) RequestOption {
+	return WithPollInterval(d)
+}
+
+// WithSort sets the sort order of the search hits, as a list of
+// <field>:<direction> pairs.
+//
+// This is required by Client.SearchAll, and optional for
+// Client.SearchIndexMinDocs.
+func WithSort(fieldDirection ...string) RequestOption {
 	return func(opts *requestOptions) {
-		opts.interval = d
+		opts.sort = fieldDirection
+	}
+}
+
+// WithPageSize sets the number of hits requested in each page
+// of results by Client.SearchAll and Client.ScrollAll. Defaults to 10.
+func WithPageSize(size int) RequestOption {
+	return func(opts *requestOptions) {
+		opts.pageSize = size
+	}
+}
+
+// WithPageFunc sets a function which Client.ScrollAll calls with each
+// page of hits, rather than accumulating them, to bound memory usage
+// when exporting large result sets. If f returns an error, scrolling
+// stops and the error is returned.
+func WithPageFunc(f func(hits []SearchHit) error) RequestOption {
+	return func(opts *requestOptions) {
+		opts.pageFunc = f
+	}
+}
+
+// WithPITKeepAlive makes Client.SearchIndexMinDocs search against a
+// point in time (PIT), kept alive for d. This ensures each search is
+// consistent while the underlying indices change.
+//
+// A fresh PIT is opened for each search attempt, so that documents
+// indexed while polling become visible, and the previous PIT is
+// closed. The last PIT is closed when Client.SearchIndexMinDocs returns.
+func WithPITKeepAlive(d time.Duration) RequestOption {
+	return func(opts *requestOptions) {
+		opts.pitKeepAlive = d
+	}
+}
+
+// WithRefresh sets whether the target indices are refreshed before
+// searching or counting documents. Defaults to true.
+//
+// Disabling refresh avoids the cost (and privileges) of refreshing,
+// at the expense of only seeing documents in already-refreshed indices.
+func WithRefresh(refresh bool) RequestOption {
+	return func(opts *requestOptions) {
+		opts.refresh = refresh
+	}
+}
+
+// WithRefreshTarget sets the comma-separated indices to refresh before
+// searching or counting documents, which defaults to the searched indices.
+func WithRefreshTarget(target string) RequestOption {
+	return func(opts *requestOptions) {
+		opts.refreshTarget = target
+	}
+}
+
+// ReadConsistency determines whether searches and counts observe
+// recently indexed documents, trading consistency for speed.
+type ReadConsistency string
+
+const (
+	// ReadConsistencyRefresh refreshes the target indices before each
+	// search or count, so that all documents indexed beforehand are
+	// visible. This is the default.
+	ReadConsistencyRefresh ReadConsistency = "refresh"
+
+	// ReadConsistencyEventual skips refreshing, and enables the shard
+	// request cache so that repeated polls are served from the cache.
+	//
+	// This avoids the cost of refreshing, which is pure overhead for
+	// immutable data such as historical or frozen indices. However,
+	// documents only become visible once Elasticsearch periodically
+	// refreshes the indices, and search requests which use "now" in
+	// date ranges are never cached.
+	ReadConsistencyEventual ReadConsistency = "eventual"
+)
+
+// WithReadConsistency sets the read consistency for searching and
+// counting documents. Defaults to ReadConsistencyRefresh.
+//
+// ReadConsistencyEventual overrides WithRefresh.
+func WithReadConsistency(level ReadConsistency) RequestOption {
+	return func(opts *requestOptions) {
+		opts.consistency = level
+	}
+}
+
+// WithPreference sets the search preference for Client.SearchIndexMinDocs
+// and Client.SearchAll, e.g. "_primary" so that polling reads are not
+// served by stale replicas. See SearchRequest.WithPreference.
+func WithPreference(preference string) RequestOption {
+	return func(opts *requestOptions) {
+		opts.preference = preference
+	}
+}
+
+// WithRouting sets the custom routing values for Client.SearchIndexMinDocs
+// and Client.SearchAll. See SearchRequest.WithRouting.
+func WithRouting(routing ...string) RequestOption {
+	return func(opts *requestOptions) {
+		opts.routing = routing
+	}
+}
+
+// WithExactTotalHits sets whether Client.SearchIndexMinDocs requires
+// exact total hits, returning all matching documents. Defaults to true.
+//
+// If false, the search sets terminate_after to the minimum number of
+// documents, so each shard stops searching once it has found enough.
+// This is useful for existence checks against large indices.
+func WithExactTotalHits(exact bool) RequestOption {
+	return func(opts *requestOptions) {
+		opts.exactTotalHits = exact
+	}
+}
+
+// WithExpandWildcards sets the comma-separated types of indices that
+// wildcard targets are expanded to, for searching, counting, and
+// refreshing documents. Valid types are open, closed, hidden, none,
+// and all.
+//
+// By default searches and counts expand to "open,hidden", and
+// refreshes expand to "all".
+func WithExpandWildcards(expandWildcards string) RequestOption {
+	return func(opts *requestOptions) {
+		opts.expandWildcards = expandWildcards
+	}
+}
+
+// ValidateExpandWildcards returns an error if expandWildcards is not a
+// comma-separated list of open, closed, hidden, none, or all.
+func ValidateExpandWildcards(expandWildcards string) error {
+	for _, v := range strings.Split(expandWildcards, ",") {
+		switch strings.TrimSpace(v) {
+		case "open", "closed", "hidden", "none", "all":
+		default:
+			return fmt.Errorf(
+				"invalid expand_wildcards %q, expected a comma-separated list of: open, closed, hidden, none, all",
+				expandWildcards,
+			)
+		}
+	}
+	return nil
+}
+
+// expandWildcardsOr returns the expand_wildcards value set by
+// WithExpandWildcards, or def if none was set.
+func (opts requestOptions) expandWildcardsOr(def string) string {
+	if opts.expandWildcards != "" {
+		return opts.expandWildcards
+	}
+	return def
+}
+
+// validate returns an error if any of the options are invalid.
+func (opts requestOptions) validate() error {
+	switch opts.consistency {
+	case "", ReadConsistencyRefresh, ReadConsistencyEventual:
+	default:
+		return fmt.Errorf(
+			"invalid read consistency %q, expected one of: %s, %s",
+			opts.consistency, ReadConsistencyRefresh, ReadConsistencyEventual,
+		)
+	}
+	if opts.expandWildcards != "" {
+		if err := ValidateExpandWildcards(opts.expandWildcards); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
 // ConditionFunc evaluates the esapi.Response.
@@ -0,0 +1,138 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package espoll
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	backoff := retryBackoff(3 * time.Second)
+	assert.Equal(t, 500*time.Millisecond, backoff(1))
+	assert.Equal(t, time.Second, backoff(2))
+	assert.Equal(t, 2*time.Second, backoff(3))
+	assert.Equal(t, 3*time.Second, backoff(4))
+	assert.Equal(t, 3*time.Second, backoff(10))
+	assert.Equal(t, 3*time.Second, backoff(100))
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestRetryAfterTransport(t *testing.T) {
+	for name, tc := range map[string]struct {
+		retryAfter string
+		expected   time.Duration
+	}{
+		"seconds":        {retryAfter: "2", expected: 2 * time.Second},
+		"capped":         {retryAfter: "60", expected: 10 * time.Second},
+		"http_date":      {retryAfter: time.Now().Add(5 * time.Second).UTC().Format(http.TimeFormat), expected: 5 * time.Second},
+		"absent":         {expected: 500 * time.Millisecond},
+		"invalid":        {retryAfter: "soon", expected: 500 * time.Millisecond},
+		"negative":       {retryAfter: "-1", expected: 500 * time.Millisecond},
+		"past_http_date": {retryAfter: "Mon, 02 Jan 2006 15:04:05 GMT", expected: 0},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var bodies []string
+			transport := &retryAfterTransport{
+				rt: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+					body, err := io.ReadAll(req.Body)
+					require.NoError(t, err)
+					bodies = append(bodies, string(body))
+					status := http.StatusOK
+					if len(bodies) == 1 {
+						status = http.StatusTooManyRequests
+					}
+					header := make(http.Header)
+					if tc.retryAfter != "" {
+						header.Set("Retry-After", tc.retryAfter)
+					}
+					return &http.Response{
+						StatusCode: status,
+						Header:     header,
+						Body:       io.NopCloser(strings.NewReader(`{}`)),
+					}, nil
+				}),
+				maxRetries: 3,
+				backoff:    retryBackoff(10 * time.Second),
+				maxBackoff: 10 * time.Second,
+			}
+			var waits []time.Duration
+			transport.wait = func(ctx context.Context, d time.Duration) error {
+				waits = append(waits, d)
+				return nil
+			}
+
+			req, err := http.NewRequest(http.MethodPost, "http://elasticsearch.invalid/_search", strings.NewReader(`{"size":1}`))
+			require.NoError(t, err)
+			resp, err := transport.RoundTrip(req)
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, []string{`{"size":1}`, `{"size":1}`}, bodies)
+			require.Len(t, waits, 1)
+			if name == "http_date" {
+				// HTTP-dates have a resolution of one second.
+				assert.InDelta(t, tc.expected, waits[0], float64(time.Second))
+			} else {
+				assert.Equal(t, tc.expected, waits[0])
+			}
+		})
+	}
+}
+
+func TestRetryAfterTransportMaxRetries(t *testing.T) {
+	var attempts int
+	transport := &retryAfterTransport{
+		rt: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			attempts++
+			return &http.Response{
+				StatusCode: http.StatusTooManyRequests,
+				Header:     http.Header{"Retry-After": []string{"1"}},
+				Body:       io.NopCloser(strings.NewReader(`{}`)),
+			}, nil
+		}),
+		maxRetries: 2,
+		backoff:    retryBackoff(time.Second),
+		maxBackoff: time.Second,
+		wait:       func(context.Context, time.Duration) error { return nil },
+	}
+	req, err := http.NewRequest(http.MethodGet, "http://elasticsearch.invalid/", nil)
+	require.NoError(t, err)
+	resp, err := transport.RoundTrip(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, 3, attempts)
+
+	// Waiting is interrupted by cancellation of the request's context.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	transport.wait = waitContext
+	_, err = transport.RoundTrip(req.WithContext(ctx))
+	assert.ErrorIs(t, err, context.Canceled)
+}
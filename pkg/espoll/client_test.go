@@ -0,0 +1,331 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package espoll_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/apm-tools/pkg/espoll"
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+func TestDoErrorBody(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{
+		  "error": {
+		    "root_cause": [{"type": "parsing_exception", "reason": "unknown query [foo]"}],
+		    "type": "parsing_exception",
+		    "reason": "unknown query [foo]"
+		  },
+		  "status": 400
+		}`))
+	})
+
+	var result espoll.SearchResult
+	_, err := client.NewSearchRequest("traces-*").Do(context.Background(), &result)
+	require.Error(t, err)
+
+	var esErr *espoll.ESError
+	require.True(t, errors.As(err, &esErr))
+	assert.Equal(t, http.StatusBadRequest, esErr.StatusCode)
+	assert.Equal(t, "parsing_exception", esErr.Type)
+	assert.Equal(t, "unknown query [foo]", esErr.Reason)
+	assert.EqualError(t, err, "elasticsearch returned status 400: parsing_exception: unknown query [foo]")
+}
+
+func TestDoErrorNonJSONBody(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`forbidden`))
+	})
+
+	var result espoll.SearchResult
+	_, err := client.NewSearchRequest("traces-*").Do(context.Background(), &result)
+	var esErr *espoll.ESError
+	require.True(t, errors.As(err, &esErr))
+	assert.Equal(t, http.StatusForbidden, esErr.StatusCode)
+	assert.Empty(t, esErr.Type)
+	assert.Contains(t, esErr.Message, "forbidden")
+}
+
+func TestNewClientMaxRetries(t *testing.T) {
+	var requests atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	intPtr := func(i int) *int { return &i }
+	for name, tc := range map[string]struct {
+		maxRetries *int
+		expected   int64
+	}{
+		"default":    {maxRetries: nil, expected: 6},
+		"no_retries": {maxRetries: intPtr(0), expected: 1},
+		"one_retry":  {maxRetries: intPtr(1), expected: 2},
+	} {
+		t.Run(name, func(t *testing.T) {
+			requests.Store(0)
+			client, err := espoll.NewClient(espoll.ClientConfig{
+				Addresses:  []string{srv.URL},
+				MaxRetries: tc.maxRetries,
+				MaxBackoff: time.Millisecond,
+			})
+			require.NoError(t, err)
+
+			var result espoll.SearchResult
+			_, err = client.NewSearchRequest("traces-*").Do(context.Background(), &result)
+			require.Error(t, err)
+			assert.Equal(t, tc.expected, requests.Load())
+		})
+	}
+}
+
+func TestDoWithHeader(t *testing.T) {
+	var requests []http.Header
+	transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		requests = append(requests, req.Header.Clone())
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header: http.Header{
+				"X-Elastic-Product": []string{"Elasticsearch"},
+				"Content-Type":      []string{"application/json"},
+			},
+			Body: io.NopCloser(strings.NewReader(`{"hits":{"total":{"value":0,"relation":"eq"},"hits":[]}}`)),
+		}, nil
+	})
+	es, err := elasticsearch.NewClient(elasticsearch.Config{Transport: transport})
+	require.NoError(t, err)
+	client := espoll.WrapClient(es)
+
+	var result espoll.SearchResult
+	_, err = client.NewSearchRequest("traces-*").Do(context.Background(), &result,
+		espoll.WithHeader("X-Opaque-Id", "espoll-test"),
+		espoll.WithHeader("X-Route", "a"),
+		espoll.WithHeader("X-Route", "b"),
+	)
+	require.NoError(t, err)
+	require.Len(t, requests, 1)
+	assert.Equal(t, []string{"espoll-test"}, requests[0].Values("X-Opaque-Id"))
+	assert.Equal(t, []string{"a", "b"}, requests[0].Values("X-Route"))
+
+	_, err = client.NewSearchRequest("traces-*").Do(context.Background(), &result)
+	require.NoError(t, err)
+	require.Len(t, requests, 2)
+	assert.Empty(t, requests[1].Values("X-Opaque-Id"))
+}
+
+func TestWithRequestDump(t *testing.T) {
+	var bodies []string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if len(bodies) == 1 {
+			io.WriteString(w, `{"hits":{"total":{"value":0,"relation":"eq"},"hits":[]}}`)
+			return
+		}
+		io.WriteString(w, `{"hits":{"total":{"value":1,"relation":"eq"},"hits":[{"_id":"1","_index":"a","_source":{},"fields":{}}]}}`)
+	})
+
+	var dump bytes.Buffer
+	_, err := client.SearchIndexMinDocs(context.Background(), 1, "traces-*",
+		espoll.TermQuery{Field: "service.name", Value: "svc"},
+		espoll.WithRefresh(false),
+		espoll.WithPollInterval(time.Millisecond),
+		espoll.WithHeader("Authorization", "ApiKey secret"),
+		espoll.WithRequestDump(&dump),
+	)
+	require.NoError(t, err)
+
+	// The request is dumped once, but its body is sent on each attempt.
+	require.Len(t, bodies, 2)
+	assert.Equal(t, bodies[0], bodies[1])
+	assert.Equal(t, 1, strings.Count(dump.String(), "POST /traces-*/_search"))
+	assert.Contains(t, dump.String(), "\nAuthorization: [REDACTED]\n")
+	assert.NotContains(t, dump.String(), "secret")
+	assert.Contains(t, dump.String(), "\n\n"+bodies[0])
+}
+
+func TestNewClientWithRequestDump(t *testing.T) {
+	var requests atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		if requests.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		io.WriteString(w, `{"hits":{"total":{"value":0,"relation":"eq"},"hits":[]}}`)
+	}))
+	defer srv.Close()
+
+	client, err := espoll.NewClient(espoll.ClientConfig{
+		Addresses:  []string{srv.URL},
+		Username:   "elastic",
+		Password:   "hunter2",
+		MaxBackoff: time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	var dump bytes.Buffer
+	var result espoll.SearchResult
+	_, err = client.NewSearchRequest("traces-*").Do(context.Background(), &result,
+		espoll.WithRequestDump(&dump),
+	)
+	require.NoError(t, err)
+
+	// The request is dumped by the HTTP transport, with the full URL
+	// and the client's authentication, but not again when retried.
+	assert.Equal(t, int64(2), requests.Load())
+	assert.Equal(t, 1, strings.Count(dump.String(), "POST "+srv.URL+"/traces-*/_search"))
+	assert.Contains(t, dump.String(), "\nAuthorization: [REDACTED]\n")
+	assert.NotContains(t, dump.String(), "hunter2")
+}
+
+func TestNewRequestDumper(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	var dump bytes.Buffer
+	client := &http.Client{Transport: espoll.NewRequestDumper(nil, &dump)}
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/path?q=1", strings.NewReader("body"))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	echoed, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, "body", string(echoed))
+	assert.Equal(t, "POST "+srv.URL+"/path?q=1\nAuthorization: [REDACTED]\n\nbody\n", dump.String())
+}
+
+func TestWithPollInterval(t *testing.T) {
+	var attempts atomic.Int64
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		io.WriteString(w, `{"hits":{"total":{"value":0,"relation":"eq"},"hits":[]}}`)
+	})
+
+	for _, interval := range []time.Duration{20 * time.Millisecond, 100 * time.Millisecond} {
+		attempts.Store(0)
+		var result espoll.SearchResult
+		start := time.Now()
+		_, err := client.NewSearchRequest("traces-*").Do(context.Background(), &result,
+			espoll.WithCondition(result.Hits.NonEmptyCondition()),
+			espoll.WithTimeout(250*time.Millisecond),
+			espoll.WithPollInterval(interval),
+		)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), time.Second)
+
+		// One attempt is made immediately, and then one after each
+		// interval until the timeout. Allow for scheduling delays.
+		expected := 1 + int64(250*time.Millisecond/interval)
+		assert.LessOrEqual(t, attempts.Load(), expected, "interval %s", interval)
+		assert.GreaterOrEqual(t, attempts.Load(), expected/2, "interval %s", interval)
+	}
+}
+
+func TestDoContextCancelled(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"hits":{"total":{"value":0,"relation":"eq"},"hits":[]}}`)
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	var result espoll.SearchResult
+	_, err := client.NewSearchRequest("traces-*").Do(ctx, &result,
+		espoll.WithCondition(result.Hits.NonEmptyCondition()),
+		espoll.WithPollInterval(time.Hour),
+	)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestWithCompression(t *testing.T) {
+	var contentEncoding, body string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		contentEncoding = r.Header.Get("Content-Encoding")
+		var rd io.Reader = r.Body
+		if contentEncoding == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			require.NoError(t, err)
+			rd = zr
+		}
+		b, err := io.ReadAll(rd)
+		require.NoError(t, err)
+		body = string(b)
+		io.WriteString(w, `{"hits":{"total":{"value":1,"relation":"eq"},"hits":[{"_id":"1","_index":"a","_source":{},"fields":{}}]}}`)
+	})
+
+	values := make([]any, 100)
+	for i := range values {
+		values[i] = fmt.Sprintf("trace-%d", i)
+	}
+	largeQuery := espoll.TermsQuery{Field: "trace.id", Values: values}
+	smallQuery := espoll.TermQuery{Field: "trace.id", Value: "trace-0"}
+
+	for name, tc := range map[string]struct {
+		query    json.Marshaler
+		opts     []espoll.RequestOption
+		encoding string
+	}{
+		"large":          {query: largeQuery, encoding: "gzip"},
+		"small":          {query: smallQuery},
+		"small_enabled":  {query: smallQuery, opts: []espoll.RequestOption{espoll.WithCompression(true)}, encoding: "gzip"},
+		"large_disabled": {query: largeQuery, opts: []espoll.RequestOption{espoll.WithCompression(false)}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var result espoll.SearchResult
+			_, err := client.NewSearchRequest("traces-*").WithQuery(tc.query).Do(context.Background(), &result, tc.opts...)
+			require.NoError(t, err)
+
+			expected, err := json.Marshal(map[string]any{"query": tc.query, "fields": []string{"*"}})
+			require.NoError(t, err)
+			assert.Equal(t, tc.encoding, contentEncoding)
+			assert.JSONEq(t, string(expected), body)
+		})
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,149 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package espoll
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// NewRequestDumper returns an http.RoundTripper which writes each request
+// to w before sending it with rt, or http.DefaultTransport if rt is nil.
+// The method, full URL, headers, and body are written, for debugging;
+// the values of Authorization headers are redacted.
+func NewRequestDumper(rt http.RoundTripper, w io.Writer) http.RoundTripper {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	return &requestDumper{rt: rt, w: w}
+}
+
+// requestDumper is an http.RoundTripper which writes each request to w,
+// or if w is nil, only requests marked by markRequestDump.
+type requestDumper struct {
+	rt http.RoundTripper
+	w  io.Writer
+	mu sync.Mutex // serializes writes to w
+}
+
+func (d *requestDumper) RoundTrip(req *http.Request) (*http.Response, error) {
+	w := d.w
+	if w == nil {
+		mark, _ := req.Context().Value(requestDumpKey{}).(*requestDumpMark)
+		if mark == nil || !mark.take() {
+			return d.rt.RoundTrip(req)
+		}
+		w = mark.w
+	}
+	d.mu.Lock()
+	req, err := dumpRequest(w, req)
+	d.mu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("error dumping request: %w", err)
+	}
+	return d.rt.RoundTrip(req)
+}
+
+type requestDumpKey struct{}
+
+// requestDumpMark marks a request to be written to w
+// by the HTTP transport of a client created by NewClient.
+type requestDumpMark struct {
+	w    io.Writer
+	once sync.Once
+}
+
+// take reports whether the request should be dumped, which
+// is true only the first time, so that retries are not dumped.
+func (m *requestDumpMark) take() (ok bool) {
+	m.once.Do(func() { ok = true })
+	return ok
+}
+
+// markRequestDump returns req with a context marking it
+// to be written to w by the client's HTTP transport.
+func markRequestDump(req *http.Request, w io.Writer) *http.Request {
+	ctx := context.WithValue(req.Context(), requestDumpKey{}, &requestDumpMark{w: w})
+	return req.WithContext(ctx)
+}
+
+// dumpRequest writes the method, URL, headers, and body of req to w,
+// redacting the values of any Authorization headers. Gzip-encoded
+// bodies are written decoded. The returned request must be sent in
+// place of req, as its body will have been consumed.
+func dumpRequest(w io.Writer, req *http.Request) (*http.Request, error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		if req.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(bytes.NewReader(body))
+			if err != nil {
+				return nil, err
+			}
+			if body, err = io.ReadAll(zr); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Requests passed to the Elasticsearch client have no host
+	// until it selects a node, so only the path can be written.
+	url := req.URL.String()
+	if req.URL.Host == "" {
+		url = req.URL.RequestURI()
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s %s\n", req.Method, url)
+	keys := make([]string, 0, len(req.Header))
+	for key := range req.Header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range req.Header[key] {
+			if http.CanonicalHeaderKey(key) == "Authorization" {
+				value = "[REDACTED]"
+			}
+			fmt.Fprintf(&buf, "%s: %s\n", key, value)
+		}
+	}
+	buf.WriteByte('\n')
+	if len(body) > 0 {
+		buf.Write(body)
+		if body[len(body)-1] != '\n' {
+			buf.WriteByte('\n')
+		}
+	}
+	if _, err := buf.WriteTo(w); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
@@ -0,0 +1,106 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package espoll
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/elastic/go-elasticsearch/v8/esutil"
+)
+
+// EQLSearch searches index with the given EQL query, returning the results.
+//
+// By default a single request is made. To poll until the results satisfy
+// some condition, specify WithCondition; e.g. with EQLMinSequencesCondition.
+func (es *Client) EQLSearch(
+	ctx context.Context,
+	index string,
+	query string,
+	opts ...RequestOption,
+) (EQLResult, error) {
+	var body struct {
+		Query  string   `json:"query"`
+		Fields []string `json:"fields"`
+	}
+	body.Query = query
+	body.Fields = []string{"*"}
+	req := esapi.EqlSearchRequest{
+		Index: strings.Join(splitTargets(index), ","),
+		Body:  esutil.NewJSONReader(&body),
+	}
+	var result EQLResult
+	if _, err := es.Do(ctx, req, &result, opts...); err != nil {
+		return result, fmt.Errorf("failed issuing request: %w", err)
+	}
+	return result, nil
+}
+
+// EQLResult holds the results of an EQL search.
+type EQLResult struct {
+	Hits EQLHits `json:"hits"`
+}
+
+// EQLHits holds the hits of an EQL search. Depending on the query,
+// either Events or Sequences will be populated.
+type EQLHits struct {
+	Total     SearchHitsTotal `json:"total"`
+	Events    []SearchHit     `json:"events"`
+	Sequences []EQLSequence   `json:"sequences"`
+}
+
+// EQLSequence holds a sequence of events matching an EQL sequence query.
+type EQLSequence struct {
+	JoinKeys []any       `json:"join_keys"`
+	Events   []SearchHit `json:"events"`
+}
+
+// EQLMinEventsCondition returns a ConditionFunc which will return true if
+// the EQL search response contains at least min events.
+func EQLMinEventsCondition(min int) ConditionFunc {
+	return func(resp *esapi.Response) bool {
+		var result struct {
+			Hits struct {
+				Events []json.RawMessage `json:"events"`
+			} `json:"hits"`
+		}
+		if err := decodeResponseBody(resp, &result); err != nil {
+			return false
+		}
+		return len(result.Hits.Events) >= min
+	}
+}
+
+// EQLMinSequencesCondition returns a ConditionFunc which will return true
+// if the EQL search response contains at least min sequences.
+func EQLMinSequencesCondition(min int) ConditionFunc {
+	return func(resp *esapi.Response) bool {
+		var result struct {
+			Hits struct {
+				Sequences []json.RawMessage `json:"sequences"`
+			} `json:"hits"`
+		}
+		if err := decodeResponseBody(resp, &result); err != nil {
+			return false
+		}
+		return len(result.Hits.Sequences) >= min
+	}
+}
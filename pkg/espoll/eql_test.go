@@ -0,0 +1,99 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package espoll_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/apm-tools/pkg/espoll"
+)
+
+func TestEQLSearchEvents(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/logs-*/_eql/search", r.URL.Path)
+		var body struct {
+			Query string `json:"query"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, `any where error.id != null`, body.Query)
+		w.Write([]byte(`{
+		  "is_partial": false,
+		  "is_running": false,
+		  "timed_out": false,
+		  "hits": {
+		    "total": {"value": 2, "relation": "eq"},
+		    "events": [
+		      {"_index": "logs-1", "_id": "a", "_source": {"error": {"id": "1"}}, "fields": {"error.id": ["1"]}},
+		      {"_index": "logs-1", "_id": "b", "_source": {"error": {"id": "2"}}, "fields": {"error.id": ["2"]}}
+		    ]
+		  }
+		}`))
+	})
+
+	result, err := client.EQLSearch(context.Background(), "logs-*", `any where error.id != null`)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Hits.Total.Value)
+	require.Len(t, result.Hits.Events, 2)
+	assert.Equal(t, "a", result.Hits.Events[0].ID)
+	assert.Equal(t, []any{"2"}, result.Hits.Events[1].Fields["error.id"])
+	assert.Empty(t, result.Hits.Sequences)
+}
+
+func TestEQLSearchSequences(t *testing.T) {
+	var requests int
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.Write([]byte(`{"hits": {"total": {"value": 0, "relation": "eq"}, "sequences": []}}`))
+			return
+		}
+		w.Write([]byte(`{
+		  "hits": {
+		    "total": {"value": 1, "relation": "eq"},
+		    "sequences": [{
+		      "join_keys": ["abc"],
+		      "events": [
+		        {"_index": "traces-1", "_id": "tx", "_source": {}, "fields": {"trace.id": ["abc"]}},
+		        {"_index": "logs-1", "_id": "err", "_source": {}, "fields": {"trace.id": ["abc"]}}
+		      ]
+		    }]
+		  }
+		}`))
+	})
+
+	result, err := client.EQLSearch(context.Background(), "traces-*,logs-*",
+		`sequence by trace.id [transaction where true] [error where true]`,
+		espoll.WithCondition(espoll.EQLMinSequencesCondition(1)),
+		espoll.WithPollInterval(time.Millisecond),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, 2, requests)
+	require.Len(t, result.Hits.Sequences, 1)
+	sequence := result.Hits.Sequences[0]
+	assert.Equal(t, []any{"abc"}, sequence.JoinKeys)
+	require.Len(t, sequence.Events, 2)
+	assert.Equal(t, "tx", sequence.Events[0].ID)
+	assert.Equal(t, "err", sequence.Events[1].ID)
+}
@@ -0,0 +1,87 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package espoll
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/elastic/go-elasticsearch/v8/esutil"
+)
+
+// ESQLQuery issues the given ES|QL query, returning the results.
+//
+// By default a single request is made. To poll until the results satisfy
+// some condition, specify WithCondition; e.g. with ESQLMinRowsCondition.
+func (es *Client) ESQLQuery(
+	ctx context.Context,
+	query string,
+	opts ...RequestOption,
+) (ESQLResult, error) {
+	var body struct {
+		Query string `json:"query"`
+	}
+	body.Query = query
+	req := esapi.EsqlQueryRequest{
+		Body: esutil.NewJSONReader(&body),
+	}
+	var result ESQLResult
+	if _, err := es.Do(ctx, req, &result, opts...); err != nil {
+		return result, fmt.Errorf("failed issuing request: %w", err)
+	}
+	return result, nil
+}
+
+// ESQLResult holds the columnar results of an ES|QL query.
+type ESQLResult struct {
+	Columns []Column `json:"columns"`
+	Rows    [][]any  `json:"values"`
+}
+
+// Column describes a column of an ES|QL query result.
+type Column struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Row returns the i'th row of the result, keyed by column name.
+func (r ESQLResult) Row(i int) map[string]any {
+	row := make(map[string]any, len(r.Columns))
+	for j, column := range r.Columns {
+		if j < len(r.Rows[i]) {
+			row[column.Name] = r.Rows[i][j]
+		}
+	}
+	return row
+}
+
+// ESQLMinRowsCondition returns a ConditionFunc which will return true if
+// the ES|QL query response contains at least min rows.
+func ESQLMinRowsCondition(min int) ConditionFunc {
+	return func(resp *esapi.Response) bool {
+		var result struct {
+			Values []json.RawMessage `json:"values"`
+		}
+		if err := decodeResponseBody(resp, &result); err != nil {
+			return false
+		}
+		return len(result.Values) >= min
+	}
+}
@@ -0,0 +1,72 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package espoll_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/apm-tools/pkg/espoll"
+)
+
+func TestESQLQuery(t *testing.T) {
+	const query = `FROM traces-* | STATS count = COUNT(*) BY service.name | SORT service.name`
+	var requests int
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, "/_query", r.URL.Path)
+		var body struct {
+			Query string `json:"query"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, query, body.Query)
+		if requests == 1 {
+			w.Write([]byte(`{"columns": [{"name": "count", "type": "long"}, {"name": "service.name", "type": "keyword"}], "values": []}`))
+			return
+		}
+		w.Write([]byte(`{
+		  "columns": [
+		    {"name": "count", "type": "long"},
+		    {"name": "service.name", "type": "keyword"}
+		  ],
+		  "values": [
+		    [3, "backend"],
+		    [5, "frontend"]
+		  ]
+		}`))
+	})
+
+	result, err := client.ESQLQuery(context.Background(), query,
+		espoll.WithCondition(espoll.ESQLMinRowsCondition(2)),
+		espoll.WithPollInterval(time.Millisecond),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, 2, requests)
+	assert.Equal(t, []espoll.Column{
+		{Name: "count", Type: "long"},
+		{Name: "service.name", Type: "keyword"},
+	}, result.Columns)
+	assert.Equal(t, [][]any{{3.0, "backend"}, {5.0, "frontend"}}, result.Rows)
+	assert.Equal(t, map[string]any{"count": 5.0, "service.name": "frontend"}, result.Row(1))
+}
@@ -0,0 +1,126 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package espoll
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// APM event types, as returned by SearchHit.EventType.
+const (
+	EventTypeError       = "error"
+	EventTypeLog         = "log"
+	EventTypeMetric      = "metric"
+	EventTypeSpan        = "span"
+	EventTypeTransaction = "transaction"
+)
+
+// FieldsEventType returns the APM event type of a document, given its
+// fields as returned by the search fields API, or "" if it cannot be
+// determined.
+//
+// The event type is derived from data_stream.type and, if needed,
+// data_stream.dataset and the presence of span.type or transaction.type.
+// Unlike SearchHit.EventType, processor.event is not considered.
+func FieldsEventType(fields json.RawMessage) string {
+	return dataStreamEventType(fieldsGetter(fields))
+}
+
+// EventType returns the APM event type of the hit, or "" if it cannot
+// be determined. The event type is derived as for FieldsEventType, and
+// if the hit has no data_stream.type, from processor.event.
+//
+// The hit's fields are used if they were requested, and otherwise its
+// _source, in which fields may be nested objects or dotted keys.
+func (h *SearchHit) EventType() string {
+	if !isNullJSON(h.RawFields) {
+		if t := eventType(fieldsGetter(h.RawFields)); t != "" {
+			return t
+		}
+	}
+	if isNullJSON(h.RawSource) {
+		return ""
+	}
+	return eventType(func(field string) gjson.Result {
+		if r := gjson.GetBytes(h.RawSource, field); r.Exists() {
+			return r
+		}
+		return gjson.GetBytes(h.RawSource, escapeField(field))
+	})
+}
+
+// DecodeAs unmarshals the hit's _source into out, like UnmarshalSource,
+// after checking that the hit has the given event type.
+func (h *SearchHit) DecodeAs(eventType string, out any) error {
+	if t := h.EventType(); t != eventType {
+		return fmt.Errorf("hit %q has event type %q, expected %q", h.ID, t, eventType)
+	}
+	return h.UnmarshalSource(out)
+}
+
+// eventType derives the event type using get to look up fields,
+// falling back to processor.event for documents without data streams.
+func eventType(get func(field string) gjson.Result) string {
+	if t := dataStreamEventType(get); t != "" {
+		return t
+	}
+	switch event := get("processor.event").Str; event {
+	case EventTypeError, EventTypeLog, EventTypeMetric, EventTypeSpan, EventTypeTransaction:
+		return event
+	}
+	return ""
+}
+
+// dataStreamEventType derives the event type from data stream
+// fields, using get to look up fields.
+func dataStreamEventType(get func(field string) gjson.Result) string {
+	switch get("data_stream.type").Str {
+	case "logs":
+		if get("data_stream.dataset").Str == "apm.error" {
+			return EventTypeError
+		}
+		return EventTypeLog
+	case "metrics":
+		return EventTypeMetric
+	case "traces":
+		if get("span.type").Exists() {
+			return EventTypeSpan
+		}
+		if get("transaction.type").Exists() {
+			return EventTypeTransaction
+		}
+	}
+	return ""
+}
+
+// fieldsGetter returns a function for looking up
+// the first value of fields in the search fields API.
+func fieldsGetter(fields json.RawMessage) func(field string) gjson.Result {
+	return func(field string) gjson.Result {
+		return gjson.GetBytes(fields, escapeField(field)+".0")
+	}
+}
+
+// escapeField escapes the dots in a field name for use as a gjson path.
+func escapeField(field string) string {
+	return strings.ReplaceAll(field, ".", `\.`)
+}
@@ -0,0 +1,112 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package espoll_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/apm-tools/pkg/espoll"
+)
+
+func TestSearchHitEventType(t *testing.T) {
+	for name, tc := range map[string]struct {
+		hit      string
+		expected string
+	}{
+		"transaction_fields": {
+			hit:      `{"_id":"1","_source":{},"fields":{"data_stream.type":["traces"],"transaction.type":["request"]}}`,
+			expected: espoll.EventTypeTransaction,
+		},
+		"span_fields": {
+			hit:      `{"_id":"1","_source":{},"fields":{"data_stream.type":["traces"],"span.type":["db"]}}`,
+			expected: espoll.EventTypeSpan,
+		},
+		"error_source": {
+			hit:      `{"_id":"1","_source":{"data_stream":{"type":"logs","dataset":"apm.error"}}}`,
+			expected: espoll.EventTypeError,
+		},
+		"log_source_dotted": {
+			hit:      `{"_id":"1","_source":{"data_stream.type":"logs","data_stream.dataset":"apm.app.svc"}}`,
+			expected: espoll.EventTypeLog,
+		},
+		"span_processor_event_fields": {
+			hit:      `{"_id":"1","fields":{"processor.event":["span"]}}`,
+			expected: espoll.EventTypeSpan,
+		},
+		"metric_processor_event": {
+			hit:      `{"_id":"1","_source":{"processor":{"event":"metric"}}}`,
+			expected: espoll.EventTypeMetric,
+		},
+		"unknown": {
+			hit:      `{"_id":"1","_source":{"message":"hello"}}`,
+			expected: "",
+		},
+		"no_source": {
+			hit:      `{"_id":"1"}`,
+			expected: "",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var hit espoll.SearchHit
+			require.NoError(t, json.Unmarshal([]byte(tc.hit), &hit))
+			assert.Equal(t, tc.expected, hit.EventType())
+		})
+	}
+}
+
+func TestSearchHitDecodeAs(t *testing.T) {
+	var result espoll.SearchResult
+	require.NoError(t, json.Unmarshal([]byte(`{"hits":{"hits":[
+	  {"_id":"tx","_source":{"data_stream":{"type":"traces"},"transaction":{"type":"request","name":"GET /"}}},
+	  {"_id":"err","_source":{"data_stream":{"type":"logs","dataset":"apm.error"},"error":{"id":"abc"}}}
+	]}}`), &result))
+	require.Len(t, result.Hits.Hits, 2)
+	txHit, errHit := result.Hits.Hits[0], result.Hits.Hits[1]
+
+	var tx struct {
+		Transaction struct {
+			Name string `json:"name"`
+		} `json:"transaction"`
+	}
+	require.NoError(t, txHit.DecodeAs(espoll.EventTypeTransaction, &tx))
+	assert.Equal(t, "GET /", tx.Transaction.Name)
+
+	var e struct {
+		Error struct {
+			ID string `json:"id"`
+		} `json:"error"`
+	}
+	require.NoError(t, errHit.DecodeAs(espoll.EventTypeError, &e))
+	assert.Equal(t, "abc", e.Error.ID)
+
+	err := errHit.DecodeAs(espoll.EventTypeTransaction, &tx)
+	assert.EqualError(t, err, `hit "err" has event type "error", expected "transaction"`)
+}
+
+func TestFieldsEventType(t *testing.T) {
+	assert.Equal(t, espoll.EventTypeMetric, espoll.FieldsEventType(json.RawMessage(`{"data_stream.type":["metrics"]}`)))
+	assert.Equal(t, espoll.EventTypeError, espoll.FieldsEventType(json.RawMessage(
+		`{"data_stream.type":["logs"],"data_stream.dataset":["apm.error"]}`,
+	)))
+	assert.Equal(t, "", espoll.FieldsEventType(json.RawMessage(`{"data_stream.type":["traces"]}`)))
+	assert.Equal(t, "", espoll.FieldsEventType(json.RawMessage(`{"processor.event":["span"]}`)))
+}
@@ -0,0 +1,120 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package espoll
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// MultiSearch issues the given search requests in a single _msearch
+// request, returning the results in the same order as reqs.
+//
+// If an individual search fails, its SearchResult.Error field will be
+// set rather than MultiSearch returning an error.
+func (es *Client) MultiSearch(
+	ctx context.Context,
+	reqs []*SearchRequest,
+	opts ...RequestOption,
+) ([]SearchResult, error) {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, req := range reqs {
+		if err := enc.Encode(req.msearchHeader()); err != nil {
+			return nil, fmt.Errorf("error encoding msearch header: %w", err)
+		}
+		if err := enc.Encode(req.msearchBody()); err != nil {
+			return nil, fmt.Errorf("error encoding msearch body: %w", err)
+		}
+	}
+
+	var result struct {
+		Responses []json.RawMessage `json:"responses"`
+	}
+	msearchReq := esapi.MsearchRequest{Body: &body}
+	if _, err := es.Do(ctx, msearchReq, &result, opts...); err != nil {
+		return nil, fmt.Errorf("failed issuing request: %w", err)
+	}
+	if n := len(result.Responses); n != len(reqs) {
+		return nil, fmt.Errorf("expected %d responses, got %d", len(reqs), n)
+	}
+
+	results := make([]SearchResult, len(reqs))
+	for i, raw := range result.Responses {
+		var response struct {
+			Status int `json:"status"`
+			Error  *struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(raw, &response); err != nil {
+			return nil, fmt.Errorf("error decoding response %d: %w", i, err)
+		}
+		if response.Error != nil {
+			results[i].Error = &ESError{
+				StatusCode: response.Status,
+				Type:       response.Error.Type,
+				Reason:     response.Error.Reason,
+				Message:    string(raw),
+			}
+			continue
+		}
+		if err := json.Unmarshal(raw, &results[i]); err != nil {
+			return nil, fmt.Errorf("error decoding response %d: %w", i, err)
+		}
+	}
+	return results, nil
+}
+
+func (r *SearchRequest) msearchHeader() map[string]any {
+	header := make(map[string]any)
+	if len(r.Index) > 0 {
+		header["index"] = r.Index
+	}
+	if r.ExpandWildcards != "" {
+		header["expand_wildcards"] = r.ExpandWildcards
+	}
+	return header
+}
+
+func (r *SearchRequest) msearchBody() any {
+	// Size and sort are URL parameters for searches,
+	// but must be specified in the body for msearch.
+	type sortOrder struct {
+		Order string `json:"order,omitempty"`
+	}
+	type msearchBody struct {
+		searchRequestBody
+		Size *int                   `json:"size,omitempty"`
+		Sort []map[string]sortOrder `json:"sort,omitempty"`
+	}
+	body := msearchBody{searchRequestBody: r.body, Size: r.Size}
+	for _, fieldDirection := range r.Sort {
+		field, direction, _ := strings.Cut(fieldDirection, ":")
+		body.Sort = append(body.Sort, map[string]sortOrder{
+			field: {Order: direction},
+		})
+	}
+	return body
+}
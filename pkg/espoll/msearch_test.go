@@ -0,0 +1,94 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package espoll_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/apm-tools/pkg/espoll"
+)
+
+func TestMultiSearch(t *testing.T) {
+	var lines []map[string]any
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/_msearch", r.URL.Path)
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			var line map[string]any
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+			lines = append(lines, line)
+		}
+		w.Write([]byte(`{
+		  "responses": [
+		    {
+		      "status": 200,
+		      "hits": {
+		        "total": {"value": 1, "relation": "eq"},
+		        "hits": [{"_index": "traces-1", "_id": "tx", "_source": {}, "fields": {"transaction.id": ["abc"]}}]
+		      }
+		    },
+		    {
+		      "status": 400,
+		      "error": {"type": "parsing_exception", "reason": "unknown query [foo]"}
+		    }
+		  ]
+		}`))
+	})
+
+	results, err := client.MultiSearch(context.Background(), []*espoll.SearchRequest{
+		client.NewSearchRequest("traces-*").
+			WithQuery(espoll.TermQuery{Field: "transaction.id", Value: "abc"}).
+			WithSort("@timestamp:desc").
+			WithSize(5),
+		client.NewSearchRequest("logs-*").WithQuery(map[string]any{"foo": map[string]any{}}),
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	assert.Equal(t, []map[string]any{
+		{"index": []any{"traces-*"}},
+		{
+			"query":  map[string]any{"term": map[string]any{"transaction.id": map[string]any{"value": "abc"}}},
+			"fields": []any{"*"},
+			"size":   5.0,
+			"sort":   []any{map[string]any{"@timestamp": map[string]any{"order": "desc"}}},
+		},
+		{"index": []any{"logs-*"}},
+		{
+			"query":  map[string]any{"foo": map[string]any{}},
+			"fields": []any{"*"},
+		},
+	}, lines)
+
+	assert.Nil(t, results[0].Error)
+	require.Len(t, results[0].Hits.Hits, 1)
+	assert.Equal(t, "tx", results[0].Hits.Hits[0].ID)
+
+	require.NotNil(t, results[1].Error)
+	assert.Equal(t, http.StatusBadRequest, results[1].Error.StatusCode)
+	assert.Equal(t, "parsing_exception", results[1].Error.Type)
+	assert.Equal(t, "unknown query [foo]", results[1].Error.Reason)
+	assert.Empty(t, results[1].Hits.Hits)
+}
@@ -0,0 +1,141 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package espoll
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/elastic/go-elasticsearch/v8/esutil"
+)
+
+// OpenPointInTime opens a point in time (PIT) for index, returning its ID.
+//
+// The PIT will be kept alive for at least keepAlive, and should be closed
+// with ClosePointInTime when it is no longer needed.
+func (es *Client) OpenPointInTime(ctx context.Context, index string, keepAlive time.Duration) (string, error) {
+	return es.openPointInTime(ctx, index, keepAlive, "open,hidden")
+}
+
+// openPointInTime opens a point in time (PIT) for index, expanding
+// wildcard targets to the given comma-separated index types.
+func (es *Client) openPointInTime(ctx context.Context, index string, keepAlive time.Duration, expandWildcards string) (string, error) {
+	req := esapi.OpenPointInTimeRequest{
+		Index:           splitTargets(index),
+		KeepAlive:       formatKeepAlive(keepAlive),
+		ExpandWildcards: expandWildcards,
+	}
+	var result struct {
+		ID string `json:"id"`
+	}
+	if _, err := es.Do(ctx, req, &result); err != nil {
+		return "", fmt.Errorf("failed opening point in time for %s: %w", index, err)
+	}
+	return result.ID, nil
+}
+
+// ClosePointInTime closes the point in time (PIT) with the given ID.
+func (es *Client) ClosePointInTime(ctx context.Context, id string) error {
+	req := esapi.ClosePointInTimeRequest{
+		Body: esutil.NewJSONReader(map[string]string{"id": id}),
+	}
+	if _, err := es.Do(ctx, req, nil); err != nil {
+		return fmt.Errorf("failed closing point in time: %w", err)
+	}
+	return nil
+}
+
+// WithPointInTime sets the point in time (PIT) for the search request.
+//
+// Searches against a PIT must not specify a target, so the request's
+// Index and ExpandWildcards fields are cleared.
+func (r *SearchRequest) WithPointInTime(id string) *SearchRequest {
+	r.Index = nil
+	r.ExpandWildcards = ""
+	if r.body.PIT == nil {
+		r.body.PIT = &pointInTime{}
+	}
+	r.body.PIT.ID = id
+	r.bodySet = true
+	return r
+}
+
+// WithPointInTimeKeepAlive extends the lifetime of the search request's
+// point in time (PIT) by keepAlive. This must be used with WithPointInTime.
+func (r *SearchRequest) WithPointInTimeKeepAlive(keepAlive time.Duration) *SearchRequest {
+	if r.body.PIT == nil {
+		r.body.PIT = &pointInTime{}
+	}
+	r.body.PIT.KeepAlive = formatKeepAlive(keepAlive)
+	r.bodySet = true
+	return r
+}
+
+// pitSearchRequest is a Request which opens a new point in time (PIT)
+// for each search attempt, closing the PIT of the previous attempt.
+// Searching a single PIT repeatedly would never observe new documents.
+type pitSearchRequest struct {
+	req       *SearchRequest
+	index     string
+	keepAlive time.Duration
+	pitID     string
+
+	// expandWildcards is used when opening each PIT, as the
+	// search request's ExpandWildcards is cleared.
+	expandWildcards string
+
+	// transport is used for searches, adding any request headers.
+	transport esapi.Transport
+}
+
+func (p *pitSearchRequest) Do(ctx context.Context, _ esapi.Transport) (*esapi.Response, error) {
+	p.close()
+	pitID, err := p.req.es.openPointInTime(ctx, p.index, p.keepAlive, p.expandWildcards)
+	if err != nil {
+		return nil, err
+	}
+	p.pitID = pitID
+	p.req.WithPointInTime(pitID).WithPointInTimeKeepAlive(p.keepAlive)
+	// The body differs for each attempt, so bypass the transport
+	// passed in by Client.Do, which may replay the first body.
+	p.req.Body = esutil.NewJSONReader(&p.req.body)
+	return p.req.SearchRequest.Do(ctx, p.transport)
+}
+
+// close closes the most recently opened PIT, if any. A new context is
+// used, as the search context may have been cancelled or timed out.
+func (p *pitSearchRequest) close() {
+	if p.pitID == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	p.req.es.ClosePointInTime(ctx, p.pitID)
+	p.pitID = ""
+}
+
+type pointInTime struct {
+	ID        string `json:"id"`
+	KeepAlive string `json:"keep_alive,omitempty"`
+}
+
+func formatKeepAlive(d time.Duration) string {
+	return fmt.Sprintf("%dms", d.Milliseconds())
+}
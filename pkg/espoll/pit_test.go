@@ -0,0 +1,118 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package espoll_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/apm-tools/pkg/espoll"
+)
+
+func TestSearchIndexMinDocsPointInTime(t *testing.T) {
+	var searches, opened int
+	var closed []string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/_refresh"):
+			w.Write([]byte(`{}`))
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/_pit"):
+			assert.Equal(t, "/traces-*/_pit", r.URL.Path)
+			assert.Equal(t, "60000ms", r.URL.Query().Get("keep_alive"))
+			opened++
+			fmt.Fprintf(w, `{"id":"pit-%d"}`, opened)
+		case r.Method == http.MethodDelete && r.URL.Path == "/_pit":
+			var body struct {
+				ID string `json:"id"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			closed = append(closed, body.ID)
+			w.Write([]byte(`{"succeeded":true,"num_freed":1}`))
+		case r.URL.Path == "/_search":
+			searches++
+			var body struct {
+				PIT struct {
+					ID        string `json:"id"`
+					KeepAlive string `json:"keep_alive"`
+				} `json:"pit"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			// Each attempt must search a newly opened PIT,
+			// so that newly indexed documents are visible.
+			assert.Equal(t, fmt.Sprintf("pit-%d", opened), body.PIT.ID)
+			assert.Equal(t, "60000ms", body.PIT.KeepAlive)
+			if searches < 3 {
+				w.Write([]byte(`{"hits":{"total":{"value":0,"relation":"eq"},"hits":[]}}`))
+				return
+			}
+			w.Write([]byte(`{"hits":{"total":{"value":1,"relation":"eq"},"hits":[{"_id":"1","_source":{},"fields":{}}]}}`))
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+	})
+
+	result, err := client.SearchIndexMinDocs(context.Background(), 1, "traces-*", nil,
+		espoll.WithPITKeepAlive(time.Minute),
+		espoll.WithPollInterval(time.Millisecond),
+	)
+	require.NoError(t, err)
+	assert.Len(t, result.Hits.Hits, 1)
+	assert.Equal(t, 3, searches)
+	assert.Equal(t, 3, opened)
+	assert.Equal(t, []string{"pit-1", "pit-2", "pit-3"}, closed)
+}
+
+func TestSearchIndexMinDocsPointInTimeClosedOnTimeout(t *testing.T) {
+	var closed []string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/_refresh"):
+			w.Write([]byte(`{}`))
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/_pit"):
+			w.Write([]byte(`{"id":"pit-id"}`))
+		case r.Method == http.MethodDelete && r.URL.Path == "/_pit":
+			var body struct {
+				ID string `json:"id"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			closed = append(closed, body.ID)
+			w.Write([]byte(`{"succeeded":true,"num_freed":1}`))
+		case r.URL.Path == "/_search":
+			w.Write([]byte(`{"hits":{"total":{"value":0,"relation":"eq"},"hits":[]}}`))
+		}
+	})
+
+	_, err := client.SearchIndexMinDocs(context.Background(), 1, "traces-*", nil,
+		espoll.WithPITKeepAlive(time.Minute),
+		espoll.WithPollInterval(time.Millisecond),
+		espoll.WithTimeout(20*time.Millisecond),
+	)
+	require.Error(t, err)
+	assert.NotEmpty(t, closed)
+	for _, id := range closed {
+		assert.Equal(t, "pit-id", id)
+	}
+}
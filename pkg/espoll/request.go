@@ -19,6 +19,8 @@ package espoll
 
 import (
 	"bytes"
+	"compress/gzip"
+	"fmt"
 	"io"
 	"net/http"
 
@@ -66,6 +68,85 @@ func (br *bodyRepeater) Perform(req *http.Request) (*http.Response, error) {
 	return br.t.Perform(req)
 }
 
+// headerSetter wraps an esapi.Transport, setting headers on each request.
+type headerSetter struct {
+	t      esapi.Transport
+	header http.Header
+}
+
+func (hs *headerSetter) Perform(req *http.Request) (*http.Response, error) {
+	for key, values := range hs.header {
+		req.Header[key] = append([]string(nil), values...)
+	}
+	return hs.t.Perform(req)
+}
+
+// compressionThreshold is the minimum request body size, in bytes,
+// which is compressed unless compression is explicitly enabled.
+const compressionThreshold = 1024
+
+// bodyCompressor wraps an esapi.Transport, gzip-encoding request bodies
+// of at least compressionThreshold bytes, or all bodies if always is true.
+type bodyCompressor struct {
+	t      esapi.Transport
+	always bool
+}
+
+func (bc *bodyCompressor) Perform(req *http.Request) (*http.Response, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return bc.t.Perform(req)
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if bc.always || len(body) >= compressionThreshold {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(body); err != nil {
+			return nil, fmt.Errorf("error compressing request body: %w", err)
+		}
+		if err := zw.Close(); err != nil {
+			return nil, fmt.Errorf("error compressing request body: %w", err)
+		}
+		body = buf.Bytes()
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	req.Body, _ = req.GetBody()
+	req.ContentLength = int64(len(body))
+	return bc.t.Perform(req)
+}
+
+// requestDumpMarker wraps an esapi.Transport, writing the first request
+// performed to w. If httpDump is true, the request is marked for the
+// client's HTTP transport to write, with the full URL and headers added
+// by the Elasticsearch client; otherwise the request is written as is.
+type requestDumpMarker struct {
+	t        esapi.Transport
+	w        io.Writer
+	httpDump bool
+	dumped   bool
+}
+
+func (rd *requestDumpMarker) Perform(req *http.Request) (*http.Response, error) {
+	if rd.dumped {
+		return rd.t.Perform(req)
+	}
+	rd.dumped = true
+	if rd.httpDump {
+		return rd.t.Perform(markRequestDump(req, rd.w))
+	}
+	req, err := dumpRequest(rd.w, req)
+	if err != nil {
+		return nil, fmt.Errorf("error dumping request: %w", err)
+	}
+	return rd.t.Perform(req)
+}
+
 type readCloser struct {
 	io.Reader
 	io.Closer
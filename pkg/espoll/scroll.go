@@ -0,0 +1,120 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package espoll
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/elastic/go-elasticsearch/v8/esutil"
+)
+
+// ScrollAll searches index with query using the scroll API, returning
+// all matching hits. The scroll context is kept alive for keepAlive
+// between pages, and is cleared when ScrollAll returns.
+//
+// The number of hits requested per page may be set with WithPageSize,
+// and the sort order with WithSort; hits are otherwise returned in
+// index order. If a callback is set with WithPageFunc, each page of hits
+// is passed to it instead of being accumulated, and ScrollAll returns
+// no hits.
+//
+// Prefer SearchAll where possible; ScrollAll is for clusters where
+// scrolling is required, or for exporting very large result sets.
+func (es *Client) ScrollAll(
+	ctx context.Context,
+	index string,
+	query json.Marshaler,
+	keepAlive time.Duration,
+	opts ...RequestOption,
+) ([]SearchHit, error) {
+	options := newRequestOptions(opts)
+	if options.pageSize <= 0 {
+		return nil, fmt.Errorf("invalid page size %d", options.pageSize)
+	}
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+
+	req := es.NewSearchRequest(index).WithSize(options.pageSize)
+	req.ExpandWildcards = options.expandWildcardsOr("open,hidden")
+	req.Scroll = keepAlive
+	if len(options.sort) > 0 {
+		req = req.WithSort(options.sort...)
+	} else {
+		// Sorting by _doc is the most efficient order for scrolling.
+		req = req.WithSort("_doc")
+	}
+	if query != nil {
+		req = req.WithQuery(query)
+	}
+
+	// Decode the scroll ID along with the search result.
+	var hits []SearchHit
+	var page scrollResult
+	req.Body = esutil.NewJSONReader(&req.body)
+	if _, err := es.Do(ctx, &req.SearchRequest, &page, opts...); err != nil {
+		return nil, fmt.Errorf("failed issuing request: %w", err)
+	}
+	defer func() {
+		if page.ScrollID != "" {
+			es.clearScroll(page.ScrollID)
+		}
+	}()
+	for len(page.Hits.Hits) > 0 {
+		if options.pageFunc != nil {
+			if err := options.pageFunc(page.Hits.Hits); err != nil {
+				return nil, err
+			}
+		} else {
+			hits = append(hits, page.Hits.Hits...)
+		}
+		scrollReq := esapi.ScrollRequest{
+			Body: esutil.NewJSONReader(map[string]string{
+				"scroll":    formatKeepAlive(keepAlive),
+				"scroll_id": page.ScrollID,
+			}),
+		}
+		page.Hits.Hits = nil
+		if _, err := es.Do(ctx, scrollReq, &page, opts...); err != nil {
+			return hits, fmt.Errorf("failed scrolling: %w", err)
+		}
+	}
+	return hits, nil
+}
+
+// scrollResult holds a page of scroll search results.
+type scrollResult struct {
+	SearchResult
+	ScrollID string `json:"_scroll_id"`
+}
+
+// clearScroll clears the scroll context with the given ID. A new
+// context is used, as the search context may have been cancelled
+// or timed out.
+func (es *Client) clearScroll(scrollID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req := esapi.ClearScrollRequest{
+		Body: esutil.NewJSONReader(map[string][]string{"scroll_id": {scrollID}}),
+	}
+	es.Do(ctx, req, nil)
+}
@@ -0,0 +1,155 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package espoll_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/apm-tools/pkg/espoll"
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// newScrollClient returns a client whose transport serves a scroll over
+// pages, returning a new scroll ID with each page, and records requests.
+func newScrollClient(t testing.TB, pages [][]string, requests *[]string) *espoll.Client {
+	t.Helper()
+	var page int
+	transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		var body []byte
+		if req.Body != nil {
+			var err error
+			body, err = io.ReadAll(req.Body)
+			require.NoError(t, err)
+		}
+		*requests = append(*requests, fmt.Sprintf("%s %s %s", req.Method, req.URL.RequestURI(), body))
+
+		var respBody string
+		switch req.URL.Path {
+		case "/_search/scroll":
+			if req.Method == http.MethodDelete {
+				respBody = `{"succeeded":true,"num_freed":1}`
+				break
+			}
+			fallthrough
+		default:
+			var hits []string
+			if page < len(pages) {
+				for _, id := range pages[page] {
+					hits = append(hits, fmt.Sprintf(`{"_index":"logs","_id":%q,"_source":{},"fields":{}}`, id))
+				}
+			}
+			page++
+			respBody = fmt.Sprintf(`{"_scroll_id":"scroll-%d","hits":{"total":{"value":3,"relation":"eq"},"hits":[%s]}}`,
+				page, strings.Join(hits, ","),
+			)
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header: http.Header{
+				"X-Elastic-Product": []string{"Elasticsearch"},
+				"Content-Type":      []string{"application/json"},
+			},
+			Body: io.NopCloser(strings.NewReader(respBody)),
+		}, nil
+	})
+	es, err := elasticsearch.NewClient(elasticsearch.Config{Transport: transport})
+	require.NoError(t, err)
+	return espoll.WrapClient(es)
+}
+
+func TestScrollAll(t *testing.T) {
+	var requests []string
+	client := newScrollClient(t, [][]string{{"1", "2"}, {"3"}}, &requests)
+
+	hits, err := client.ScrollAll(context.Background(), "logs-*",
+		espoll.TermQuery{Field: "service.name", Value: "svc"}, time.Minute,
+		espoll.WithPageSize(2),
+	)
+	require.NoError(t, err)
+
+	var ids []string
+	for _, hit := range hits {
+		ids = append(ids, hit.ID)
+	}
+	assert.Equal(t, []string{"1", "2", "3"}, ids)
+	assert.Equal(t, []string{
+		`POST /logs-*/_search?expand_wildcards=open%2Chidden&scroll=60000ms&size=2&sort=_doc ` +
+			`{"query":{"term":{"service.name":{"value":"svc"}}},"fields":["*"]}` + "\n",
+		`POST /_search/scroll {"scroll":"60000ms","scroll_id":"scroll-1"}` + "\n",
+		`POST /_search/scroll {"scroll":"60000ms","scroll_id":"scroll-2"}` + "\n",
+		`DELETE /_search/scroll {"scroll_id":["scroll-3"]}` + "\n",
+	}, requests)
+}
+
+func TestScrollAllPageFunc(t *testing.T) {
+	var requests []string
+	client := newScrollClient(t, [][]string{{"1", "2"}, {"3"}}, &requests)
+
+	var pages [][]string
+	hits, err := client.ScrollAll(context.Background(), "logs-*", nil, time.Minute,
+		espoll.WithPageFunc(func(hits []espoll.SearchHit) error {
+			var ids []string
+			for _, hit := range hits {
+				ids = append(ids, hit.ID)
+			}
+			pages = append(pages, ids)
+			return nil
+		}),
+	)
+	require.NoError(t, err)
+	assert.Empty(t, hits)
+	assert.Equal(t, [][]string{{"1", "2"}, {"3"}}, pages)
+}
+
+func TestScrollAllClearsOnError(t *testing.T) {
+	var requests []string
+	client := newScrollClient(t, [][]string{{"1", "2"}, {"3"}}, &requests)
+
+	pageErr := errors.New("boom")
+	_, err := client.ScrollAll(context.Background(), "logs-*", nil, time.Minute,
+		espoll.WithPageFunc(func(hits []espoll.SearchHit) error { return pageErr }),
+	)
+	assert.ErrorIs(t, err, pageErr)
+	require.Len(t, requests, 2)
+	assert.Equal(t, `DELETE /_search/scroll {"scroll_id":["scroll-1"]}`+"\n", requests[1])
+}
+
+func TestScrollAllSort(t *testing.T) {
+	var requests []string
+	client := newScrollClient(t, nil, &requests)
+
+	hits, err := client.ScrollAll(context.Background(), "logs-*", nil, time.Second,
+		espoll.WithSort("@timestamp:asc"),
+	)
+	require.NoError(t, err)
+	assert.Empty(t, hits)
+	assert.Equal(t, []string{
+		`POST /logs-*/_search?expand_wildcards=open%2Chidden&scroll=1000ms&size=10&sort=%40timestamp%3Aasc {"fields":["*"]}` + "\n",
+		`DELETE /_search/scroll {"scroll_id":["scroll-1"]}` + "\n",
+	}, requests)
+}
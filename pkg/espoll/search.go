@@ -20,6 +20,7 @@ package espoll
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -29,17 +30,27 @@ import (
 
 // SearchIndexMinDocs searches index with query, returning the results.
 //
-// If the search returns fewer than min results within 10 seconds
-// (by default), SearchIndexMinDocs will return an error.
+// If the search returns fewer than min results within 1 minute (by
+// default, see WithTimeout), SearchIndexMinDocs will return an error.
+// The search is retried every 500ms (by default, see WithPollInterval).
+//
+// By default all hits up to the total number of matching documents are
+// returned. If WithExactTotalHits(false) is specified, the search is
+// terminated early after min documents are found in each shard.
 func (es *Client) SearchIndexMinDocs(
 	ctx context.Context,
 	min int, index string,
 	query json.Marshaler,
 	opts ...RequestOption,
 ) (SearchResult, error) {
+	options := newRequestOptions(opts)
+	if err := options.validate(); err != nil {
+		return SearchResult{}, err
+	}
+
 	var result SearchResult
 	req := es.NewSearchRequest(index)
-	req.ExpandWildcards = "open,hidden"
+	req.ExpandWildcards = options.expandWildcardsOr("open,hidden")
 	if min > 10 {
 		// Size defaults to 10. If the caller expects more than 10,
 		// return it in the search so we don't have to search again.
@@ -48,22 +59,91 @@ func (es *Client) SearchIndexMinDocs(
 	if query != nil {
 		req = req.WithQuery(query)
 	}
-	opts = append(opts, WithCondition(AllCondition(
-		result.Hits.MinHitsCondition(min),
-		result.Hits.TotalHitsCondition(req),
-	)))
+	if len(options.sort) > 0 {
+		req = req.WithSort(options.sort...)
+	}
+	req = req.WithPreference(options.preference).WithRouting(options.routing...)
+	if options.consistency == ReadConsistencyEventual {
+		req = req.WithRequestCache(true)
+	}
+	if options.exactTotalHits {
+		opts = append(opts, WithCondition(AllCondition(
+			result.Hits.MinHitsCondition(min),
+			result.Hits.TotalHitsCondition(req),
+		)))
+	} else {
+		// Stop each shard once it has found min documents. The total
+		// hits is then a lower bound, so TotalHitsCondition is not used.
+		req = req.WithTerminateAfter(min)
+		opts = append(opts, WithCondition(result.Hits.MinHitsCondition(min)))
+	}
 
 	// Refresh the indices before issuing the search request.
-	refreshReq := esapi.IndicesRefreshRequest{
-		Index:           strings.Split(",", index),
-		ExpandWildcards: "all",
+	if err := es.refresh(ctx, index, options); err != nil {
+		return result, err
 	}
-	rsp, err := refreshReq.Do(ctx, es.Transport)
-	if err != nil {
-		return result, fmt.Errorf("failed refreshing indices: %s: %w", index, err)
+
+	var searchReq Request = &req.SearchRequest
+	if options.pitKeepAlive > 0 {
+		pitReq := &pitSearchRequest{
+			req:       req,
+			index:     index,
+			keepAlive: options.pitKeepAlive,
+			transport: options.wrapTransport(es, es.httpDump),
+
+			expandWildcards: req.ExpandWildcards,
+		}
+		defer pitReq.close()
+		searchReq = pitReq
+	} else {
+		req.Body = esutil.NewJSONReader(&req.body)
 	}
 
-	rsp.Body.Close()
+	if _, err := es.Do(ctx, searchReq, &result, opts...); err != nil {
+		return result, fmt.Errorf("failed issuing request: %w", err)
+	}
+	return result, nil
+}
+
+// SearchUntilAgg repeatedly searches index with query and the given
+// aggregations, without returning any hits, until cond returns true for
+// the search result, returning the final result.
+//
+// If cond does not return true within 1 minute (by default),
+// SearchUntilAgg will return an error. Any condition specified with
+// WithCondition must also be satisfied.
+func (es *Client) SearchUntilAgg(
+	ctx context.Context,
+	index string,
+	query json.Marshaler,
+	aggs map[string]any,
+	cond func(SearchResult) bool,
+	opts ...RequestOption,
+) (SearchResult, error) {
+	options := newRequestOptions(opts)
+	if err := options.validate(); err != nil {
+		return SearchResult{}, err
+	}
+
+	var result SearchResult
+	req := es.NewSearchRequest(index).WithSize(0).WithAggregations(aggs)
+	req.ExpandWildcards = options.expandWildcardsOr("open,hidden")
+	if options.consistency == ReadConsistencyEventual {
+		req = req.WithRequestCache(true)
+	}
+	if query != nil {
+		req = req.WithQuery(query)
+	}
+	aggCond := func(*esapi.Response) bool { return cond(result) }
+	if options.cond != nil {
+		aggCond = AllCondition(options.cond, aggCond)
+	}
+	opts = append(opts, WithCondition(aggCond))
+
+	// Refresh the indices before issuing the search request.
+	if err := es.refresh(ctx, index, options); err != nil {
+		return result, err
+	}
 
 	if _, err := req.Do(ctx, &result, opts...); err != nil {
 		return result, fmt.Errorf("failed issuing request: %w", err)
@@ -71,29 +151,307 @@ func (es *Client) SearchIndexMinDocs(
 	return result, nil
 }
 
+// CountIndexMinDocs counts the documents in index matching query,
+// returning the final count.
+//
+// If the count is less than min within 1 minute (by default, see
+// WithTimeout), CountIndexMinDocs will return an error.
+func (es *Client) CountIndexMinDocs(
+	ctx context.Context,
+	min int, index string,
+	query json.Marshaler,
+	opts ...RequestOption,
+) (int, error) {
+	options := newRequestOptions(opts)
+	if err := options.validate(); err != nil {
+		return 0, err
+	}
+
+	var result struct {
+		Count int `json:"count"`
+	}
+	req := esapi.CountRequest{
+		Index:           splitTargets(index),
+		ExpandWildcards: options.expandWildcardsOr("open,hidden"),
+	}
+	if query != nil {
+		var body struct {
+			Query any `json:"query"`
+		}
+		body.Query = query
+		req.Body = esutil.NewJSONReader(&body)
+	}
+	opts = append(opts, WithCondition(func(*esapi.Response) bool {
+		return result.Count >= min
+	}))
+
+	// Refresh the indices before issuing the count request.
+	if err := es.refresh(ctx, index, options); err != nil {
+		return 0, err
+	}
+
+	if _, err := es.Do(ctx, req, &result, opts...); err != nil {
+		return result.Count, fmt.Errorf("failed issuing request: %w", err)
+	}
+	return result.Count, nil
+}
+
+// refresh refreshes the given comma-separated indices, or the
+// configured refresh target, unless refresh has been disabled or
+// eventual read consistency has been requested.
+func (es *Client) refresh(ctx context.Context, index string, options requestOptions) error {
+	if !options.refresh || options.consistency == ReadConsistencyEventual {
+		return nil
+	}
+	if options.refreshTarget != "" {
+		index = options.refreshTarget
+	}
+	refreshReq := esapi.IndicesRefreshRequest{
+		Index:           splitTargets(index),
+		ExpandWildcards: options.expandWildcardsOr("all"),
+	}
+	rsp, err := refreshReq.Do(ctx, options.wrapTransport(es.Transport, es.httpDump))
+	if err != nil {
+		return fmt.Errorf("failed refreshing indices: %s: %w", index, err)
+	}
+	defer rsp.Body.Close()
+	if rsp.IsError() {
+		return fmt.Errorf("failed refreshing indices: %s: %s", index, rsp.String())
+	}
+	return nil
+}
+
+// SearchAll searches index with query, paging through all matching
+// results using search_after and accumulating them into a single
+// SearchResult.
+//
+// SearchAll requires a stable sort order to be specified with WithSort.
+// The number of hits requested per page may be set with WithPageSize.
+func (es *Client) SearchAll(
+	ctx context.Context,
+	index string,
+	query json.Marshaler,
+	opts ...RequestOption,
+) (SearchResult, error) {
+	options := newRequestOptions(opts)
+	if len(options.sort) == 0 {
+		return SearchResult{}, errors.New("SearchAll requires a sort order")
+	}
+	if options.pageSize <= 0 {
+		return SearchResult{}, fmt.Errorf("invalid page size %d", options.pageSize)
+	}
+	if err := options.validate(); err != nil {
+		return SearchResult{}, err
+	}
+
+	var result SearchResult
+	var searchAfter []any
+	for {
+		req := es.NewSearchRequest(index)
+		req = req.WithSort(options.sort...).WithSize(options.pageSize)
+		req.ExpandWildcards = options.expandWildcards
+		req = req.WithPreference(options.preference).WithRouting(options.routing...)
+		if options.consistency == ReadConsistencyEventual {
+			req = req.WithRequestCache(true)
+		}
+		if query != nil {
+			req = req.WithQuery(query)
+		}
+		if searchAfter != nil {
+			req = req.WithSearchAfter(searchAfter...)
+		}
+		var page SearchResult
+		if _, err := req.Do(ctx, &page, opts...); err != nil {
+			return result, fmt.Errorf("failed issuing request: %w", err)
+		}
+		if searchAfter == nil {
+			result.Aggregations = page.Aggregations
+		}
+		result.Hits.Total = page.Hits.Total
+		result.Hits.Hits = append(result.Hits.Hits, page.Hits.Hits...)
+		if len(page.Hits.Hits) < options.pageSize {
+			break
+		}
+		searchAfter = page.Hits.Hits[len(page.Hits.Hits)-1].Sort
+	}
+	return result, nil
+}
+
 // NewSearchRequest returns a search request using the wrapped Elasticsearch
 // client.
 func (es *Client) NewSearchRequest(index string) *SearchRequest {
 	req := &SearchRequest{es: es}
-	req.Index = strings.Split(index, ",")
-	req.Body = strings.NewReader(`{"fields": ["*"]}`)
+	req.Index = splitTargets(index)
+	req.body.Fields = []string{"*"}
 	return req
 }
 
+// splitTargets splits a comma-separated list of data streams, indices,
+// and aliases, trimming any whitespace surrounding each element.
+func splitTargets(targets string) []string {
+	split := strings.Split(targets, ",")
+	for i, target := range split {
+		split[i] = strings.TrimSpace(target)
+	}
+	return split
+}
+
 // SearchRequest wraps an esapi.SearchRequest with a Client.
 type SearchRequest struct {
 	esapi.SearchRequest
-	es *Client
+	es   *Client
+	body searchRequestBody
+
+	// bodySet records whether body has been modified with one of the
+	// builder methods, in which case it replaces any SearchRequest.Body.
+	bodySet bool
+}
+
+// searchRequestBody holds the body of a SearchRequest, which is
+// encoded when the request is performed.
+type searchRequestBody struct {
+	Query           any            `json:"query,omitempty"`
+	Fields          []string       `json:"fields,omitempty"`
+	Source          *sourceFilter  `json:"_source,omitempty"`
+	RuntimeMappings map[string]any `json:"runtime_mappings,omitempty"`
+	Aggregations    map[string]any `json:"aggs,omitempty"`
+	SearchAfter     []any          `json:"search_after,omitempty"`
+	PIT             *pointInTime   `json:"pit,omitempty"`
+	Collapse        *collapse      `json:"collapse,omitempty"`
+	Highlight       *highlight     `json:"highlight,omitempty"`
+}
+
+type highlight struct {
+	Fields map[string]struct{} `json:"fields"`
+}
+
+type collapse struct {
+	Field     string      `json:"field"`
+	InnerHits []InnerHits `json:"inner_hits,omitempty"`
+}
+
+// InnerHits describes the inner hits to return for each collapsed
+// search hit. See SearchRequest.WithCollapse.
+type InnerHits struct {
+	// Name identifies the inner hits in SearchHit.InnerHits.
+	Name string
+
+	// Size holds the maximum number of inner hits to return
+	// for each collapsed hit. Elasticsearch defaults to 3.
+	Size int
+
+	// Sort holds the sort order of the inner hits, as a list
+	// of <field>:<direction> pairs.
+	Sort []string
+}
+
+func (h InnerHits) MarshalJSON() ([]byte, error) {
+	type sortOrder struct {
+		Order string `json:"order,omitempty"`
+	}
+	type innerHits struct {
+		Name string                 `json:"name"`
+		Size int                    `json:"size,omitempty"`
+		Sort []map[string]sortOrder `json:"sort,omitempty"`
+	}
+	out := innerHits{Name: h.Name, Size: h.Size}
+	for _, fieldDirection := range h.Sort {
+		field, direction, _ := strings.Cut(fieldDirection, ":")
+		out.Sort = append(out.Sort, map[string]sortOrder{
+			field: {Order: direction},
+		})
+	}
+	return json.Marshal(out)
+}
+
+type sourceFilter struct {
+	Includes []string `json:"includes,omitempty"`
+	Excludes []string `json:"excludes,omitempty"`
 }
 
 func (r *SearchRequest) WithQuery(q any) *SearchRequest {
-	var body struct {
-		Query  any      `json:"query"`
-		Fields []string `json:"fields"`
+	r.body.Query = q
+	r.bodySet = true
+	return r
+}
+
+// WithFields sets the fields to return for each hit, overriding the
+// default of all fields ("*").
+func (r *SearchRequest) WithFields(fields ...string) *SearchRequest {
+	r.body.Fields = fields
+	r.bodySet = true
+	return r
+}
+
+// WithSourceIncludes sets the _source fields to include for each hit.
+func (r *SearchRequest) WithSourceIncludes(fields ...string) *SearchRequest {
+	if r.body.Source == nil {
+		r.body.Source = &sourceFilter{}
+	}
+	r.body.Source.Includes = fields
+	r.bodySet = true
+	return r
+}
+
+// WithSourceExcludes sets the _source fields to exclude for each hit.
+func (r *SearchRequest) WithSourceExcludes(fields ...string) *SearchRequest {
+	if r.body.Source == nil {
+		r.body.Source = &sourceFilter{}
+	}
+	r.body.Source.Excludes = fields
+	r.bodySet = true
+	return r
+}
+
+// WithRuntimeMappings sets runtime fields for the search request, which
+// may be used in the query and returned with fields. Each key is a field
+// name, and each value the field definition, e.g. {"type": "keyword"}.
+func (r *SearchRequest) WithRuntimeMappings(mappings map[string]any) *SearchRequest {
+	r.body.RuntimeMappings = mappings
+	r.bodySet = true
+	return r
+}
+
+// WithAggregations sets the aggregations for the search request, keyed
+// by name. The results may be decoded with e.g. SearchResult.TermsAggregation.
+func (r *SearchRequest) WithAggregations(aggs map[string]any) *SearchRequest {
+	r.body.Aggregations = aggs
+	r.bodySet = true
+	return r
+}
+
+// WithSearchAfter sets the search_after values for the search request,
+// which should be the sort values of the last hit of the previous page.
+//
+// search_after requires the request to be sorted; see WithSort.
+func (r *SearchRequest) WithSearchAfter(values ...any) *SearchRequest {
+	r.body.SearchAfter = values
+	r.bodySet = true
+	return r
+}
+
+// WithCollapse collapses the search hits by field, returning only the
+// top hit for each distinct value, e.g. the latest document for each
+// service. The field must be a keyword or numeric field with doc_values.
+//
+// Any innerHits are returned for each collapsed hit in
+// SearchHit.InnerHits, keyed by InnerHits.Name.
+func (r *SearchRequest) WithCollapse(field string, innerHits ...InnerHits) *SearchRequest {
+	r.body.Collapse = &collapse{Field: field, InnerHits: innerHits}
+	r.bodySet = true
+	return r
+}
+
+// WithHighlight requests highlighted fragments of the given fields,
+// which may include wildcards, for the terms matching the query. The
+// fragments are returned in SearchHit.Highlight, using the default
+// highlighter settings.
+func (r *SearchRequest) WithHighlight(fields ...string) *SearchRequest {
+	r.body.Highlight = &highlight{Fields: make(map[string]struct{}, len(fields))}
+	for _, field := range fields {
+		r.body.Highlight.Fields[field] = struct{}{}
 	}
-	body.Query = q
-	body.Fields = []string{"*"}
-	r.Body = esutil.NewJSONReader(&body)
+	r.bodySet = true
 	return r
 }
 
@@ -102,18 +460,60 @@ func (r *SearchRequest) WithSort(fieldDirection ...string) *SearchRequest {
 	return r
 }
 
+// WithPreference sets the nodes and shards used for the search request,
+// e.g. "_primary" to avoid reading stale results from replicas, or a
+// custom string to consistently route searches to the same shards.
+func (r *SearchRequest) WithPreference(preference string) *SearchRequest {
+	r.Preference = preference
+	return r
+}
+
+// WithRouting sets the custom routing values for the search request,
+// restricting the search to the shards for those values.
+func (r *SearchRequest) WithRouting(routing ...string) *SearchRequest {
+	r.Routing = routing
+	return r
+}
+
+// WithTerminateAfter sets the maximum number of documents to collect
+// for each shard, after which the search terminates early.
+//
+// The total hits of an early-terminated search is a lower bound rather
+// than an exact count, so TotalHitsCondition should not be used with
+// the request; it will never resize a request with terminate_after set.
+func (r *SearchRequest) WithTerminateAfter(n int) *SearchRequest {
+	r.TerminateAfter = &n
+	return r
+}
+
+// WithRequestCache sets whether the results of the search request
+// are cached by the shard request cache, overriding the index setting.
+func (r *SearchRequest) WithRequestCache(enabled bool) *SearchRequest {
+	r.RequestCache = &enabled
+	return r
+}
+
 func (r *SearchRequest) WithSize(size int) *SearchRequest {
 	r.Size = &size
 	return r
 }
 
 func (r *SearchRequest) Do(ctx context.Context, out *SearchResult, opts ...RequestOption) (*esapi.Response, error) {
+	// Only replace the body if it was built with the builder methods,
+	// or if the caller has not set one directly.
+	if r.bodySet || r.Body == nil {
+		r.Body = esutil.NewJSONReader(&r.body)
+	}
 	return r.es.Do(ctx, &r.SearchRequest, out, opts...)
 }
 
 type SearchResult struct {
 	Hits         SearchHits                 `json:"hits"`
 	Aggregations map[string]json.RawMessage `json:"aggregations"`
+
+	// Error holds the error for an individual search in a
+	// Client.MultiSearch request, if it failed.
+	Error *ESError `json:"-"`
 }
 
 type SearchHits struct {
@@ -140,8 +540,14 @@ func (h *SearchHits) MinHitsCondition(min int) ConditionFunc {
 // TotalHitsCondition returns a ConditionFunc which will return true if the number of h.Hits
 // is at least h.Total.Value. If the condition returns false, it will update req.Size to
 // accommodate the number of hits in the following search.
+//
+// If req has terminate_after set, h.Total.Value is not an exact count, and the condition
+// always returns true without updating req.Size.
 func (h *SearchHits) TotalHitsCondition(req *SearchRequest) ConditionFunc {
 	return func(*esapi.Response) bool {
+		if req.TerminateAfter != nil {
+			return true
+		}
 		if len(h.Hits) >= h.Total.Value {
 			return true
 		}
@@ -157,8 +563,17 @@ type SearchHit struct {
 	Score     float64
 	Fields    map[string][]any
 	Source    map[string]any
+	Sort      []any
 	RawSource json.RawMessage
 	RawFields json.RawMessage
+
+	// InnerHits holds the inner hits of a collapsed search hit,
+	// keyed by name. See SearchRequest.WithCollapse.
+	InnerHits map[string]SearchHits
+
+	// Highlight holds the highlighted fragments for each field.
+	// See SearchRequest.WithHighlight.
+	Highlight map[string][]string
 }
 
 func (h *SearchHit) UnmarshalJSON(data []byte) error {
@@ -166,28 +581,53 @@ func (h *SearchHit) UnmarshalJSON(data []byte) error {
 		Index  string          `json:"_index"`
 		ID     string          `json:"_id"`
 		Score  float64         `json:"_score"`
+		Sort   []any           `json:"sort"`
 		Source json.RawMessage `json:"_source"`
 		Fields json.RawMessage `json:"fields"`
+
+		InnerHits map[string]struct {
+			Hits SearchHits `json:"hits"`
+		} `json:"inner_hits"`
+		Highlight map[string][]string `json:"highlight"`
 	}
 	if err := json.Unmarshal(data, &searchHit); err != nil {
 		return err
 	}
+	h.Highlight = searchHit.Highlight
+	if len(searchHit.InnerHits) > 0 {
+		h.InnerHits = make(map[string]SearchHits, len(searchHit.InnerHits))
+		for name, innerHits := range searchHit.InnerHits {
+			h.InnerHits[name] = innerHits.Hits
+		}
+	}
 	h.Index = searchHit.Index
 	h.ID = searchHit.ID
 	h.Score = searchHit.Score
+	h.Sort = searchHit.Sort
 	h.RawSource = searchHit.Source
 	h.RawFields = searchHit.Fields
 	h.Source = make(map[string]any)
 	h.Fields = make(map[string][]interface{})
-	if err := json.Unmarshal(h.RawSource, &h.Source); err != nil {
-		return fmt.Errorf("error unmarshaling _source: %w", err)
+	// _source may be disabled, and fields are only returned when
+	// requested, in which case the maps are left empty.
+	if !isNullJSON(h.RawSource) {
+		if err := json.Unmarshal(h.RawSource, &h.Source); err != nil {
+			return fmt.Errorf("error unmarshaling _source: %w", err)
+		}
 	}
-	if err := json.Unmarshal(h.RawFields, &h.Fields); err != nil {
-		return fmt.Errorf("error unmarshaling fields: %w", err)
+	if !isNullJSON(h.RawFields) {
+		if err := json.Unmarshal(h.RawFields, &h.Fields); err != nil {
+			return fmt.Errorf("error unmarshaling fields: %w", err)
+		}
 	}
 	return nil
 }
 
+// isNullJSON reports whether raw is absent or JSON null.
+func isNullJSON(raw json.RawMessage) bool {
+	return len(raw) == 0 || string(raw) == "null"
+}
+
 func (h *SearchHit) UnmarshalSource(out any) error {
 	return json.Unmarshal(h.RawSource, out)
 }
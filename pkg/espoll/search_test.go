@@ -0,0 +1,595 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package espoll_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/apm-tools/pkg/espoll"
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+func TestSearchIndexMinDocsRefreshTargets(t *testing.T) {
+	for _, index := range []string{"traces-*,logs-*", "traces-*, logs-*"} {
+		t.Run(index, func(t *testing.T) {
+			var refreshPaths []string
+			client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				if strings.HasSuffix(r.URL.Path, "/_refresh") {
+					refreshPaths = append(refreshPaths, r.URL.Path)
+					w.Write([]byte(`{}`))
+					return
+				}
+				w.Write([]byte(`{"hits":{"total":{"value":1,"relation":"eq"},"hits":[{"_index":"x","_id":"1","_source":{},"fields":{}}]}}`))
+			})
+			_, err := client.SearchIndexMinDocs(context.Background(), 1, index, nil)
+			require.NoError(t, err)
+
+			require.Len(t, refreshPaths, 1)
+			targets := strings.Split(strings.TrimSuffix(strings.TrimPrefix(refreshPaths[0], "/"), "/_refresh"), ",")
+			assert.Equal(t, []string{"traces-*", "logs-*"}, targets)
+		})
+	}
+}
+
+func TestSearchIndexMinDocsRefresh(t *testing.T) {
+	type testcase struct {
+		opts        []espoll.RequestOption
+		refreshPath string // empty if no refresh expected
+	}
+	for name, tc := range map[string]testcase{
+		"default":        {refreshPath: "/traces-*/_refresh"},
+		"enabled":        {opts: []espoll.RequestOption{espoll.WithRefresh(true)}, refreshPath: "/traces-*/_refresh"},
+		"disabled":       {opts: []espoll.RequestOption{espoll.WithRefresh(false)}},
+		"refresh_target": {opts: []espoll.RequestOption{espoll.WithRefreshTarget("traces-apm-default")}, refreshPath: "/traces-apm-default/_refresh"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			for _, refreshFails := range []bool{false, true} {
+				var refreshPaths []string
+				client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+					if strings.HasSuffix(r.URL.Path, "/_refresh") {
+						refreshPaths = append(refreshPaths, r.URL.Path)
+						if refreshFails {
+							w.WriteHeader(http.StatusForbidden)
+						}
+						w.Write([]byte(`{}`))
+						return
+					}
+					w.Write([]byte(`{"hits":{"total":{"value":1,"relation":"eq"},"hits":[{"_index":"x","_id":"1","_source":{},"fields":{}}]}}`))
+				})
+				_, err := client.SearchIndexMinDocs(context.Background(), 1, "traces-*", nil, tc.opts...)
+				if tc.refreshPath == "" {
+					assert.NoError(t, err)
+					assert.Empty(t, refreshPaths)
+					continue
+				}
+				assert.Equal(t, []string{tc.refreshPath}, refreshPaths)
+				if refreshFails {
+					assert.ErrorContains(t, err, "failed refreshing indices")
+				} else {
+					assert.NoError(t, err)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchIndexMinDocsReadConsistency(t *testing.T) {
+	for name, tc := range map[string]struct {
+		opts     []espoll.RequestOption
+		expected []string
+	}{
+		"default": {
+			expected: []string{"/traces-*/_refresh ", "/traces-*/_search "},
+		},
+		"refresh": {
+			opts:     []espoll.RequestOption{espoll.WithReadConsistency(espoll.ReadConsistencyRefresh)},
+			expected: []string{"/traces-*/_refresh ", "/traces-*/_search "},
+		},
+		"eventual": {
+			opts:     []espoll.RequestOption{espoll.WithReadConsistency(espoll.ReadConsistencyEventual)},
+			expected: []string{"/traces-*/_search true"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var requests []string
+			client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				requests = append(requests, r.URL.Path+" "+r.URL.Query().Get("request_cache"))
+				w.Write([]byte(`{"hits":{"total":{"value":1,"relation":"eq"},"hits":[{"_index":"x","_id":"1","_source":{},"fields":{}}]}}`))
+			})
+			_, err := client.SearchIndexMinDocs(context.Background(), 1, "traces-*", nil, tc.opts...)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, requests)
+		})
+	}
+}
+
+func TestReadConsistencyInvalid(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request: %s", r.URL.Path)
+	})
+	_, err := client.SearchIndexMinDocs(context.Background(), 1, "traces-*", nil,
+		espoll.WithReadConsistency("strong"),
+	)
+	assert.EqualError(t, err, `invalid read consistency "strong", expected one of: refresh, eventual`)
+}
+
+func TestSearchRequestSourceFiltering(t *testing.T) {
+	var body []byte
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		body, err = io.ReadAll(r.Body)
+		require.NoError(t, err)
+		w.Write([]byte(`{"hits":{"total":{"value":0,"relation":"eq"},"hits":[]}}`))
+	})
+
+	var result espoll.SearchResult
+	_, err := client.NewSearchRequest("traces-*").
+		WithSourceIncludes("service.*", "trace.id").
+		WithQuery(espoll.TermQuery{Field: "service.name", Value: "foo"}).
+		WithSourceExcludes("service.node").
+		WithFields("service.name").
+		Do(context.Background(), &result)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+	  "query": {"term": {"service.name": {"value": "foo"}}},
+	  "fields": ["service.name"],
+	  "_source": {"includes": ["service.*", "trace.id"], "excludes": ["service.node"]}
+	}`, string(body))
+}
+
+func TestSearchRequestRuntimeMappings(t *testing.T) {
+	var body []byte
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		body, err = io.ReadAll(r.Body)
+		require.NoError(t, err)
+		w.Write([]byte(`{"hits":{"total":{"value":0,"relation":"eq"},"hits":[]}}`))
+	})
+
+	var result espoll.SearchResult
+	_, err := client.NewSearchRequest("traces-*").
+		WithQuery(espoll.TermQuery{Field: "runtime", Value: "foo"}).
+		WithRuntimeMappings(map[string]any{
+			"runtime": map[string]any{
+				"type":   "keyword",
+				"script": map[string]any{"source": "emit(doc['service.name'].value)"},
+			},
+		}).
+		WithFields("runtime").
+		Do(context.Background(), &result)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+	  "query": {"term": {"runtime": {"value": "foo"}}},
+	  "fields": ["runtime"],
+	  "runtime_mappings": {
+	    "runtime": {"type": "keyword", "script": {"source": "emit(doc['service.name'].value)"}}
+	  }
+	}`, string(body))
+}
+
+func TestSearchRequestCollapse(t *testing.T) {
+	var body []byte
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		body, err = io.ReadAll(r.Body)
+		require.NoError(t, err)
+		w.Write([]byte(`{"hits":{"total":{"value":3,"relation":"eq"},"hits":[{
+		  "_index": "traces-apm-default", "_id": "1", "_source": {"service": {"name": "a"}},
+		  "fields": {"service.name": ["a"]},
+		  "inner_hits": {"latest": {"hits": {"total": {"value": 2, "relation": "eq"}, "hits": [
+		    {"_index": "traces-apm-default", "_id": "1", "_source": {"service": {"name": "a"}}},
+		    {"_index": "traces-apm-default", "_id": "2", "_source": {"service": {"name": "a"}}}
+		  ]}}}
+		}, {
+		  "_index": "traces-apm-default", "_id": "3", "_source": {"service": {"name": "b"}},
+		  "fields": {"service.name": ["b"]},
+		  "inner_hits": {"latest": {"hits": {"total": {"value": 1, "relation": "eq"}, "hits": [
+		    {"_index": "traces-apm-default", "_id": "3", "_source": {"service": {"name": "b"}}}
+		  ]}}}
+		}]}}`))
+	})
+
+	var result espoll.SearchResult
+	_, err := client.NewSearchRequest("traces-*").
+		WithCollapse("service.name", espoll.InnerHits{
+			Name: "latest",
+			Size: 2,
+			Sort: []string{"@timestamp:desc"},
+		}).
+		Do(context.Background(), &result)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+	  "fields": ["*"],
+	  "collapse": {
+	    "field": "service.name",
+	    "inner_hits": [{"name": "latest", "size": 2, "sort": [{"@timestamp": {"order": "desc"}}]}]
+	  }
+	}`, string(body))
+
+	require.Len(t, result.Hits.Hits, 2)
+	innerHitIDs := make(map[string][]string)
+	for _, hit := range result.Hits.Hits {
+		require.Contains(t, hit.InnerHits, "latest")
+		latest := hit.InnerHits["latest"]
+		assert.Equal(t, len(latest.Hits), latest.Total.Value)
+		for _, innerHit := range latest.Hits {
+			innerHitIDs[hit.ID] = append(innerHitIDs[hit.ID], innerHit.ID)
+			assert.Equal(t, hit.Source["service"], innerHit.Source["service"])
+		}
+	}
+	assert.Equal(t, map[string][]string{"1": {"1", "2"}, "3": {"3"}}, innerHitIDs)
+}
+
+func TestSearchRequestHighlight(t *testing.T) {
+	var body []byte
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		body, err = io.ReadAll(r.Body)
+		require.NoError(t, err)
+		w.Write([]byte(`{"hits":{"total":{"value":2,"relation":"eq"},"hits":[{
+		  "_index": "logs-apm.app-default", "_id": "1", "_source": {}, "fields": {},
+		  "highlight": {"message": ["connection <em>refused</em> by upstream"]}
+		}, {
+		  "_index": "logs-apm.app-default", "_id": "2", "_source": {}, "fields": {},
+		  "highlight": {
+		    "message": ["<em>refused</em> once", "<em>refused</em> twice"],
+		    "error.message": ["request <em>refused</em>"]
+		  }
+		}]}}`))
+	})
+
+	var result espoll.SearchResult
+	_, err := client.NewSearchRequest("logs-*").
+		WithQuery(espoll.MatchPhraseQuery{Field: "message", Value: "refused"}).
+		WithHighlight("message", "error.*").
+		Do(context.Background(), &result)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+	  "query": {"match_phrase": {"message": "refused"}},
+	  "fields": ["*"],
+	  "highlight": {"fields": {"message": {}, "error.*": {}}}
+	}`, string(body))
+
+	require.Len(t, result.Hits.Hits, 2)
+	assert.Equal(t, map[string][]string{
+		"message": {"connection <em>refused</em> by upstream"},
+	}, result.Hits.Hits[0].Highlight)
+	assert.Equal(t, map[string][]string{
+		"message":       {"<em>refused</em> once", "<em>refused</em> twice"},
+		"error.message": {"request <em>refused</em>"},
+	}, result.Hits.Hits[1].Highlight)
+}
+
+func TestSearchRequestCallerBody(t *testing.T) {
+	var body []byte
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		body, err = io.ReadAll(r.Body)
+		require.NoError(t, err)
+		w.Write([]byte(`{"hits":{"total":{"value":0,"relation":"eq"},"hits":[]}}`))
+	})
+
+	var result espoll.SearchResult
+	req := client.NewSearchRequest("traces-*")
+	req.Body = strings.NewReader(`{"query":{"match_all":{}}}`)
+	_, err := req.Do(context.Background(), &result)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"query":{"match_all":{}}}`, string(body))
+
+	// Builder methods take precedence over the caller's body.
+	req = client.NewSearchRequest("traces-*")
+	req.Body = strings.NewReader(`{"query":{"match_all":{}}}`)
+	_, err = req.WithFields().Do(context.Background(), &result)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{}`, string(body))
+}
+
+func TestSearchAll(t *testing.T) {
+	const numDocs = 25
+	var requests int
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, "seq:asc", r.URL.Query().Get("sort"))
+		assert.Equal(t, "10", r.URL.Query().Get("size"))
+
+		var body struct {
+			SearchAfter []int `json:"search_after"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		start := 0
+		if len(body.SearchAfter) > 0 {
+			start = body.SearchAfter[0] + 1
+		}
+		hits := []map[string]any{}
+		for i := start; i < numDocs && len(hits) < 10; i++ {
+			hits = append(hits, map[string]any{
+				"_id":     strconv.Itoa(i),
+				"_source": map[string]any{"seq": i},
+				"fields":  map[string]any{"seq": []int{i}},
+				"sort":    []int{i},
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"hits": map[string]any{
+				"total": map[string]any{"value": numDocs, "relation": "eq"},
+				"hits":  hits,
+			},
+		})
+	})
+
+	result, err := client.SearchAll(context.Background(), "logs-*", nil,
+		espoll.WithSort("seq:asc"), espoll.WithPageSize(10),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, 3, requests)
+	assert.Equal(t, numDocs, result.Hits.Total.Value)
+
+	ids := make(map[string]int)
+	for _, hit := range result.Hits.Hits {
+		ids[hit.ID]++
+	}
+	require.Len(t, ids, numDocs)
+	for id, n := range ids {
+		assert.Equal(t, 1, n, "document %s returned %d times", id, n)
+	}
+}
+
+func TestSearchAllRequiresSort(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("unexpected request")
+	})
+	_, err := client.SearchAll(context.Background(), "logs-*", nil)
+	assert.EqualError(t, err, "SearchAll requires a sort order")
+}
+
+func TestCountIndexMinDocs(t *testing.T) {
+	var counts int
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/_refresh"):
+			w.Write([]byte(`{}`))
+		case strings.HasSuffix(r.URL.Path, "/_count"):
+			counts++
+			fmt.Fprintf(w, `{"count":%d}`, counts)
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+	})
+
+	count, err := client.CountIndexMinDocs(context.Background(), 3, "traces-*", nil,
+		espoll.WithPollInterval(time.Millisecond),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+	assert.Equal(t, 3, counts)
+}
+
+func TestCountIndexMinDocsTimeout(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/_count") {
+			w.Write([]byte(`{"count":1}`))
+			return
+		}
+		w.Write([]byte(`{}`))
+	})
+
+	count, err := client.CountIndexMinDocs(context.Background(), 2, "traces-*", nil,
+		espoll.WithPollInterval(time.Millisecond),
+		espoll.WithTimeout(50*time.Millisecond),
+	)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 1, count)
+}
+
+// newTestClient returns an espoll.Client which sends requests to an
+// httptest.Server, serving requests with the given handler.
+func TestSearchPreferenceRouting(t *testing.T) {
+	var queries []url.Values
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/_search") {
+			queries = append(queries, r.URL.Query())
+		}
+		w.Write([]byte(`{"hits":{"total":{"value":1,"relation":"eq"},"hits":[{"_source":{},"fields":{}}]}}`))
+	})
+
+	var result espoll.SearchResult
+	_, err := client.NewSearchRequest("traces-*").
+		WithPreference("_primary").
+		WithRouting("a", "b").
+		Do(context.Background(), &result)
+	require.NoError(t, err)
+
+	_, err = client.SearchIndexMinDocs(context.Background(), 1, "traces-*", nil,
+		espoll.WithPreference("_local"),
+		espoll.WithRouting("c"),
+	)
+	require.NoError(t, err)
+
+	require.Len(t, queries, 2)
+	assert.Equal(t, "_primary", queries[0].Get("preference"))
+	assert.Equal(t, "a,b", queries[0].Get("routing"))
+	assert.Equal(t, "_local", queries[1].Get("preference"))
+	assert.Equal(t, "c", queries[1].Get("routing"))
+}
+
+func TestExpandWildcards(t *testing.T) {
+	type testcase struct {
+		opts    []espoll.RequestOption
+		refresh string
+		search  string
+	}
+	for name, tc := range map[string]testcase{
+		"default": {refresh: "all", search: "open,hidden"},
+		"set": {
+			opts:    []espoll.RequestOption{espoll.WithExpandWildcards("open,closed")},
+			refresh: "open,closed",
+			search:  "open,closed",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			expandWildcards := make(map[string][]string)
+			client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				api := r.URL.Path[strings.LastIndexByte(r.URL.Path, '/')+1:]
+				expandWildcards[api] = append(expandWildcards[api], r.URL.Query().Get("expand_wildcards"))
+				w.Write([]byte(`{"count":1,"hits":{"total":{"value":1,"relation":"eq"},"hits":[{"_source":{},"fields":{}}]}}`))
+			})
+			_, err := client.SearchIndexMinDocs(context.Background(), 1, "traces-*", nil, tc.opts...)
+			require.NoError(t, err)
+			_, err = client.CountIndexMinDocs(context.Background(), 1, "traces-*", nil, tc.opts...)
+			require.NoError(t, err)
+
+			assert.Equal(t, map[string][]string{
+				"_refresh": {tc.refresh, tc.refresh},
+				"_search":  {tc.search},
+				"_count":   {tc.search},
+			}, expandWildcards)
+		})
+	}
+}
+
+func TestExpandWildcardsInvalid(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request: %s", r.URL)
+	})
+	for _, value := range []string{"open,bogus", "open,", "*"} {
+		_, err := client.SearchIndexMinDocs(context.Background(), 1, "traces-*", nil,
+			espoll.WithExpandWildcards(value),
+		)
+		assert.ErrorContains(t, err, "invalid expand_wildcards")
+	}
+	assert.NoError(t, espoll.ValidateExpandWildcards("open, hidden"))
+}
+
+func TestSearchIndexMinDocsTerminateAfter(t *testing.T) {
+	for name, exact := range map[string]bool{"exact": true, "inexact": false} {
+		t.Run(name, func(t *testing.T) {
+			var queries []url.Values
+			client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				if strings.HasSuffix(r.URL.Path, "/_search") {
+					queries = append(queries, r.URL.Query())
+				}
+				// The total exceeds the hits, as it would for
+				// a search terminated early across shards.
+				w.Write([]byte(`{"hits":{"total":{"value":4,"relation":"eq"},"hits":[{"_source":{},"fields":{}},{"_source":{},"fields":{}}]}}`))
+			})
+			_, err := client.SearchIndexMinDocs(context.Background(), 2, "traces-*", nil,
+				espoll.WithExactTotalHits(exact),
+				espoll.WithTimeout(100*time.Millisecond),
+				espoll.WithPollInterval(10*time.Millisecond),
+			)
+			if exact {
+				// The total is never reached.
+				assert.Error(t, err)
+				require.NotEmpty(t, queries)
+				assert.False(t, queries[0].Has("terminate_after"))
+				assert.Equal(t, "4", queries[len(queries)-1].Get("size"))
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, queries, 1)
+			assert.Equal(t, "2", queries[0].Get("terminate_after"))
+		})
+	}
+}
+
+func TestTotalHitsConditionTerminateAfter(t *testing.T) {
+	var client espoll.Client
+	req := client.NewSearchRequest("traces-*").WithTerminateAfter(1)
+	hits := espoll.SearchHits{Total: espoll.SearchHitsTotal{Value: 10}}
+	assert.True(t, hits.TotalHitsCondition(req)(nil))
+	assert.Nil(t, req.Size)
+}
+
+func TestSearchHitUnmarshalJSON(t *testing.T) {
+	for name, tc := range map[string]struct {
+		hit    string
+		source map[string]any
+		fields map[string][]any
+	}{
+		"source_and_fields": {
+			hit:    `{"_id":"1","_source":{"a":"b"},"fields":{"a":["b"]}}`,
+			source: map[string]any{"a": "b"},
+			fields: map[string][]any{"a": {"b"}},
+		},
+		"source_only": {
+			hit:    `{"_id":"1","_source":{"a":"b"}}`,
+			source: map[string]any{"a": "b"},
+			fields: map[string][]any{},
+		},
+		"fields_only": {
+			hit:    `{"_id":"1","fields":{"a":["b"]}}`,
+			source: map[string]any{},
+			fields: map[string][]any{"a": {"b"}},
+		},
+		"neither": {
+			hit:    `{"_id":"1"}`,
+			source: map[string]any{},
+			fields: map[string][]any{},
+		},
+		"null": {
+			hit:    `{"_id":"1","_source":null,"fields":null}`,
+			source: map[string]any{},
+			fields: map[string][]any{},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var hit espoll.SearchHit
+			require.NoError(t, json.Unmarshal([]byte(tc.hit), &hit))
+			assert.Equal(t, "1", hit.ID)
+			assert.Equal(t, tc.source, hit.Source)
+			assert.Equal(t, tc.fields, hit.Fields)
+		})
+	}
+
+	var hit espoll.SearchHit
+	err := json.Unmarshal([]byte(`{"_id":"1","_source":["a"]}`), &hit)
+	assert.ErrorContains(t, err, "error unmarshaling _source")
+}
+
+func newTestClient(t testing.TB, handler http.HandlerFunc) *espoll.Client {
+	t.Helper()
+	var mu sync.Mutex
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := elasticsearch.NewClient(elasticsearch.Config{
+		Addresses: []string{srv.URL},
+	})
+	require.NoError(t, err)
+	return espoll.WrapClient(client)
+}
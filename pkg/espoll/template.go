@@ -0,0 +1,79 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package espoll
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"text/template"
+)
+
+// QueryTemplate is a parameterized query, for issuing queries of the
+// same shape with different values.
+type QueryTemplate struct {
+	tmpl *template.Template
+}
+
+// NewQueryTemplate parses tmpl as a text/template, returning a
+// QueryTemplate which may be rendered with different parameters.
+//
+// Parameters are referenced by name, e.g. {{.service}}, and are
+// substituted with their JSON encoding, so string values must not
+// be quoted in the template. For example:
+//
+//	{"term": {"service.name": {{.service}}}}
+func NewQueryTemplate(tmpl string) (*QueryTemplate, error) {
+	t, err := template.New("query").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing query template: %w", err)
+	}
+	return &QueryTemplate{tmpl: t}, nil
+}
+
+// Render returns the query with params substituted, for passing to
+// the Client's search methods. Errors rendering the template, such as
+// missing parameters, or rendering invalid JSON, are returned when the
+// query is encoded.
+func (t *QueryTemplate) Render(params map[string]any) json.Marshaler {
+	return renderedQuery{tmpl: t.tmpl, params: params}
+}
+
+type renderedQuery struct {
+	tmpl   *template.Template
+	params map[string]any
+}
+
+func (q renderedQuery) MarshalJSON() ([]byte, error) {
+	encoded := make(map[string]string, len(q.params))
+	for k, v := range q.params {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding query parameter %q: %w", k, err)
+		}
+		encoded[k] = string(data)
+	}
+	var buf bytes.Buffer
+	if err := q.tmpl.Execute(&buf, encoded); err != nil {
+		return nil, fmt.Errorf("error rendering query template: %w", err)
+	}
+	if !json.Valid(buf.Bytes()) {
+		return nil, fmt.Errorf("query template rendered invalid JSON: %s", buf.String())
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,65 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package espoll
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryTemplate(t *testing.T) {
+	tmpl, err := NewQueryTemplate(`{"term": {"service.name": {{.service}}}}`)
+	require.NoError(t, err)
+
+	data, err := json.Marshal(tmpl.Render(map[string]any{"service": "frontend"}))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"term": {"service.name": "frontend"}}`, string(data))
+
+	// Values are escaped, so they cannot alter the query's structure.
+	data, err = json.Marshal(tmpl.Render(map[string]any{"service": `a"}, "b": {"c`}))
+	require.NoError(t, err)
+	assert.True(t, json.Valid(data))
+	assert.JSONEq(t, `{"term": {"service.name": "a\"}, \"b\": {\"c"}}`, string(data))
+
+	// Non-string values are encoded as JSON too.
+	tmpl, err = NewQueryTemplate(`{"term": {"service.name": {"value": {{.service}}, "boost": {{.boost}}}}}`)
+	require.NoError(t, err)
+	expected, err := json.Marshal(TermQuery{Field: "service.name", Value: "frontend", Boost: 1.5})
+	require.NoError(t, err)
+	data, err = json.Marshal(tmpl.Render(map[string]any{"service": "frontend", "boost": 1.5}))
+	require.NoError(t, err)
+	assert.JSONEq(t, string(expected), string(data))
+}
+
+func TestQueryTemplateErrors(t *testing.T) {
+	_, err := NewQueryTemplate(`{"term": {"service.name": {{.service}`)
+	assert.ErrorContains(t, err, "error parsing query template")
+
+	tmpl, err := NewQueryTemplate(`{"term": {"service.name": {{.service}}}}`)
+	require.NoError(t, err)
+	_, err = json.Marshal(tmpl.Render(nil))
+	assert.ErrorContains(t, err, "error rendering query template")
+
+	tmpl, err = NewQueryTemplate(`{"term": {"service.name": {{.service}}}`)
+	require.NoError(t, err)
+	_, err = json.Marshal(tmpl.Render(map[string]any{"service": "frontend"}))
+	assert.ErrorContains(t, err, "query template rendered invalid JSON")
+}
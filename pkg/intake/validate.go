@@ -0,0 +1,109 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package intake
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// maxLineSize holds the maximum size of an ND-JSON line,
+// matching the default max event size of APM Server.
+const maxLineSize = 300 * 1024
+
+// eventTypes holds the known top-level keys of intake V2 events.
+var eventTypes = map[string]bool{
+	"metadata":    true,
+	"transaction": true,
+	"span":        true,
+	"error":       true,
+	"metricset":   true,
+	"log":         true,
+}
+
+// LineError describes an invalid line of an ND-JSON event stream.
+type LineError struct {
+	// Line holds the 1-based line number.
+	Line int
+	// Err holds the reason the line is invalid.
+	Err error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// ValidateIntakeNDJSON checks that each non-empty line read from r is a
+// JSON object with exactly one of the known intake V2 event keys:
+// metadata, transaction, span, error, metricset, or log.
+//
+// ValidateIntakeNDJSON does not validate the events themselves. Each
+// invalid line is reported with a *LineError; an error reading from r
+// ends validation and is returned last.
+func ValidateIntakeNDJSON(r io.Reader) []error {
+	var errs []error
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxLineSize)
+	var line int
+	for scanner.Scan() {
+		line++
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		if err := validateEvent(data); err != nil {
+			errs = append(errs, &LineError{Line: line, Err: err})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, fmt.Errorf("line %d: error reading events: %w", line+1, err))
+	}
+	return errs
+}
+
+func validateEvent(data []byte) error {
+	if data[0] != '{' {
+		return errors.New("not a JSON object")
+	}
+	var event map[string]json.RawMessage
+	if err := json.Unmarshal(data, &event); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	keys := make([]string, 0, len(event))
+	for key := range event {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	switch {
+	case len(keys) != 1:
+		return fmt.Errorf("expected exactly one event key, got %d: [%s]", len(keys), strings.Join(keys, ", "))
+	case !eventTypes[keys[0]]:
+		return fmt.Errorf("unknown event key %q", keys[0])
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package intake_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/apm-tools/pkg/intake"
+)
+
+func TestValidateIntakeNDJSONValid(t *testing.T) {
+	errs := intake.ValidateIntakeNDJSON(strings.NewReader(`{"metadata":{"service":{"name":"svc"}}}
+{"transaction":{"id":"tx"}}
+{"span":{"id":"span"}}
+
+{"error":{"id":"err"}}
+{"metricset":{"samples":{}}}
+{"log":{"message":"hello"}}
+`))
+	assert.Empty(t, errs)
+}
+
+func TestValidateIntakeNDJSONInvalid(t *testing.T) {
+	errs := intake.ValidateIntakeNDJSON(strings.NewReader(`{"metadata":{}}
+{"transaction":{}
+["span"]
+{}
+{"span":{},"error":{}}
+{"event":{}}
+{"log":{}}
+`))
+	require.Len(t, errs, 5)
+	var messages []string
+	for _, err := range errs {
+		var lineErr *intake.LineError
+		require.True(t, errors.As(err, &lineErr))
+		messages = append(messages, err.Error())
+	}
+	assert.Equal(t, []string{
+		"line 2: invalid JSON: unexpected end of JSON input",
+		"line 3: not a JSON object",
+		"line 4: expected exactly one event key, got 0: []",
+		"line 5: expected exactly one event key, got 2: [error, span]",
+		`line 6: unknown event key "event"`,
+	}, messages)
+}
+
+func TestValidateIntakeNDJSONLineTooLong(t *testing.T) {
+	long := `{"log":{"message":"` + strings.Repeat("x", 400*1024) + `"}}`
+	errs := intake.ValidateIntakeNDJSON(strings.NewReader(`{"metadata":{}}` + "\n" + long + "\n"))
+	require.Len(t, errs, 1)
+	assert.ErrorContains(t, errs[0], "line 2: error reading events")
+}
+
+func TestValidateIntakeNDJSONReadError(t *testing.T) {
+	errs := intake.ValidateIntakeNDJSON(iotest.ErrReader(errors.New("boom")))
+	require.Len(t, errs, 1)
+	assert.EqualError(t, errs[0], "line 1: error reading events: boom")
+}
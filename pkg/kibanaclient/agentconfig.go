@@ -0,0 +1,93 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package kibanaclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// ErrAgentConfigNotFound is returned by GetAgentConfig when there
+// is no agent configuration for the service.
+var ErrAgentConfigNotFound = errors.New("agent configuration not found")
+
+const agentConfigPath = "/api/apm/settings/agent-configuration"
+
+// ServiceMatch identifies the services to which an agent
+// configuration applies. An empty Environment matches all
+// environments of the service.
+type ServiceMatch struct {
+	Name        string `json:"name,omitempty"`
+	Environment string `json:"environment,omitempty"`
+}
+
+// AgentConfig holds an agent central configuration stored in Kibana.
+type AgentConfig struct {
+	Service  ServiceMatch      `json:"service"`
+	Settings map[string]string `json:"settings"`
+
+	// AgentName holds the name of the agent for which the
+	// configuration is defined, if known.
+	AgentName string `json:"agent_name,omitempty"`
+
+	// AppliedByAgent reports whether an agent has
+	// applied the latest configuration.
+	AppliedByAgent bool `json:"applied_by_agent"`
+}
+
+// SetAgentConfig creates or overwrites the agent central configuration
+// for service, with the given settings.
+func (c *Client) SetAgentConfig(ctx context.Context, service ServiceMatch, settings map[string]string) error {
+	if service.Name == "" {
+		return errors.New("service name must be specified")
+	}
+	body := struct {
+		Service  ServiceMatch      `json:"service"`
+		Settings map[string]string `json:"settings"`
+	}{Service: service, Settings: settings}
+	if err := c.Do(ctx, http.MethodPut, agentConfigPath+"?overwrite=true", body, nil); err != nil {
+		return fmt.Errorf("error setting agent configuration: %w", err)
+	}
+	return nil
+}
+
+// GetAgentConfig returns the agent central configuration for service.
+// If there is none, GetAgentConfig returns an error satisfying
+// errors.Is(err, ErrAgentConfigNotFound).
+func (c *Client) GetAgentConfig(ctx context.Context, service ServiceMatch) (AgentConfig, error) {
+	if service.Name == "" {
+		return AgentConfig{}, errors.New("service name must be specified")
+	}
+	query := url.Values{"name": []string{service.Name}}
+	if service.Environment != "" {
+		query.Set("environment", service.Environment)
+	}
+	var config AgentConfig
+	err := c.Do(ctx, http.MethodGet, agentConfigPath+"/view?"+query.Encode(), nil, &config)
+	var kibanaErr *Error
+	if errors.As(err, &kibanaErr) && kibanaErr.StatusCode == http.StatusNotFound {
+		return AgentConfig{}, fmt.Errorf("error getting agent configuration for %q: %w", service.Name, ErrAgentConfigNotFound)
+	}
+	if err != nil {
+		return AgentConfig{}, fmt.Errorf("error getting agent configuration: %w", err)
+	}
+	return config, nil
+}
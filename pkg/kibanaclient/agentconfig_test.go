@@ -0,0 +1,119 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package kibanaclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/apm-tools/pkg/apmclient"
+	"github.com/elastic/apm-tools/pkg/kibanaclient"
+)
+
+func TestSetAgentConfig(t *testing.T) {
+	for name, tc := range map[string]struct {
+		service  kibanaclient.ServiceMatch
+		expected string
+	}{
+		"service": {
+			service:  kibanaclient.ServiceMatch{Name: "svc"},
+			expected: `{"service":{"name":"svc"},"settings":{"transaction_sample_rate":"0.5"}}`,
+		},
+		"environment": {
+			service:  kibanaclient.ServiceMatch{Name: "svc", Environment: "production"},
+			expected: `{"service":{"name":"svc","environment":"production"},"settings":{"transaction_sample_rate":"0.5"}}`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var body json.RawMessage
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodPut, r.Method)
+				assert.Equal(t, "/api/apm/settings/agent-configuration", r.URL.Path)
+				assert.Equal(t, "true", r.URL.Query().Get("overwrite"))
+				assert.Equal(t, "1", r.Header.Get("kbn-xsrf"))
+				assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+				username, password, _ := r.BasicAuth()
+				assert.Equal(t, "elastic", username)
+				assert.Equal(t, "changeme", password)
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+				w.Write([]byte(`{}`))
+			}))
+			defer srv.Close()
+
+			client, err := kibanaclient.New(apmclient.Config{
+				KibanaURL: srv.URL,
+				Username:  "elastic",
+				Password:  "changeme",
+			})
+			require.NoError(t, err)
+			err = client.SetAgentConfig(context.Background(), tc.service, map[string]string{
+				"transaction_sample_rate": "0.5",
+			})
+			require.NoError(t, err)
+			assert.JSONEq(t, tc.expected, string(body))
+		})
+	}
+}
+
+func TestGetAgentConfig(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method)
+		assert.Equal(t, "/api/apm/settings/agent-configuration/view", r.URL.Path)
+		assert.Equal(t, "ApiKey api_key", r.Header.Get("Authorization"))
+		query := r.URL.Query()
+		if query.Get("name") != "svc" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		assert.Equal(t, "production", query.Get("environment"))
+		w.Write([]byte(`{
+		  "service": {"name": "svc", "environment": "production"},
+		  "settings": {"capture_body": "all"},
+		  "agent_name": "go",
+		  "applied_by_agent": true,
+		  "etag": "abc123"
+		}`))
+	}))
+	defer srv.Close()
+
+	client, err := kibanaclient.New(apmclient.Config{
+		KibanaURL: srv.URL,
+		APIKey:    "api_key",
+	})
+	require.NoError(t, err)
+
+	config, err := client.GetAgentConfig(context.Background(), kibanaclient.ServiceMatch{
+		Name: "svc", Environment: "production",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, kibanaclient.AgentConfig{
+		Service:        kibanaclient.ServiceMatch{Name: "svc", Environment: "production"},
+		Settings:       map[string]string{"capture_body": "all"},
+		AgentName:      "go",
+		AppliedByAgent: true,
+	}, config)
+
+	_, err = client.GetAgentConfig(context.Background(), kibanaclient.ServiceMatch{Name: "unknown"})
+	assert.ErrorIs(t, err, kibanaclient.ErrAgentConfigNotFound)
+}
@@ -0,0 +1,165 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package kibanaclient provides a client for the Kibana APIs.
+package kibanaclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/elastic/apm-tools/pkg/apmclient"
+)
+
+// Client is a client for the Kibana APIs.
+type Client struct {
+	kibanaURL  string
+	username   string
+	password   string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// Option is an option for New.
+type Option func(*Client)
+
+// WithTimeout specifies a timeout for each request.
+// Zero, the default, means no timeout.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.httpClient.Timeout = d
+	}
+}
+
+// New returns a new Client for the Kibana at cfg.KibanaURL.
+//
+// Requests are authenticated with cfg.APIKey if specified, and
+// otherwise with cfg.Username and cfg.Password. Requests are sent
+// with cfg.Transport if specified, and otherwise with a transport
+// honouring cfg.TLSSkipVerify.
+func New(cfg apmclient.Config, opts ...Option) (*Client, error) {
+	if cfg.KibanaURL == "" {
+		return nil, errors.New("Kibana URL must be configured")
+	}
+	transport := cfg.Transport
+	if transport == nil {
+		defaultTransport := http.DefaultTransport.(*http.Transport).Clone()
+		defaultTransport.TLSClientConfig = &tls.Config{InsecureSkipVerify: cfg.TLSSkipVerify}
+		transport = defaultTransport
+	}
+	c := &Client{
+		kibanaURL:  strings.TrimSuffix(cfg.KibanaURL, "/"),
+		username:   cfg.Username,
+		password:   cfg.Password,
+		apiKey:     cfg.APIKey,
+		httpClient: &http.Client{Transport: transport},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// Body holds a request body which is sent as is,
+// rather than being encoded as JSON.
+type Body struct {
+	ContentType string
+	Reader      io.Reader
+}
+
+// Error is returned by Client.Do when Kibana
+// responds with a non-2xx status code.
+type Error struct {
+	StatusCode int
+	Status     string
+	Body       string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("Kibana responded with %q: %s", e.Status, e.Body)
+}
+
+// Do performs a request to the Kibana API at path.
+//
+// If body is a Body, it is sent as is; otherwise if non-nil, it is
+// sent encoded as JSON. If out is an io.Writer, the response body is
+// copied to it; otherwise if non-nil, the response body is decoded
+// into it as JSON. If Kibana responds with a non-2xx status code,
+// an *Error is returned.
+func (c *Client) Do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	var contentType string
+	switch body := body.(type) {
+	case nil:
+	case Body:
+		reader, contentType = body.Reader, body.ContentType
+	default:
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("error encoding request body: %w", err)
+		}
+		reader, contentType = bytes.NewReader(data), "application/json"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.kibanaURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("error creating HTTP request: %w", err)
+	}
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "ApiKey "+c.apiKey)
+	} else {
+		req.SetBasicAuth(c.username, c.password)
+	}
+	req.Header.Set("kbn-xsrf", "1")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error performing HTTP request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return &Error{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Body:       string(bytes.TrimSpace(respBody)),
+		}
+	}
+	switch out := out.(type) {
+	case nil:
+	case io.Writer:
+		if _, err := io.Copy(out, resp.Body); err != nil {
+			return fmt.Errorf("error reading response body: %w", err)
+		}
+	default:
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("error decoding response body: %w", err)
+		}
+	}
+	return nil
+}
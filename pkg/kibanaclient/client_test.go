@@ -0,0 +1,145 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package kibanaclient_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/apm-tools/pkg/apmclient"
+	"github.com/elastic/apm-tools/pkg/kibanaclient"
+)
+
+func TestDoBasicAuth(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/api/things", r.URL.Path)
+		assert.Equal(t, "1", r.Header.Get("kbn-xsrf"))
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		username, password, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "elastic", username)
+		assert.Equal(t, "changeme", password)
+
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, map[string]any{"name": "thing"}, body)
+		w.Write([]byte(`{"id": "abc"}`))
+	}))
+	defer srv.Close()
+
+	client, err := kibanaclient.New(apmclient.Config{
+		KibanaURL: srv.URL,
+		Username:  "elastic",
+		Password:  "changeme",
+	})
+	require.NoError(t, err)
+
+	var out struct {
+		ID string `json:"id"`
+	}
+	err = client.Do(context.Background(), http.MethodPost, "/api/things", map[string]string{"name": "thing"}, &out)
+	require.NoError(t, err)
+	assert.Equal(t, "abc", out.ID)
+}
+
+func TestDoAPIKey(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "ApiKey api_key", r.Header.Get("Authorization"))
+		assert.Equal(t, "1", r.Header.Get("kbn-xsrf"))
+		assert.Empty(t, r.Header.Get("Content-Type"))
+		w.Write([]byte("raw response"))
+	}))
+	defer srv.Close()
+
+	client, err := kibanaclient.New(apmclient.Config{
+		KibanaURL: srv.URL + "/",
+		Username:  "elastic",
+		Password:  "changeme",
+		APIKey:    "api_key",
+	})
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	err = client.Do(context.Background(), http.MethodGet, "/api/things", nil, &out)
+	require.NoError(t, err)
+	assert.Equal(t, "raw response", out.String())
+}
+
+func TestDoBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "text/plain", r.Header.Get("Content-Type"))
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "hello", string(body))
+	}))
+	defer srv.Close()
+
+	client, err := kibanaclient.New(apmclient.Config{KibanaURL: srv.URL})
+	require.NoError(t, err)
+	err = client.Do(context.Background(), http.MethodPut, "/api/things", kibanaclient.Body{
+		ContentType: "text/plain",
+		Reader:      strings.NewReader("hello"),
+	}, nil)
+	require.NoError(t, err)
+}
+
+func TestDoError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(`{"message": "conflict"}` + "\n"))
+	}))
+	defer srv.Close()
+
+	client, err := kibanaclient.New(apmclient.Config{KibanaURL: srv.URL})
+	require.NoError(t, err)
+	err = client.Do(context.Background(), http.MethodPost, "/api/things", nil, nil)
+	var kibanaErr *kibanaclient.Error
+	require.ErrorAs(t, err, &kibanaErr)
+	assert.Equal(t, http.StatusConflict, kibanaErr.StatusCode)
+	assert.EqualError(t, err, `Kibana responded with "409 Conflict": {"message": "conflict"}`)
+}
+
+func TestDoTLSSkipVerify(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	client, err := kibanaclient.New(apmclient.Config{KibanaURL: srv.URL})
+	require.NoError(t, err)
+	err = client.Do(context.Background(), http.MethodGet, "/", nil, nil)
+	assert.ErrorContains(t, err, "certificate")
+
+	client, err = kibanaclient.New(apmclient.Config{KibanaURL: srv.URL, TLSSkipVerify: true})
+	require.NoError(t, err)
+	err = client.Do(context.Background(), http.MethodGet, "/", nil, nil)
+	assert.NoError(t, err)
+}
+
+func TestNewNoKibanaURL(t *testing.T) {
+	_, err := kibanaclient.New(apmclient.Config{})
+	assert.EqualError(t, err, "Kibana URL must be configured")
+}
@@ -18,8 +18,24 @@
 package metricgen
 
 import (
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/elastic/apm-tools/internal/apmconn"
+)
+
+// Errors returned by validation of the config, which may be
+// detected with errors.Is.
+var (
+	ErrMissingServerURL = errors.New("APM server URL cannot be empty")
+	// ErrMissingAPIKey is returned if neither an API Key nor a secret
+	// token is configured.
+	ErrMissingAPIKey       = errors.New("API Key and secret token cannot both be empty")
+	ErrInvalidOTLPProtocol = errors.New("unknown otlp protocol")
 )
 
 type ConfigOption func(*config)
@@ -27,10 +43,21 @@ type ConfigOption func(*config)
 type config struct {
 	// apiKey holds an Elasticsearch API key.
 	apiKey string
+	// secretToken holds an APM Server secret token.
+	secretToken string
 	// apmServerURL holdes the Elasticsearch APM server URL endpoint.
 	apmServerURL string
 	// verifyServerCert determines if endpoint TLS certificates will be validated.
 	verifyServerCert bool
+	// certFile and keyFile hold the paths of a client certificate
+	// and key for mutual TLS.
+	certFile string
+	keyFile  string
+	// caFile holds the path of a CA certificate used to verify the server.
+	caFile string
+	// proxyURL holds the URL of an HTTP proxy for OTLP HTTP requests.
+	// If empty, the proxy is determined by the environment.
+	proxyURL string
 
 	// apmServiceName holds the service name sent with Elastic APM metrics.
 	apmServiceName string
@@ -39,6 +66,47 @@ type config struct {
 	// otlpProtocol specifies the OTLP protocol to use for sending metrics.
 	// Valid values are: grpc, http/protobuf.
 	otlpProtocol string
+	// grpcKeepaliveTime and grpcKeepaliveTimeout configure keepalive
+	// pings on gRPC connections, if grpcKeepaliveTime is positive.
+	grpcKeepaliveTime    time.Duration
+	grpcKeepaliveTimeout time.Duration
+	// dialTimeout bounds the time taken to establish gRPC connections.
+	dialTimeout time.Duration
+	// temporality specifies the aggregation temporality of OTLP metrics.
+	// If unset, the OTel SDK default (cumulative) is used.
+	temporality metricdata.Temporality
+
+	// histogram determines if a histogram metric is generated
+	// in addition to the counter.
+	histogram bool
+	// gauge determines if a gauge metric is generated
+	// in addition to the counter.
+	gauge bool
+	// exponentialHistogram determines if an exponential histogram
+	// metric is generated in addition to the counter.
+	exponentialHistogram bool
+	// histogramValues holds the values recorded by the histograms.
+	// If empty, the values 1.0, 10.0, and 100.0 are recorded.
+	histogramValues []float64
+
+	// metrics holds the counters to generate. If empty, a single
+	// counter with value 1.0 is generated.
+	metrics []counterConfig
+
+	// resourceAttributes holds additional resource attributes
+	// sent with OTLP metrics.
+	resourceAttributes map[string]string
+	// scopeName and scopeVersion hold the instrumentation scope
+	// of OTLP metrics. If scopeName is empty, "metricgen" is used.
+	scopeName    string
+	scopeVersion string
+}
+
+// counterConfig holds the name, value and attributes of a generated counter.
+type counterConfig struct {
+	name  string
+	value float64
+	attrs map[string]string
 }
 
 const (
@@ -53,16 +121,42 @@ func (cfg config) Validate() error {
 	}
 
 	if cfg.apmServerURL == "" {
-		errs = append(errs, errors.New("APM server URL cannot be empty"))
+		errs = append(errs, ErrMissingServerURL)
+	}
+	switch {
+	case cfg.apiKey == "" && cfg.secretToken == "":
+		errs = append(errs, ErrMissingAPIKey)
+	case cfg.apiKey != "" && cfg.secretToken != "":
+		errs = append(errs, errors.New("API Key and secret token cannot both be set"))
+	}
+
+	if _, err := cfg.tlsConfig(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := apmconn.ProxyFunc(cfg.proxyURL); err != nil {
+		errs = append(errs, err)
+	}
+
+	for _, m := range cfg.metrics {
+		if m.name == "" {
+			errs = append(errs, errors.New("metric name cannot be empty"))
+		}
 	}
-	if cfg.apiKey == "" {
-		errs = append(errs, errors.New("API Key cannot be empty"))
+
+	switch cfg.temporality {
+	case 0, metricdata.CumulativeTemporality, metricdata.DeltaTemporality:
+	default:
+		errs = append(errs, fmt.Errorf("unknown temporality: %s", cfg.temporality))
+	}
+
+	if cfg.grpcKeepaliveTime < 0 || cfg.grpcKeepaliveTimeout < 0 || cfg.dialTimeout < 0 {
+		errs = append(errs, errors.New("gRPC keepalive and dial timeouts cannot be negative"))
 	}
 
 	switch cfg.otlpProtocol {
 	case httpOTLPProtocol, grpcOTLPProtocol:
 	default:
-		errs = append(errs, fmt.Errorf("unknown otlp protocol: %s", cfg.otlpProtocol))
+		errs = append(errs, fmt.Errorf("%w: %s", ErrInvalidOTLPProtocol, cfg.otlpProtocol))
 	}
 
 	if len(errs) > 0 {
@@ -72,6 +166,25 @@ func (cfg config) Validate() error {
 	return nil
 }
 
+// tlsConfig returns the TLS configuration for connecting to the APM Server.
+func (cfg config) tlsConfig() (*tls.Config, error) {
+	return apmconn.TLSOptions{
+		InsecureSkipVerify: !cfg.verifyServerCert,
+		CertFile:           cfg.certFile,
+		KeyFile:            cfg.keyFile,
+		CAFile:             cfg.caFile,
+	}.Config()
+}
+
+// grpcOptions returns the settings for the OTLP gRPC connection.
+func (cfg config) grpcOptions() apmconn.GRPCOptions {
+	return apmconn.GRPCOptions{
+		KeepaliveTime:    cfg.grpcKeepaliveTime,
+		KeepaliveTimeout: cfg.grpcKeepaliveTimeout,
+		DialTimeout:      cfg.dialTimeout,
+	}
+}
+
 func newConfig(opts ...ConfigOption) config {
 	cfg := config{
 		otlpProtocol: "grpc",
@@ -89,6 +202,12 @@ func WithAPIKey(s string) ConfigOption {
 	}
 }
 
+func WithSecretToken(s string) ConfigOption {
+	return func(c *config) {
+		c.secretToken = s
+	}
+}
+
 func WithAPMServerURL(s string) ConfigOption {
 	return func(c *config) {
 		c.apmServerURL = s
@@ -101,6 +220,39 @@ func WithVerifyServerCert(b bool) ConfigOption {
 	}
 }
 
+// WithClientCert specifies the paths of a PEM encoded client certificate
+// and key, presented to the APM Server for mutual TLS authentication.
+//
+// This config will be ignored when using SendIntakeV2.
+func WithClientCert(certFile, keyFile string) ConfigOption {
+	return func(c *config) {
+		c.certFile = certFile
+		c.keyFile = keyFile
+	}
+}
+
+// WithCACert specifies the path of a PEM encoded CA certificate used
+// to verify the APM Server's certificate, instead of the system roots.
+//
+// This config will be ignored when using SendIntakeV2.
+func WithCACert(caFile string) ConfigOption {
+	return func(c *config) {
+		c.caFile = caFile
+	}
+}
+
+// WithProxyURL specifies the URL of an HTTP proxy through which OTLP
+// HTTP and Prometheus requests are sent. If unset, the proxy is determined by the
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+//
+// This config is ignored by the OTLP gRPC exporter, which would need
+// a dialer issuing HTTP CONNECT requests (grpc.WithContextDialer).
+func WithProxyURL(s string) ConfigOption {
+	return func(c *config) {
+		c.proxyURL = s
+	}
+}
+
 // WithElasticAPMServiceName specifies the service name that
 // the Elastic APM agent will use.
 //
@@ -130,3 +282,97 @@ func WithOTLPProtocol(p string) ConfigOption {
 		c.otlpProtocol = p
 	}
 }
+
+// WithGRPCKeepalive enables keepalive pings on OTLP gRPC connections,
+// sent after interval without activity, closing the connection if there is
+// no response within timeout. This detects connections silently dropped
+// by intermediaries such as load balancers. gRPC enforces a minimum
+// interval of 10 seconds, and defaults timeout to 20 seconds if zero.
+func WithGRPCKeepalive(interval, timeout time.Duration) ConfigOption {
+	return func(c *config) {
+		c.grpcKeepaliveTime = interval
+		c.grpcKeepaliveTimeout = timeout
+	}
+}
+
+// WithDialTimeout bounds the time taken to establish each OTLP gRPC
+// connection.
+func WithDialTimeout(d time.Duration) ConfigOption {
+	return func(c *config) {
+		c.dialTimeout = d
+	}
+}
+
+// WithHistogram specifies whether to generate a histogram in addition
+// to the counter, recording the values specified by WithHistogramValues.
+// With SendIntakeV2, the histogram is recorded via the apmotel bridge,
+// and sent as a histogram metricset.
+func WithHistogram(b bool) ConfigOption {
+	return func(c *config) {
+		c.histogram = b
+	}
+}
+
+// WithHistogramValues specifies the values recorded by the histograms
+// generated with WithHistogram and WithExponentialHistogram. Defaults
+// to 1.0, 10.0, and 100.0.
+func WithHistogramValues(values ...float64) ConfigOption {
+	return func(c *config) {
+		c.histogramValues = values
+	}
+}
+
+func WithGauge(b bool) ConfigOption {
+	return func(c *config) {
+		c.gauge = b
+	}
+}
+
+// WithExponentialHistogram specifies whether to generate a histogram
+// aggregated with base-2 exponential buckets, in addition to the counter.
+//
+// This config will be ignored when using SendIntakeV2.
+func WithExponentialHistogram(b bool) ConfigOption {
+	return func(c *config) {
+		c.exponentialHistogram = b
+	}
+}
+
+// WithTemporality specifies the aggregation temporality of metrics
+// sent with SendOTLP: metricdata.CumulativeTemporality (the default)
+// or metricdata.DeltaTemporality.
+func WithTemporality(t metricdata.Temporality) ConfigOption {
+	return func(c *config) {
+		c.temporality = t
+	}
+}
+
+// WithMetric appends a counter with the given name, value and attributes
+// to the metrics to generate. It may be specified multiple times.
+func WithMetric(name string, value float64, attrs map[string]string) ConfigOption {
+	return func(c *config) {
+		c.metrics = append(c.metrics, counterConfig{name: name, value: value, attrs: attrs})
+	}
+}
+
+// WithResourceAttributes specifies additional resource attributes to send
+// with OTLP metrics. The service.name attribute is always set from
+// WithOTLPServiceName, and cannot be overridden.
+//
+// This config will be ignored when using SendIntakeV2.
+func WithResourceAttributes(attrs map[string]string) ConfigOption {
+	return func(c *config) {
+		c.resourceAttributes = attrs
+	}
+}
+
+// WithScope specifies the instrumentation scope name and version of
+// OTLP metrics. The scope name defaults to "metricgen".
+//
+// This config will be ignored when using SendIntakeV2.
+func WithScope(name, version string) ConfigOption {
+	return func(c *config) {
+		c.scopeName = name
+		c.scopeVersion = version
+	}
+}
@@ -15,5 +15,5 @@
 // specific language governing permissions and limitations
 // under the License.
 
-// Package metricgen generates Elastic APM V2 and OTLP data for smoke testing.
+// Package metricgen generates Elastic APM V2, OTLP and Prometheus data for smoke testing.
 package metricgen
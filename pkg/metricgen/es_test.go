@@ -0,0 +1,78 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build integration
+// +build integration
+
+package metricgen_test
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/apm-tools/pkg/espoll"
+)
+
+// newServiceName returns a service name unique to this test run,
+// so that metrics documents from previous runs are not matched.
+func newServiceName(prefix string) string {
+	return prefix + "_" + strconv.FormatInt(time.Now().UnixNano(), 36)
+}
+
+// newESClient returns an espoll.Client for the Elasticsearch cluster
+// that APM Server writes to, configured with the same environment
+// variables as apmtool.
+func newESClient(t testing.TB) *espoll.Client {
+	t.Helper()
+	client, err := espoll.NewClient(espoll.ClientConfig{
+		Addresses:     []string{os.Getenv("ELASTICSEARCH_URL")},
+		Username:      os.Getenv("ELASTICSEARCH_USERNAME"),
+		Password:      os.Getenv("ELASTICSEARCH_PASSWORD"),
+		APIKey:        os.Getenv("ELASTICSEARCH_API_KEY"),
+		TLSSkipVerify: true,
+	})
+	require.NoError(t, err)
+	return client
+}
+
+// assertMetricDocs asserts that metrics documents for serviceName
+// containing each of the given fields have been indexed, and that
+// the given counters have the expected values.
+func assertMetricDocs(t testing.TB, serviceName string, counters map[string]float64, fields ...string) {
+	t.Helper()
+	es := newESClient(t)
+	for _, field := range fields {
+		result, err := es.SearchIndexMinDocs(context.Background(), 1, "metrics-apm*",
+			espoll.BoolQuery{Filter: []any{
+				espoll.TermQuery{Field: "service.name", Value: serviceName},
+				espoll.ExistsQuery{Field: field},
+			}},
+			espoll.WithTimeout(time.Minute),
+		)
+		require.NoError(t, err, "no metrics documents with field %q", field)
+		require.NotEmpty(t, result.Hits.Hits)
+		if value, ok := counters[field]; ok {
+			assert.Equal(t, []any{value}, result.Hits.Hits[0].Fields[field], "field %q", field)
+		}
+	}
+}
@@ -32,7 +32,11 @@ import (
 //
 // Metrics sent are:
 // - apm(float64, value=1.0); gathered from a apm.MetricGatherer
-// - apmotel(float64, value=1.0); gathered from a otel MeterProvider through apmotel bridge
+// - apmotel(float64, value=1.0); gathered from a otel MeterProvider through apmotel bridge,
+// unless overridden by WithMetric
+// - apmotel_histogram(float64 histogram, values=1.0, 10.0, 100.0); if WithHistogram(true),
+// unless the values are overridden by WithHistogramValues
+// - apmotel_gauge(int64, value=1); if WithGauge(true)
 // All builtin APM Agent metrics have been disabled.
 func SendIntakeV2(_ context.Context, opts ...ConfigOption) (EventStats, error) {
 	cfg := newConfig(opts...)
@@ -64,10 +68,13 @@ func SendIntakeV2(_ context.Context, opts ...ConfigOption) (EventStats, error) {
 	d := tracer.RegisterMetricsGatherer(Gatherer{})
 	defer d()
 
+	// Exponential histograms are not supported by the apmotel bridge.
+	cfg.exponentialHistogram = false
+
 	meter := provider.Meter("metricgen")
-	counter, _ := meter.Float64Counter("apmotel")
-	counter.Add(context.Background(), 1)
-	stats.Add(1)
+	if err := generateMetrics(meter, "apmotel", cfg, &stats); err != nil {
+		return EventStats{}, fmt.Errorf("cannot generate metrics: %w", err)
+	}
 
 	tracer.SendMetrics(nil)
 	stats.Add(1)
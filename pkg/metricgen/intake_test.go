@@ -24,10 +24,12 @@ import (
 	"context"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/elastic/apm-tools/pkg/espoll"
 	"github.com/elastic/apm-tools/pkg/metricgen"
 )
 
@@ -47,3 +49,77 @@ func TestSendIntakeV2(t *testing.T) {
 	t.Logf("%+v\n", s)
 	assert.Equal(t, 2, s.MetricSent)
 }
+
+func TestSendIntakeV2_histogramAndGauge(t *testing.T) {
+	// generate these using tilt + apmtool agent-env
+	u := os.Getenv("ELASTIC_APM_SERVER_URL")
+	apiKey := os.Getenv("ELASTIC_APM_API_KEY")
+	serviceName := newServiceName("metricgen_apm_test")
+
+	s, err := metricgen.SendIntakeV2(context.Background(),
+		metricgen.WithAPMServerURL(u),
+		metricgen.WithAPIKey(apiKey),
+		metricgen.WithVerifyServerCert(false),
+		metricgen.WithElasticAPMServiceName(serviceName),
+		metricgen.WithHistogram(true),
+		metricgen.WithGauge(true),
+	)
+	require.NoError(t, err)
+
+	t.Logf("%+v\n", s)
+	assert.Equal(t, 4, s.MetricSent)
+	assertMetricDocs(t, serviceName,
+		map[string]float64{"apmotel": 1, "apmotel_gauge": 1},
+		"apmotel", "apmotel_histogram", "apmotel_gauge",
+	)
+}
+
+func TestSendIntakeV2_histogramValues(t *testing.T) {
+	// generate these using tilt + apmtool agent-env
+	u := os.Getenv("ELASTIC_APM_SERVER_URL")
+	apiKey := os.Getenv("ELASTIC_APM_API_KEY")
+	serviceName := newServiceName("metricgen_apm_test")
+
+	values := []float64{2, 2, 20, 200}
+	s, err := metricgen.SendIntakeV2(context.Background(),
+		metricgen.WithAPMServerURL(u),
+		metricgen.WithAPIKey(apiKey),
+		metricgen.WithVerifyServerCert(false),
+		metricgen.WithElasticAPMServiceName(serviceName),
+		metricgen.WithHistogram(true),
+		metricgen.WithHistogramValues(values...),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, 3, s.MetricSent)
+
+	result, err := newESClient(t).SearchIndexMinDocs(context.Background(), 1, "metrics-apm*",
+		espoll.BoolQuery{Filter: []any{
+			espoll.TermQuery{Field: "service.name", Value: serviceName},
+			espoll.ExistsQuery{Field: "apmotel_histogram"},
+		}},
+		espoll.WithTimeout(time.Minute),
+	)
+	require.NoError(t, err)
+	require.NotEmpty(t, result.Hits.Hits)
+
+	// The histogram is indexed with the midpoints of the non-empty
+	// buckets and their counts, which sum to the values recorded.
+	var source struct {
+		Histogram struct {
+			Values []float64 `json:"values"`
+			Counts []int     `json:"counts"`
+		} `json:"apmotel_histogram"`
+	}
+	require.NoError(t, result.Hits.Hits[0].UnmarshalSource(&source))
+	histogram := source.Histogram
+	require.Len(t, histogram.Counts, len(histogram.Values))
+	var total int
+	for _, count := range histogram.Counts {
+		total += count
+	}
+	assert.Equal(t, len(values), total)
+	for _, v := range histogram.Values {
+		assert.Greater(t, v, 0.0)
+		assert.LessOrEqual(t, v, 250.0)
+	}
+}
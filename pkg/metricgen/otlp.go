@@ -19,7 +19,6 @@ package metricgen
 
 import (
 	"context"
-	"crypto/tls"
 	"fmt"
 	"net"
 	"net/url"
@@ -29,10 +28,13 @@ import (
 	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
 	"go.opentelemetry.io/otel/metric"
 	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
 	"go.opentelemetry.io/otel/sdk/resource"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 	grpcinsecure "google.golang.org/grpc/credentials/insecure"
+
+	"github.com/elastic/apm-tools/internal/apmconn"
 )
 
 // SendOTLP sends specific metrics to the configured Elastic APM OTLP intake.
@@ -40,7 +42,12 @@ import (
 // Metrics are sent via the specified protocol.
 //
 // Metrics sent are:
-// - otlp(float64, value=1.0)
+// - otlp(float64, value=1.0); unless overridden by WithMetric
+// - otlp_histogram(float64 histogram, values=1.0, 10.0, 100.0); if WithHistogram(true),
+// unless the values are overridden by WithHistogramValues
+// - otlp_gauge(int64, value=1); if WithGauge(true)
+// - otlp_exponential_histogram(float64 exponential histogram, values=1.0, 10.0, 100.0); if WithExponentialHistogram(true),
+// unless the values are overridden by WithHistogramValues
 func SendOTLP(ctx context.Context, opts ...ConfigOption) (EventStats, error) {
 	cfg := newConfig(opts...)
 	if err := cfg.Validate(); err != nil {
@@ -67,16 +74,14 @@ func SendOTLP(ctx context.Context, opts ...ConfigOption) (EventStats, error) {
 	}
 	defer exporter.Shutdown(ctx)
 
-	resource := resource.NewSchemaless(
-		attribute.String("service.name", cfg.otlpServiceName),
-	)
 	mp := sdkmetric.NewMeterProvider(
 		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter)),
-		sdkmetric.WithResource(resource),
+		sdkmetric.WithResource(cfg.otlpResource()),
+		sdkmetric.WithView(exponentialHistogramView("otlp")),
 	)
 
 	stats := EventStats{}
-	if err := generateMetrics(mp.Meter("metricgen"), &stats); err != nil {
+	if err := generateMetrics(cfg.meter(mp), "otlp", cfg, &stats); err != nil {
 		return stats, fmt.Errorf("cannot generate metrics: %w", err)
 	}
 
@@ -87,21 +92,144 @@ func SendOTLP(ctx context.Context, opts ...ConfigOption) (EventStats, error) {
 	return stats, nil
 }
 
-func generateMetrics(m metric.Meter, stats *EventStats) error {
-	counter, _ := m.Float64Counter("otlp")
-	counter.Add(context.Background(), 1)
-	stats.Add(1)
+// generateMetrics records the metrics configured in cfg. If no counters
+// have been configured with WithMetric, a single counter named prefix is
+// recorded. Histogram and gauge metric names are prefixed by prefix.
+func generateMetrics(m metric.Meter, prefix string, cfg config, stats *EventStats) error {
+	counters := cfg.metrics
+	if len(counters) == 0 {
+		counters = []counterConfig{{name: prefix, value: 1}}
+	}
+	for _, c := range counters {
+		counter, err := m.Float64Counter(c.name)
+		if err != nil {
+			return fmt.Errorf("cannot create counter %q: %w", c.name, err)
+		}
+		attrs := make([]attribute.KeyValue, 0, len(c.attrs))
+		for k, v := range c.attrs {
+			attrs = append(attrs, attribute.String(k, v))
+		}
+		counter.Add(context.Background(), c.value, metric.WithAttributes(attrs...))
+		stats.Add(1)
+	}
+
+	if cfg.histogram {
+		histogram, err := m.Float64Histogram(prefix + "_histogram")
+		if err != nil {
+			return fmt.Errorf("cannot create histogram: %w", err)
+		}
+		for _, v := range cfg.recordedHistogramValues() {
+			histogram.Record(context.Background(), v)
+		}
+		stats.Add(1)
+	}
+
+	if cfg.exponentialHistogram {
+		// The histogram is aggregated with exponential
+		// buckets by the view from exponentialHistogramView.
+		histogram, err := m.Float64Histogram(prefix + exponentialHistogramSuffix)
+		if err != nil {
+			return fmt.Errorf("cannot create exponential histogram: %w", err)
+		}
+		for _, v := range cfg.recordedHistogramValues() {
+			histogram.Record(context.Background(), v)
+		}
+		stats.Add(1)
+	}
+
+	if cfg.gauge {
+		_, err := m.Int64ObservableGauge(prefix+"_gauge",
+			metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+				o.Observe(1)
+				return nil
+			}),
+		)
+		if err != nil {
+			return fmt.Errorf("cannot create gauge: %w", err)
+		}
+		stats.Add(1)
+	}
 
 	return nil
 }
 
+// otlpResource returns the resource of OTLP metrics, holding the
+// service name and the attributes specified by WithResourceAttributes.
+func (cfg config) otlpResource() *resource.Resource {
+	attrs := make([]attribute.KeyValue, 0, len(cfg.resourceAttributes)+1)
+	for k, v := range cfg.resourceAttributes {
+		if k == "service.name" {
+			continue
+		}
+		attrs = append(attrs, attribute.String(k, v))
+	}
+	attrs = append(attrs, attribute.String("service.name", cfg.otlpServiceName))
+	return resource.NewSchemaless(attrs...)
+}
+
+// meter returns a meter from mp with the
+// instrumentation scope specified by WithScope.
+func (cfg config) meter(mp metric.MeterProvider) metric.Meter {
+	name := cfg.scopeName
+	if name == "" {
+		name = "metricgen"
+	}
+	var opts []metric.MeterOption
+	if cfg.scopeVersion != "" {
+		opts = append(opts, metric.WithInstrumentationVersion(cfg.scopeVersion))
+	}
+	return mp.Meter(name, opts...)
+}
+
+// recordedHistogramValues returns the values to record
+// in histograms, as specified by WithHistogramValues.
+func (cfg config) recordedHistogramValues() []float64 {
+	if len(cfg.histogramValues) == 0 {
+		return []float64{1, 10, 100}
+	}
+	return cfg.histogramValues
+}
+
+// exponentialHistogramSuffix is appended to the metric name prefix
+// to name the exponential histogram recorded by generateMetrics.
+const exponentialHistogramSuffix = "_exponential_histogram"
+
+// exponentialHistogramView returns a view which aggregates the exponential
+// histogram recorded by generateMetrics for prefix with base-2 exponential
+// buckets, using the OpenTelemetry default maximum size and scale.
+func exponentialHistogramView(prefix string) sdkmetric.View {
+	return sdkmetric.NewView(
+		sdkmetric.Instrument{Name: prefix + exponentialHistogramSuffix},
+		sdkmetric.Stream{Aggregation: sdkmetric.AggregationBase2ExponentialHistogram{
+			MaxSize:  160,
+			MaxScale: 20,
+		}},
+	)
+}
+
+// temporalitySelector returns a sdkmetric.TemporalitySelector using t for
+// all instrument kinds except up-down counters, which are always cumulative
+// as they represent a current value rather than a rate of change.
+func temporalitySelector(t metricdata.Temporality) sdkmetric.TemporalitySelector {
+	return func(kind sdkmetric.InstrumentKind) metricdata.Temporality {
+		switch kind {
+		case sdkmetric.InstrumentKindUpDownCounter, sdkmetric.InstrumentKindObservableUpDownCounter:
+			return metricdata.CumulativeTemporality
+		}
+		return t
+	}
+}
+
 func newOTLPMetricHTTPExporter(ctx context.Context, cfg config) (*otlpmetrichttp.Exporter, error) {
 	endpoint, err := otlpEndpoint(cfg.apmServerURL)
 	if err != nil {
 		return nil, err
 	}
 
-	tlsConfig := &tls.Config{InsecureSkipVerify: !cfg.verifyServerCert}
+	tlsConfig, err := cfg.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
 	opts := []otlpmetrichttp.Option{
 		otlpmetrichttp.WithEndpoint(endpoint.Host),
 		otlpmetrichttp.WithTLSClientConfig(tlsConfig),
@@ -109,13 +237,29 @@ func newOTLPMetricHTTPExporter(ctx context.Context, cfg config) (*otlpmetrichttp
 	if endpoint.Scheme == "http" {
 		opts = append(opts, otlpmetrichttp.WithInsecure())
 	}
+	proxy, err := apmconn.ProxyFunc(cfg.proxyURL)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, otlpmetrichttp.WithProxy(proxy))
 
-	headers := map[string]string{"Authorization": "ApiKey " + cfg.apiKey}
-	opts = append(opts, otlpmetrichttp.WithHeaders(headers))
+	opts = append(opts, otlpmetrichttp.WithHeaders(otlpHeaders(cfg)))
+	if cfg.temporality != 0 {
+		opts = append(opts, otlpmetrichttp.WithTemporalitySelector(temporalitySelector(cfg.temporality)))
+	}
 
 	return otlpmetrichttp.New(ctx, opts...)
 }
 
+// otlpHeaders returns the headers to send with OTLP requests,
+// authorizing with either the secret token or API key in cfg.
+func otlpHeaders(cfg config) map[string]string {
+	if cfg.secretToken != "" {
+		return map[string]string{"Authorization": "Bearer " + cfg.secretToken}
+	}
+	return map[string]string{"Authorization": "ApiKey " + cfg.apiKey}
+}
+
 func otlpEndpoint(s string) (*url.URL, error) {
 	u, err := url.Parse(s)
 	if err != nil {
@@ -150,22 +294,26 @@ func newOTLPMetricGRPCExporter(ctx context.Context, cfg config) (*otlpmetricgrpc
 		// If http:// is specified, then use insecure (plaintext).
 		transportCredentials = grpcinsecure.NewCredentials()
 	case "https":
-		transportCredentials = credentials.NewTLS(&tls.Config{InsecureSkipVerify: !cfg.verifyServerCert})
+		tlsConfig, err := cfg.tlsConfig()
+		if err != nil {
+			return nil, func() {}, err
+		}
+		transportCredentials = credentials.NewTLS(tlsConfig)
 	}
 
-	grpcConn, err := grpc.NewClient(
-		endpoint.Host,
-		grpc.WithTransportCredentials(transportCredentials),
-		grpc.WithDefaultCallOptions(grpc.UseCompressor("gzip")),
-	)
-	cleanup := func() { grpcConn.Close() }
+	// grpc.NewClient does not perform any I/O; the connection is
+	// established lazily on the first export, and closed by cleanup.
+	grpcConn, err := grpc.NewClient(endpoint.Host, cfg.grpcOptions().DialOptions(transportCredentials)...)
 	if err != nil {
-		return nil, cleanup, fmt.Errorf("cannot create grpc dial context: %w", err)
+		return nil, func() {}, fmt.Errorf("cannot create grpc client: %w", err)
 	}
+	cleanup := func() { grpcConn.Close() }
 
 	opts := []otlpmetricgrpc.Option{otlpmetricgrpc.WithGRPCConn(grpcConn)}
-	headers := map[string]string{"Authorization": "ApiKey " + cfg.apiKey}
-	opts = append(opts, otlpmetricgrpc.WithHeaders(headers))
+	opts = append(opts, otlpmetricgrpc.WithHeaders(otlpHeaders(cfg)))
+	if cfg.temporality != 0 {
+		opts = append(opts, otlpmetricgrpc.WithTemporalitySelector(temporalitySelector(cfg.temporality)))
+	}
 
 	e, err := otlpmetricgrpc.New(ctx, opts...)
 	return e, cleanup, err
@@ -0,0 +1,332 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package metricgen
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/elastic/apm-tools/internal/apmconn"
+)
+
+func TestGenerateMetricsWithMetric(t *testing.T) {
+	cfg := newConfig(
+		WithMetric("first", 1, map[string]string{"a": "1"}),
+		WithMetric("second", 2, map[string]string{"b": "2"}),
+		WithMetric("third", 3, nil),
+	)
+	rm, stats := generateTestMetrics(t, cfg)
+	assert.Equal(t, 3, stats.MetricSent)
+
+	require.Len(t, rm.ScopeMetrics, 1)
+	metrics := rm.ScopeMetrics[0].Metrics
+	require.Len(t, metrics, 3)
+	for i, expected := range []struct {
+		name  string
+		value float64
+		attrs attribute.Set
+	}{
+		{name: "first", value: 1, attrs: attribute.NewSet(attribute.String("a", "1"))},
+		{name: "second", value: 2, attrs: attribute.NewSet(attribute.String("b", "2"))},
+		{name: "third", value: 3, attrs: attribute.NewSet()},
+	} {
+		assert.Equal(t, expected.name, metrics[i].Name)
+		sum, ok := metrics[i].Data.(metricdata.Sum[float64])
+		require.True(t, ok)
+		require.Len(t, sum.DataPoints, 1)
+		assert.Equal(t, expected.value, sum.DataPoints[0].Value)
+		assert.True(t, expected.attrs.Equals(&sum.DataPoints[0].Attributes))
+	}
+}
+
+func TestGenerateMetricsDefault(t *testing.T) {
+	rm, stats := generateTestMetrics(t, newConfig())
+	assert.Equal(t, 1, stats.MetricSent)
+
+	require.Len(t, rm.ScopeMetrics, 1)
+	metrics := rm.ScopeMetrics[0].Metrics
+	require.Len(t, metrics, 1)
+	assert.Equal(t, "otlp", metrics[0].Name)
+}
+
+func TestGenerateMetricsExponentialHistogram(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(reader),
+		sdkmetric.WithView(exponentialHistogramView("otlp")),
+	)
+	defer mp.Shutdown(context.Background())
+
+	var stats EventStats
+	cfg := newConfig(WithExponentialHistogram(true))
+	require.NoError(t, generateMetrics(mp.Meter("metricgen"), "otlp", cfg, &stats))
+	assert.Equal(t, 2, stats.MetricSent)
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	require.Len(t, rm.ScopeMetrics, 1)
+	metrics := rm.ScopeMetrics[0].Metrics
+	require.Len(t, metrics, 2)
+	assert.Equal(t, "otlp_exponential_histogram", metrics[1].Name)
+	histogram, ok := metrics[1].Data.(metricdata.ExponentialHistogram[float64])
+	require.True(t, ok, "expected exponential histogram, got %T", metrics[1].Data)
+	require.Len(t, histogram.DataPoints, 1)
+	dp := histogram.DataPoints[0]
+	assert.Equal(t, uint64(3), dp.Count)
+	assert.Equal(t, float64(111), dp.Sum)
+	// The scale is the highest at which values 1 to 100
+	// fit within the maximum of 160 buckets.
+	assert.Equal(t, int32(4), dp.Scale)
+}
+
+func TestGenerateMetricsHistogramValues(t *testing.T) {
+	rm, stats := generateTestMetrics(t, newConfig(
+		WithHistogram(true),
+		WithHistogramValues(2, 2, 20, 200),
+	))
+	assert.Equal(t, 2, stats.MetricSent)
+
+	require.Len(t, rm.ScopeMetrics, 1)
+	metrics := rm.ScopeMetrics[0].Metrics
+	require.Len(t, metrics, 2)
+	assert.Equal(t, "otlp_histogram", metrics[1].Name)
+	histogram, ok := metrics[1].Data.(metricdata.Histogram[float64])
+	require.True(t, ok, "expected histogram, got %T", metrics[1].Data)
+	require.Len(t, histogram.DataPoints, 1)
+	dp := histogram.DataPoints[0]
+	assert.Equal(t, uint64(4), dp.Count)
+	assert.Equal(t, float64(224), dp.Sum)
+
+	// The values are recorded in the OpenTelemetry default buckets:
+	// 2 twice in (0, 5], 20 in (10, 25], and 200 in (100, 250].
+	counts := make(map[float64]uint64)
+	for i, count := range dp.BucketCounts {
+		if count > 0 {
+			counts[dp.Bounds[i]] = count
+		}
+	}
+	assert.Equal(t, map[float64]uint64{5: 2, 25: 1, 250: 1}, counts)
+}
+
+func TestGenerateMetricsResourceAndScope(t *testing.T) {
+	rm, _ := generateTestMetrics(t, newConfig(
+		WithOTLPServiceName("svc"),
+		WithResourceAttributes(map[string]string{
+			"deployment.environment": "test",
+			"service.name":           "ignored",
+		}),
+		WithScope("custom", "1.2.3"),
+	))
+
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String("deployment.environment", "test"),
+		attribute.String("service.name", "svc"),
+	}, rm.Resource.Attributes())
+
+	require.Len(t, rm.ScopeMetrics, 1)
+	assert.Equal(t, "custom", rm.ScopeMetrics[0].Scope.Name)
+	assert.Equal(t, "1.2.3", rm.ScopeMetrics[0].Scope.Version)
+}
+
+func TestGenerateMetricsDefaultScope(t *testing.T) {
+	rm, _ := generateTestMetrics(t, newConfig(WithOTLPServiceName("svc")))
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String("service.name", "svc"),
+	}, rm.Resource.Attributes())
+	require.Len(t, rm.ScopeMetrics, 1)
+	assert.Equal(t, "metricgen", rm.ScopeMetrics[0].Scope.Name)
+	assert.Empty(t, rm.ScopeMetrics[0].Scope.Version)
+}
+
+func TestTemporalityDelta(t *testing.T) {
+	cfg := newConfig(
+		WithAPMServerURL("http://localhost:8200"),
+		WithTemporality(metricdata.DeltaTemporality),
+		WithHistogram(true),
+	)
+	exporter, err := newOTLPMetricHTTPExporter(context.Background(), cfg)
+	require.NoError(t, err)
+	defer exporter.Shutdown(context.Background())
+	assert.Equal(t, metricdata.DeltaTemporality, exporter.Temporality(sdkmetric.InstrumentKindCounter))
+	assert.Equal(t, metricdata.DeltaTemporality, exporter.Temporality(sdkmetric.InstrumentKindHistogram))
+	assert.Equal(t, metricdata.CumulativeTemporality, exporter.Temporality(sdkmetric.InstrumentKindUpDownCounter))
+
+	rm, _ := generateTestMetrics(t, cfg, sdkmetric.WithTemporalitySelector(exporter.Temporality))
+	require.Len(t, rm.ScopeMetrics, 1)
+	metrics := rm.ScopeMetrics[0].Metrics
+	require.Len(t, metrics, 2)
+	sum, ok := metrics[0].Data.(metricdata.Sum[float64])
+	require.True(t, ok)
+	assert.Equal(t, metricdata.DeltaTemporality, sum.Temporality)
+	histogram, ok := metrics[1].Data.(metricdata.Histogram[float64])
+	require.True(t, ok)
+	assert.Equal(t, metricdata.DeltaTemporality, histogram.Temporality)
+}
+
+func TestTemporalityDefault(t *testing.T) {
+	exporter, err := newOTLPMetricHTTPExporter(context.Background(), newConfig(
+		WithAPMServerURL("http://localhost:8200"),
+	))
+	require.NoError(t, err)
+	defer exporter.Shutdown(context.Background())
+	assert.Equal(t, metricdata.CumulativeTemporality, exporter.Temporality(sdkmetric.InstrumentKindCounter))
+	assert.Equal(t, metricdata.CumulativeTemporality, exporter.Temporality(sdkmetric.InstrumentKindHistogram))
+}
+
+func TestNewOTLPMetricGRPCExporterInsecure(t *testing.T) {
+	// Nothing is listening on the endpoint; construction
+	// must succeed without establishing a connection.
+	exporter, cleanup, err := newOTLPMetricGRPCExporter(context.Background(), newConfig(
+		WithAPMServerURL("http://127.0.0.1:1"),
+		WithAPIKey("key"),
+	))
+	require.NoError(t, err)
+	require.NotNil(t, exporter)
+	assert.NoError(t, exporter.Shutdown(context.Background()))
+	cleanup()
+}
+
+func TestOTLPMetricHTTPExporterProxy(t *testing.T) {
+	requests := make(chan string, 1)
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- r.URL.String()
+		w.Header().Set("Content-Type", "application/x-protobuf")
+	}))
+	defer proxy.Close()
+
+	exporter, err := newOTLPMetricHTTPExporter(context.Background(), newConfig(
+		WithAPMServerURL("http://apm.invalid:8200"),
+		WithAPIKey("key"),
+		WithProxyURL(proxy.URL),
+	))
+	require.NoError(t, err)
+	defer exporter.Shutdown(context.Background())
+
+	rm, _ := generateTestMetrics(t, newConfig())
+	require.NoError(t, exporter.Export(context.Background(), &rm))
+	assert.Equal(t, "http://apm.invalid:8200/v1/metrics", <-requests)
+}
+
+func TestValidateProxyURL(t *testing.T) {
+	cfg := newConfig(
+		WithAPMServerURL("http://localhost:8200"),
+		WithOTLPServiceName("metricgen"),
+		WithAPIKey("key"),
+		WithProxyURL("ftp://proxy.invalid"),
+	)
+	assert.EqualError(t, cfg.Validate(), `invalid proxy URL "ftp://proxy.invalid": must be prefixed with http://, https:// or socks5://`)
+}
+
+func TestGRPCOptions(t *testing.T) {
+	cfg := newConfig(WithGRPCKeepalive(30*time.Second, 5*time.Second), WithDialTimeout(3*time.Second))
+	assert.Equal(t, apmconn.GRPCOptions{
+		KeepaliveTime:    30 * time.Second,
+		KeepaliveTimeout: 5 * time.Second,
+		DialTimeout:      3 * time.Second,
+	}, cfg.grpcOptions())
+	assert.Equal(t, apmconn.GRPCOptions{}, newConfig().grpcOptions())
+}
+
+func TestOTLPHeaders(t *testing.T) {
+	for name, tc := range map[string]struct {
+		opts     []ConfigOption
+		expected string
+	}{
+		"api_key":      {opts: []ConfigOption{WithAPIKey("key")}, expected: "ApiKey key"},
+		"secret_token": {opts: []ConfigOption{WithSecretToken("token")}, expected: "Bearer token"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg := newConfig(append(tc.opts,
+				WithAPMServerURL("http://localhost:8200"),
+				WithOTLPServiceName("metricgen"),
+			)...)
+			require.NoError(t, cfg.Validate())
+			assert.Equal(t, map[string]string{"Authorization": tc.expected}, otlpHeaders(cfg))
+		})
+	}
+}
+
+func TestValidateAuth(t *testing.T) {
+	cfg := newConfig(
+		WithAPMServerURL("http://localhost:8200"),
+		WithOTLPServiceName("metricgen"),
+		WithAPIKey("key"),
+		WithSecretToken("token"),
+	)
+	assert.EqualError(t, cfg.Validate(), "API Key and secret token cannot both be set")
+
+	cfg = newConfig(
+		WithAPMServerURL("http://localhost:8200"),
+		WithOTLPServiceName("metricgen"),
+	)
+	assert.EqualError(t, cfg.Validate(), "API Key and secret token cannot both be empty")
+}
+
+func TestValidateSentinelErrors(t *testing.T) {
+	err := newConfig(WithOTLPServiceName("metricgen")).Validate()
+	assert.ErrorIs(t, err, ErrMissingServerURL)
+	assert.ErrorIs(t, err, ErrMissingAPIKey)
+	assert.NotErrorIs(t, err, ErrInvalidOTLPProtocol)
+
+	err = newConfig(
+		WithAPMServerURL("http://localhost:8200"),
+		WithOTLPServiceName("metricgen"),
+		WithAPIKey("key"),
+		WithOTLPProtocol("http/json"),
+	).Validate()
+	assert.ErrorIs(t, err, ErrInvalidOTLPProtocol)
+	assert.EqualError(t, err, "unknown otlp protocol: http/json")
+}
+
+func TestTLSConfig(t *testing.T) {
+	tlsConfig, err := newConfig(WithVerifyServerCert(false)).tlsConfig()
+	require.NoError(t, err)
+	assert.True(t, tlsConfig.InsecureSkipVerify)
+
+	_, err = newConfig(WithClientCert("cert.pem", "missing.pem")).tlsConfig()
+	assert.ErrorContains(t, err, "failed to load client certificate")
+	_, err = newConfig(WithCACert("missing.pem")).tlsConfig()
+	assert.ErrorContains(t, err, "failed to read CA certificate")
+}
+
+// generateTestMetrics calls generateMetrics with cfg, returning
+// the collected metrics along with the stats.
+func generateTestMetrics(t testing.TB, cfg config, opts ...sdkmetric.ManualReaderOption) (metricdata.ResourceMetrics, EventStats) {
+	reader := sdkmetric.NewManualReader(opts...)
+	mp := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(reader),
+		sdkmetric.WithResource(cfg.otlpResource()),
+	)
+	defer mp.Shutdown(context.Background())
+
+	var stats EventStats
+	require.NoError(t, generateMetrics(cfg.meter(mp), "otlp", cfg, &stats))
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	return rm, stats
+}
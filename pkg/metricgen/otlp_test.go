@@ -66,3 +66,63 @@ func TestSendOTLP_grpc(t *testing.T) {
 	t.Logf("%+v\n", s)
 	assert.Equal(t, 1, s.MetricSent)
 }
+
+func TestSendOTLP_histogramAndGauge(t *testing.T) {
+	// generate these using tilt + apmtool agent-env
+	u := os.Getenv("ELASTIC_APM_SERVER_URL")
+	apiKey := os.Getenv("ELASTIC_APM_API_KEY")
+
+	for _, protocol := range []string{"http/protobuf", "grpc"} {
+		t.Run(protocol, func(t *testing.T) {
+			serviceName := newServiceName("metricgen_otlp_test")
+			s, err := metricgen.SendOTLP(context.Background(),
+				metricgen.WithAPMServerURL(u),
+				metricgen.WithAPIKey(apiKey),
+				metricgen.WithVerifyServerCert(false),
+				metricgen.WithOTLPServiceName(serviceName),
+				metricgen.WithOTLPProtocol(protocol),
+				metricgen.WithHistogram(true),
+				metricgen.WithGauge(true),
+			)
+			require.NoError(t, err)
+
+			t.Logf("%+v\n", s)
+			assert.Equal(t, 3, s.MetricSent)
+			assertMetricDocs(t, serviceName,
+				map[string]float64{"otlp": 1, "otlp_gauge": 1},
+				"otlp", "otlp_histogram", "otlp_gauge",
+			)
+		})
+	}
+}
+
+func TestSendOTLP_exponentialHistogram(t *testing.T) {
+	// generate these using tilt + apmtool agent-env
+	u := os.Getenv("ELASTIC_APM_SERVER_URL")
+	apiKey := os.Getenv("ELASTIC_APM_API_KEY")
+
+	for _, protocol := range []string{"http/protobuf", "grpc"} {
+		t.Run(protocol, func(t *testing.T) {
+			serviceName := newServiceName("metricgen_otlp_test")
+			s, err := metricgen.SendOTLP(context.Background(),
+				metricgen.WithAPMServerURL(u),
+				metricgen.WithAPIKey(apiKey),
+				metricgen.WithVerifyServerCert(false),
+				metricgen.WithOTLPServiceName(serviceName),
+				metricgen.WithOTLPProtocol(protocol),
+				metricgen.WithExponentialHistogram(true),
+			)
+			require.NoError(t, err)
+
+			t.Logf("%+v\n", s)
+			assert.Equal(t, 2, s.MetricSent)
+			// APM Server converts exponential histograms to the
+			// histogram field type, which does not record the scale;
+			// see TestGenerateMetricsExponentialHistogram.
+			assertMetricDocs(t, serviceName,
+				map[string]float64{"otlp": 1},
+				"otlp", "otlp_exponential_histogram",
+			)
+		})
+	}
+}
@@ -0,0 +1,238 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package metricgen
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/apm-tools/internal/apmconn"
+)
+
+// prometheusContentType is the content type of the
+// Prometheus text exposition format.
+const prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// prometheusBuckets holds the upper bounds of the histogram buckets,
+// matching the OpenTelemetry SDK's default explicit bucket boundaries.
+var prometheusBuckets = []float64{0, 5, 10, 25, 50, 75, 100, 250, 500, 750, 1000, 2500, 5000, 7500, 10000}
+
+// SendPrometheus pushes specific metrics in the Prometheus text exposition
+// format with a POST request to the URL given by WithAPMServerURL, which is
+// used as is, including its path. APM Server does not accept this format
+// itself, so the URL must be that of a push endpoint such as a Prometheus
+// Pushgateway, e.g. http://localhost:9091/metrics/job/<job>, from which
+// the metrics are scraped. See PrometheusHandler for serving the same
+// metrics for scraping directly.
+//
+// Each metric is labeled with job set to the OTLP service name, or the
+// Elastic APM service name if that is unset. Counters configured with
+// WithMetric that share a name are written as one metric family.
+//
+// Metrics sent are:
+// - prometheus(counter, value=1.0); unless overridden by WithMetric
+// - prometheus_histogram(histogram, values=1.0, 10.0, 100.0); if WithHistogram(true),
+// unless the values are overridden by WithHistogramValues
+// - prometheus_gauge(gauge, value=1); if WithGauge(true)
+func SendPrometheus(ctx context.Context, opts ...ConfigOption) (EventStats, error) {
+	cfg := newConfig(opts...)
+	if err := cfg.Validate(); err != nil {
+		return EventStats{}, fmt.Errorf("cannot validate Prometheus metrics configuration: %w", err)
+	}
+	tlsConfig, err := cfg.tlsConfig()
+	if err != nil {
+		return EventStats{}, err
+	}
+	proxy, err := apmconn.ProxyFunc(cfg.proxyURL)
+	if err != nil {
+		return EventStats{}, err
+	}
+
+	var buf bytes.Buffer
+	stats := writePrometheusMetrics(&buf, cfg)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.apmServerURL, &buf)
+	if err != nil {
+		return EventStats{}, fmt.Errorf("cannot create request: %w", err)
+	}
+	req.Header.Set("Content-Type", prometheusContentType)
+	for k, v := range otlpHeaders(cfg) {
+		req.Header.Set(k, v)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	transport.Proxy = proxy
+	resp, err := (&http.Client{Transport: transport}).Do(req)
+	if err != nil {
+		return EventStats{}, fmt.Errorf("cannot push metrics: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return EventStats{}, fmt.Errorf("cannot push metrics: server responded with %q: %s", resp.Status, body)
+	}
+	return stats, nil
+}
+
+// PrometheusHandler returns an http.Handler which serves the metrics
+// sent by SendPrometheus in the Prometheus text exposition format, for
+// testing scraping of the metrics. The configured URL and credentials
+// are not used.
+func PrometheusHandler(opts ...ConfigOption) http.Handler {
+	cfg := newConfig(opts...)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		writePrometheusMetrics(&buf, cfg)
+		w.Header().Set("Content-Type", prometheusContentType)
+		w.Write(buf.Bytes())
+	})
+}
+
+// writePrometheusMetrics writes the metrics configured in cfg to w in
+// the Prometheus text exposition format, returning the number written.
+func writePrometheusMetrics(w *bytes.Buffer, cfg config) EventStats {
+	var stats EventStats
+	job := cfg.otlpServiceName
+	if job == "" {
+		job = cfg.apmServiceName
+	}
+	jobLabels := map[string]string{"job": job}
+
+	counters := cfg.metrics
+	if len(counters) == 0 {
+		counters = []counterConfig{{name: "prometheus", value: 1}}
+	}
+	// Each metric family may only be described once, so group
+	// the samples by name, in the order the names first appear.
+	var names []string
+	families := make(map[string][]counterConfig)
+	for _, c := range counters {
+		name := prometheusName(c.name)
+		if _, ok := families[name]; !ok {
+			names = append(names, name)
+		}
+		families[name] = append(families[name], c)
+	}
+	for _, name := range names {
+		writePrometheusFamily(w, name, "counter")
+		for _, c := range families[name] {
+			labels := make(map[string]string, len(c.attrs)+1)
+			for k, v := range c.attrs {
+				labels[prometheusName(k)] = v
+			}
+			labels["job"] = job
+			writePrometheusSample(w, name, labels, c.value)
+			stats.Add(1)
+		}
+	}
+
+	if cfg.histogram {
+		const name = "prometheus_histogram"
+		values := cfg.recordedHistogramValues()
+		writePrometheusFamily(w, name, "histogram")
+		var sum float64
+		for _, v := range values {
+			sum += v
+		}
+		for _, bound := range prometheusBuckets {
+			var count int
+			for _, v := range values {
+				if v <= bound {
+					count++
+				}
+			}
+			writePrometheusSample(w, name+"_bucket",
+				map[string]string{"job": job, "le": formatPrometheusValue(bound)},
+				float64(count),
+			)
+		}
+		writePrometheusSample(w, name+"_bucket",
+			map[string]string{"job": job, "le": "+Inf"}, float64(len(values)),
+		)
+		writePrometheusSample(w, name+"_sum", jobLabels, sum)
+		writePrometheusSample(w, name+"_count", jobLabels, float64(len(values)))
+		stats.Add(1)
+	}
+
+	if cfg.gauge {
+		const name = "prometheus_gauge"
+		writePrometheusFamily(w, name, "gauge")
+		writePrometheusSample(w, name, jobLabels, 1)
+		stats.Add(1)
+	}
+	return stats
+}
+
+func writePrometheusFamily(w *bytes.Buffer, name, metricType string) {
+	fmt.Fprintf(w, "# HELP %s Generated by metricgen.\n", name)
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, metricType)
+}
+
+// writePrometheusSample writes a sample line, with labels sorted by name.
+func writePrometheusSample(w *bytes.Buffer, name string, labels map[string]string, value float64) {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	w.WriteString(name)
+	if len(keys) > 0 {
+		w.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, "%s=\"%s\"", k, prometheusLabelEscaper.Replace(labels[k]))
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatPrometheusValue(value))
+	w.WriteByte('\n')
+}
+
+var prometheusLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatPrometheusValue(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// prometheusName returns name with any characters not valid in
+// Prometheus metric and label names, such as dots, replaced by
+// underscores.
+func prometheusName(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		switch {
+		case r == '_', r == ':',
+			r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z',
+			r >= '0' && r <= '9' && i > 0:
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,162 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package metricgen
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSendPrometheus(t *testing.T) {
+	var body, contentType, authorization string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		body = string(b)
+		contentType = r.Header.Get("Content-Type")
+		authorization = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	stats, err := SendPrometheus(context.Background(),
+		WithAPMServerURL(srv.URL),
+		WithSecretToken("abc"),
+		WithOTLPServiceName("svc"),
+		WithMetric("http.requests", 5, map[string]string{"method": "GET", "path": `/"a"`}),
+		WithHistogram(true),
+		WithGauge(true),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, 3, stats.MetricSent)
+	assert.Equal(t, prometheusContentType, contentType)
+	assert.Equal(t, "Bearer abc", authorization)
+
+	families, samples := parsePrometheusText(t, body)
+	assert.Equal(t, map[string]string{
+		"http_requests":        "counter",
+		"prometheus_histogram": "histogram",
+		"prometheus_gauge":     "gauge",
+	}, families)
+	assert.Contains(t, samples, `http_requests{job="svc",method="GET",path="/\"a\""} 5`)
+	assert.Contains(t, samples, `prometheus_histogram_bucket{job="svc",le="0"} 0`)
+	assert.Contains(t, samples, `prometheus_histogram_bucket{job="svc",le="10"} 2`)
+	assert.Contains(t, samples, `prometheus_histogram_bucket{job="svc",le="+Inf"} 3`)
+	assert.Contains(t, samples, `prometheus_histogram_sum{job="svc"} 111`)
+	assert.Contains(t, samples, `prometheus_histogram_count{job="svc"} 3`)
+	assert.Contains(t, samples, `prometheus_gauge{job="svc"} 1`)
+}
+
+func TestPrometheusHandlerSharedName(t *testing.T) {
+	srv := httptest.NewServer(PrometheusHandler(
+		WithElasticAPMServiceName("svc"),
+		WithMetric("requests", 1, map[string]string{"method": "GET"}),
+		WithMetric("errors", 3, nil),
+		WithMetric("requests", 2, map[string]string{"method": "POST"}),
+	))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	families, samples := parsePrometheusText(t, string(b))
+	assert.Equal(t, map[string]string{"requests": "counter", "errors": "counter"}, families)
+	assert.Equal(t, []string{
+		`requests{job="svc",method="GET"} 1`,
+		`requests{job="svc",method="POST"} 2`,
+		`errors{job="svc"} 3`,
+	}, samples)
+}
+
+func TestSendPrometheusError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	_, err := SendPrometheus(context.Background(),
+		WithAPMServerURL(srv.URL),
+		WithAPIKey("abc"),
+		WithElasticAPMServiceName("svc"),
+	)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "401 Unauthorized")
+
+	_, err = SendPrometheus(context.Background(), WithAPIKey("abc"))
+	assert.ErrorIs(t, err, ErrMissingServerURL)
+}
+
+func TestPrometheusHandler(t *testing.T) {
+	srv := httptest.NewServer(PrometheusHandler(WithElasticAPMServiceName("svc")))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, prometheusContentType, resp.Header.Get("Content-Type"))
+	b, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	families, samples := parsePrometheusText(t, string(b))
+	assert.Equal(t, map[string]string{"prometheus": "counter"}, families)
+	assert.Equal(t, []string{`prometheus{job="svc"} 1`}, samples)
+}
+
+var prometheusSampleRegexp = regexp.MustCompile(
+	`^([a-zA-Z_:][a-zA-Z0-9_:]*)(\{[a-zA-Z_][a-zA-Z0-9_]*="(?:[^"\\\n]|\\.)*"(?:,[a-zA-Z_][a-zA-Z0-9_]*="(?:[^"\\\n]|\\.)*")*\})? (\S+)$`,
+)
+
+// parsePrometheusText checks the syntax of a Prometheus text exposition,
+// returning the types of its metric families and its sample lines.
+func parsePrometheusText(t testing.TB, text string) (map[string]string, []string) {
+	t.Helper()
+	families := make(map[string]string)
+	var samples []string
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "# TYPE "):
+			fields := strings.Fields(line)
+			require.Len(t, fields, 4, line)
+			require.NotContains(t, families, fields[2], "duplicate TYPE line %q", line)
+			families[fields[2]] = fields[3]
+		case strings.HasPrefix(line, "# HELP "):
+		default:
+			m := prometheusSampleRegexp.FindStringSubmatch(line)
+			require.NotNil(t, m, "invalid sample line %q", line)
+			family := strings.TrimSuffix(strings.TrimSuffix(strings.TrimSuffix(m[1], "_bucket"), "_sum"), "_count")
+			require.Contains(t, families, family, "sample %q precedes TYPE line", line)
+			samples = append(samples, line)
+		}
+	}
+	require.NoError(t, scanner.Err())
+	return families, samples
+}
@@ -0,0 +1,138 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tracegen
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// BenchmarkResult holds the results of Benchmark.
+type BenchmarkResult struct {
+	// Stats holds the aggregated stats of all traces sent successfully.
+	Stats EventStats
+
+	// Requests holds the number of traces sent, including failures.
+	Requests int
+
+	// Errors holds the number of traces that failed to send.
+	Errors int
+
+	// Elapsed holds the time taken to run the benchmark.
+	Elapsed time.Duration
+
+	// LatencyP50 and LatencyP95 hold the median and 95th percentile
+	// time taken to send a trace.
+	LatencyP50 time.Duration
+	LatencyP95 time.Duration
+}
+
+// Events returns the total number of events sent.
+func (r BenchmarkResult) Events() int {
+	return r.Stats.SpansSent + r.Stats.ExceptionsSent + r.Stats.LogsSent
+}
+
+// EventsPerSecond returns the rate at which events were sent.
+func (r BenchmarkResult) EventsPerSecond() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Events()) / r.Elapsed.Seconds()
+}
+
+// Benchmark sends traces with send from concurrency workers, each
+// sending its next trace as soon as the previous one completes, until
+// the duration specified by WithDuration elapses or ctx is cancelled.
+// As with GenerateContinuous, each trace is sent with a new random
+// trace ID unless one is specified with WithTraceID or WithTraceparent.
+//
+// Failures to send a trace are counted in the result, rather than
+// stopping the benchmark. The rate specified by WithRate is ignored.
+func Benchmark(
+	ctx context.Context, cfg Config, concurrency int,
+	send func(context.Context, Config) (EventStats, error),
+) (BenchmarkResult, error) {
+	if err := cfg.validate(); err != nil {
+		return BenchmarkResult{}, err
+	}
+	if concurrency <= 0 {
+		return BenchmarkResult{}, fmt.Errorf("invalid concurrency %d provided. must be > 0", concurrency)
+	}
+	if cfg.duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.duration)
+		defer cancel()
+	}
+
+	var mu sync.Mutex
+	var result BenchmarkResult
+	var latencies []time.Duration
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func(cfg Config) {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				if !cfg.fixedTraceID {
+					cfg.traceID = NewRandomTraceID()
+				}
+				sendStart := time.Now()
+				traceStats, err := send(ctx, cfg)
+				latency := time.Since(sendStart)
+				if err != nil && ctx.Err() != nil {
+					// Interrupted by cancellation or the duration elapsing.
+					return
+				}
+				mu.Lock()
+				result.Requests++
+				if err != nil {
+					result.Errors++
+				} else {
+					result.Stats = result.Stats.Add(traceStats)
+				}
+				latencies = append(latencies, latency)
+				mu.Unlock()
+			}
+		}(cfg)
+	}
+	wg.Wait()
+	result.Elapsed = time.Since(start)
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	result.LatencyP50 = percentile(latencies, 50)
+	result.LatencyP95 = percentile(latencies, 95)
+	return result, nil
+}
+
+// percentile returns the p-th percentile of the sorted durations,
+// using the nearest-rank method, or zero if there are none.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,108 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tracegen
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBenchmark(t *testing.T) {
+	// A fake intake endpoint, accepting all events.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	// NewConfig sets these from, and in, the environment.
+	t.Setenv("ELASTIC_APM_SERVER_URL", "")
+	t.Setenv("ELASTIC_APM_API_KEY", "")
+	cfg := NewConfig(
+		WithAPMServerURL(srv.URL),
+		WithAPIKey("abc123"),
+		WithElasticAPMServiceName("benchmark"),
+		WithDuration(time.Second),
+	)
+	result, err := Benchmark(context.Background(), cfg, 2,
+		func(ctx context.Context, cfg Config) (EventStats, error) {
+			_, stats, err := SendIntakeV2Trace(ctx, cfg)
+			return stats, err
+		},
+	)
+	require.NoError(t, err)
+	assert.Zero(t, result.Errors)
+	assert.Positive(t, result.Requests)
+	assert.Positive(t, result.EventsPerSecond())
+	assert.InDelta(t, time.Second, result.Elapsed, float64(500*time.Millisecond))
+	assert.Positive(t, result.LatencyP50)
+	assert.GreaterOrEqual(t, result.LatencyP95, result.LatencyP50)
+}
+
+func TestBenchmarkErrors(t *testing.T) {
+	cfg := NewConfig(
+		WithAPMServerURL("http://localhost:8200"),
+		WithAPIKey("abc123"),
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var calls int
+	result, err := Benchmark(ctx, cfg, 1,
+		func(ctx context.Context, cfg Config) (EventStats, error) {
+			calls++
+			if calls%2 == 0 {
+				return EventStats{}, errors.New("boom")
+			}
+			if calls == 9 {
+				cancel()
+			}
+			return EventStats{SpansSent: 2, LogsSent: 1}, nil
+		},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, 9, result.Requests)
+	assert.Equal(t, 4, result.Errors)
+	assert.Equal(t, 15, result.Events())
+}
+
+func TestBenchmarkInvalidConcurrency(t *testing.T) {
+	cfg := NewConfig(
+		WithAPMServerURL("http://localhost:8200"),
+		WithAPIKey("abc123"),
+	)
+	_, err := Benchmark(context.Background(), cfg, 0, nil)
+	assert.EqualError(t, err, "invalid concurrency 0 provided. must be > 0")
+}
+
+func TestPercentile(t *testing.T) {
+	var sorted []time.Duration
+	for i := 1; i <= 100; i++ {
+		sorted = append(sorted, time.Duration(i))
+	}
+	assert.Equal(t, time.Duration(50), percentile(sorted, 50))
+	assert.Equal(t, time.Duration(95), percentile(sorted, 95))
+	assert.Equal(t, time.Duration(100), percentile(sorted, 100))
+	assert.Equal(t, time.Duration(1), percentile(sorted[:1], 95))
+	assert.Zero(t, percentile(nil, 50))
+}
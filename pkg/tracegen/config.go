@@ -18,25 +18,90 @@
 package tracegen
 
 import (
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"math"
+	"math/rand"
+	"net/url"
 	"os"
+	"time"
 
 	"go.elastic.co/apm/v2"
+
+	"github.com/elastic/apm-tools/internal/apmconn"
+)
+
+// Errors returned by validation of Config, which may be detected
+// with errors.Is.
+var (
+	ErrMissingServerURL    = errors.New("APM Server URL must be configured")
+	ErrMissingAPIKey       = errors.New("API Key must be configured")
+	ErrInvalidSampleRate   = errors.New("invalid sample rate")
+	ErrInvalidOTLPProtocol = errors.New("invalid OTLP protocol")
 )
 
 type ConfigOption func(*Config)
 type Config struct {
-	apmServerURL string
-	apiKey       string
-	sampleRate   float64
-	traceID      apm.TraceID
-	insecure     bool
+	apmServerURLs []string
+	apiKey        string
+	sampleRate    float64
+	traceID       apm.TraceID
+	fixedTraceID  bool
+	insecure      bool
+	proxyURL      string
+
+	// certFile and keyFile hold the paths of a client certificate and
+	// key for mutual TLS, and caFile the path of a CA certificate used
+	// to verify the APM Server.
+	certFile string
+	keyFile  string
+	caFile   string
+
+	// traceparent and tracestate hold W3C Trace Context headers of an
+	// upstream trace to continue, with parentSpanID parsed from the former.
+	traceparent  string
+	tracestate   string
+	parentSpanID apm.SpanID
 
 	apmServiceName  string
 	otlpServiceName string
+	serviceVersion  string
+	serviceEnv      string
 	otlpProtocol    string
+
+	grpcKeepaliveTime    time.Duration
+	grpcKeepaliveTimeout time.Duration
+	dialTimeout          time.Duration
+	flushTimeout         time.Duration
+
+	spanCount int
+	spanDepth int
+	errorRate float64
+	spanLinks int
+
+	exitSpanKind string
+
+	logCount      int
+	logSeverities []string
+	logBody       string
+
+	disableEvents         bool
+	disableExceptions     bool
+	disableStandaloneLogs bool
+
+	transactionOutcome string
+	failureRate        float64
+	parentDuration     time.Duration
+	childDuration      time.Duration
+	exitDuration       time.Duration
+
+	rate     float64
+	duration time.Duration
+
+	// traceIDsCallback, if non-nil, is called with the IDs of
+	// each generated trace.
+	traceIDsCallback func(TraceIDs)
 }
 
 func NewConfig(opts ...ConfigOption) Config {
@@ -45,6 +110,8 @@ func NewConfig(opts ...ConfigOption) Config {
 		traceID:      NewRandomTraceID(),
 		insecure:     false,
 		otlpProtocol: "grpc",
+		spanDepth:    2,
+		logCount:     1,
 	}
 	for _, opt := range opts {
 		opt(&cfg)
@@ -55,6 +122,12 @@ func NewConfig(opts ...ConfigOption) Config {
 	return cfg
 }
 
+// TraceID returns the trace ID of the traces generated with cfg,
+// which is random unless specified with WithTraceID or WithTraceparent.
+func (cfg Config) TraceID() apm.TraceID {
+	return cfg.traceID
+}
+
 // WithSampleRate specifies the sample rate for the APM GO Agent
 func WithSampleRate(r float64) ConfigOption {
 	return func(c *Config) {
@@ -65,7 +138,22 @@ func WithSampleRate(r float64) ConfigOption {
 // WithAPMServerURL set APM Server URL (env value ELASTIC_APM_SERVER_URL)
 func WithAPMServerURL(a string) ConfigOption {
 	return func(c *Config) {
-		c.apmServerURL = a
+		c.apmServerURLs = nil
+		if a != "" {
+			c.apmServerURLs = []string{a}
+		}
+	}
+}
+
+// WithAPMServerURLs sets multiple APM Server URLs to fail over between.
+// Before sending, each URL is tried in order until a connection (and TLS
+// handshake, for https) succeeds, and the first such URL is used.
+//
+// Connections are attempted directly, so failover is not supported when
+// sending through a proxy.
+func WithAPMServerURLs(urls []string) ConfigOption {
+	return func(c *Config) {
+		c.apmServerURLs = append([]string(nil), urls...)
 	}
 }
 
@@ -80,6 +168,23 @@ func WithAPIKey(k string) ConfigOption {
 func WithTraceID(t apm.TraceID) ConfigOption {
 	return func(c *Config) {
 		c.traceID = t
+		c.fixedTraceID = true
+	}
+}
+
+// WithTraceparent continues the upstream trace identified by the given
+// W3C traceparent and tracestate headers: generated traces use its trace
+// ID, and the root transaction is a child of its parent span. The
+// traceparent must be in the format 00-<32 hex>-<16 hex>-<2 hex>.
+func WithTraceparent(traceparent, tracestate string) ConfigOption {
+	return func(c *Config) {
+		c.traceparent = traceparent
+		c.tracestate = tracestate
+		if tc, err := parseTraceparentHeader(traceparent); err == nil {
+			c.traceID = tc.Trace
+			c.fixedTraceID = true
+			c.parentSpanID = tc.Span
+		}
 	}
 }
 
@@ -90,6 +195,34 @@ func WithInsecureConn(b bool) ConfigOption {
 	}
 }
 
+// WithClientCert sets the PEM encoded client certificate and key files
+// used for mutual TLS with the APM Server, by both intake and OTLP.
+func WithClientCert(certFile, keyFile string) ConfigOption {
+	return func(c *Config) {
+		c.certFile = certFile
+		c.keyFile = keyFile
+	}
+}
+
+// WithCACert sets a PEM encoded CA certificate file that the APM Server's
+// certificate must chain to, replacing the system roots.
+func WithCACert(caFile string) ConfigOption {
+	return func(c *Config) {
+		c.caFile = caFile
+	}
+}
+
+// WithProxyURL routes OTLP/HTTP trace and log requests through the
+// proxy at u, which must be an http, https or socks5 URL. If unset,
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY are used.
+//
+// The OTLP gRPC exporters always connect directly.
+func WithProxyURL(u string) ConfigOption {
+	return func(c *Config) {
+		c.proxyURL = u
+	}
+}
+
 // WithElasticAPMServiceName specifies the service name that
 // the Elastic APM agent will use.
 //
@@ -100,6 +233,25 @@ func WithElasticAPMServiceName(s string) ConfigOption {
 	}
 }
 
+// WithServiceVersion specifies the service version recorded by both
+// the Elastic APM agent and the OpenTelemetry SDK. If unspecified,
+// the Elastic APM agent uses "0.0.1", and the OpenTelemetry resource
+// has no service version.
+func WithServiceVersion(v string) ConfigOption {
+	return func(c *Config) {
+		c.serviceVersion = v
+	}
+}
+
+// WithServiceEnvironment specifies the service environment recorded
+// by both the Elastic APM agent and the OpenTelemetry SDK, the latter
+// as the deployment.environment resource attribute.
+func WithServiceEnvironment(env string) ConfigOption {
+	return func(c *Config) {
+		c.serviceEnv = env
+	}
+}
+
 // WithOTLPServiceName specifies the service name that the
 // OpenTelemetry SDK will use.
 //
@@ -120,23 +272,356 @@ func WithOTLPProtocol(p string) ConfigOption {
 	}
 }
 
+// WithGRPCKeepalive makes the OTLP gRPC exporters ping the APM Server
+// after interval of inactivity, dropping the connection if no reply
+// arrives within timeout. The interval must be at least 10 seconds.
+func WithGRPCKeepalive(interval, timeout time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.grpcKeepaliveTime = interval
+		c.grpcKeepaliveTimeout = timeout
+	}
+}
+
+// WithDialTimeout limits how long the OTLP gRPC exporters wait for a
+// connection to the APM Server to be established.
+func WithDialTimeout(d time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.dialTimeout = d
+	}
+}
+
+// WithFlushTimeout bounds the time taken to flush each trace to the
+// server, so an unresponsive server cannot block sending indefinitely.
+// If the timeout elapses, the error returned wraps ErrFlushTimeout.
+func WithFlushTimeout(d time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.flushTimeout = d
+	}
+}
+
+// WithSpanCount specifies the number of spans to generate for each
+// trace, including the root transaction/span. The spans are arranged
+// in a tree with the depth specified by WithSpanDepth.
+//
+// If unspecified, a fixed trace of three spans is generated.
+func WithSpanCount(n int) ConfigOption {
+	return func(c *Config) {
+		c.spanCount = n
+	}
+}
+
+// WithSpanDepth specifies the maximum depth of the generated span tree,
+// with 1 being just the root. Defaults to 2, i.e. the root and its children.
+//
+// This config will be ignored unless WithSpanCount is specified.
+func WithSpanDepth(n int) ConfigOption {
+	return func(c *Config) {
+		c.spanDepth = n
+	}
+}
+
+// WithErrorRate specifies the probability, between 0 and 1, of an
+// error being recorded for each generated non-root span. Defaults to 0.
+//
+// This config will be ignored unless WithSpanCount is specified.
+func WithErrorRate(r float64) ConfigOption {
+	return func(c *Config) {
+		c.errorRate = r
+	}
+}
+
+// WithSpanLinks specifies the number of additional short-lived spans,
+// each in their own trace, to generate and link to from the root span.
+//
+// This config will be ignored when using SendIntakeV2Trace.
+func WithSpanLinks(n int) ConfigOption {
+	return func(c *Config) {
+		c.spanLinks = n
+	}
+}
+
+// WithExitSpanKind specifies the kind of exit span to generate, which
+// determines the span type and context. One of: db, messaging, external,
+// cache. If unspecified, a generic exit span is generated.
+//
+// This config will be ignored when WithSpanCount is specified.
+func WithExitSpanKind(kind string) ConfigOption {
+	return func(c *Config) {
+		c.exitSpanKind = kind
+	}
+}
+
+// WithEvents specifies whether a span event is recorded on one of the
+// generated spans. Defaults to true.
+//
+// This config will be ignored when using SendIntakeV2Trace, which does
+// not generate span events.
+func WithEvents(enabled bool) ConfigOption {
+	return func(c *Config) {
+		c.disableEvents = !enabled
+	}
+}
+
+// WithExceptions specifies whether errors are recorded for the generated
+// spans, including those specified with WithErrorRate. Defaults to true.
+func WithExceptions(enabled bool) ConfigOption {
+	return func(c *Config) {
+		c.disableExceptions = !enabled
+	}
+}
+
+// WithStandaloneLogs specifies whether standalone log records are
+// generated. If false, WithLogCount is ignored. Defaults to true.
+//
+// This config will be ignored when using SendIntakeV2Trace.
+func WithStandaloneLogs(enabled bool) ConfigOption {
+	return func(c *Config) {
+		c.disableStandaloneLogs = !enabled
+	}
+}
+
+// WithLogCount specifies the number of standalone log records to
+// generate. Defaults to 1.
+//
+// This config will be ignored when using SendIntakeV2Trace.
+func WithLogCount(n int) ConfigOption {
+	return func(c *Config) {
+		c.logCount = n
+	}
+}
+
+// WithLogSeverities specifies the severities of the generated log
+// records, which cycle through the given severities. Each must be
+// one of: trace, debug, info, warn, error, fatal. Defaults to fatal.
+//
+// This config will be ignored when using SendIntakeV2Trace.
+func WithLogSeverities(severities []string) ConfigOption {
+	return func(c *Config) {
+		c.logSeverities = severities
+	}
+}
+
+// WithLogBody specifies a text/template for the body of the generated
+// log records, which may refer to the record's .Index (starting at 0)
+// and .Severity. Defaults to "sample body value".
+//
+// This config will be ignored when using SendIntakeV2Trace.
+func WithLogBody(template string) ConfigOption {
+	return func(c *Config) {
+		c.logBody = template
+	}
+}
+
+// WithTransactionOutcome specifies the outcome of the generated
+// transaction (or root span) to one of: success, failure, unknown.
+//
+// If unspecified, the Elastic APM agent transaction outcome is
+// success, and the OpenTelemetry root span status is left unset.
+func WithTransactionOutcome(outcome string) ConfigOption {
+	return func(c *Config) {
+		c.transactionOutcome = outcome
+	}
+}
+
+// WithFailureRate specifies the probability, between 0 and 1, of the
+// generated transaction (or root span) having a failure outcome,
+// overriding WithTransactionOutcome. Defaults to 0.
+func WithFailureRate(r float64) ConfigOption {
+	return func(c *Config) {
+		c.failureRate = r
+	}
+}
+
+// WithDurations specifies the durations of the generated parent
+// (transaction or root span), child, and exit spans. Zero durations
+// are left at their defaults.
+//
+// This config will be ignored when WithSpanCount is specified.
+func WithDurations(parent, child, exit time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.parentDuration = parent
+		c.childDuration = child
+		c.exitDuration = exit
+	}
+}
+
+// WithRate specifies the number of traces per second to send
+// with GenerateContinuous.
+func WithRate(perSecond float64) ConfigOption {
+	return func(c *Config) {
+		c.rate = perSecond
+	}
+}
+
+// WithDuration specifies how long GenerateContinuous should send
+// traces for. If unspecified, traces are sent until the context is
+// cancelled.
+func WithDuration(d time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.duration = d
+	}
+}
+
+// WithTraceIDsCallback specifies a function to call with the IDs of
+// the events in each trace sent, e.g. to capture them for verification.
+func WithTraceIDsCallback(f func(TraceIDs)) ConfigOption {
+	return func(c *Config) {
+		c.traceIDsCallback = f
+	}
+}
+
 func (cfg Config) validate() error {
 	var errs []error
 	if cfg.sampleRate < 0.0001 || cfg.sampleRate > 1.0 {
 		errs = append(errs,
-			fmt.Errorf("invalid sample rate %f provided. allowed value: 0.0001 <= sample-rate <= 1.0", cfg.sampleRate),
+			fmt.Errorf("%w %f provided. allowed value: 0.0001 <= sample-rate <= 1.0", ErrInvalidSampleRate, cfg.sampleRate),
 		)
 	}
-	if cfg.apmServerURL == "" {
-		errs = append(errs, errors.New("APM Server URL must be configured"))
+	if len(cfg.apmServerURLs) == 0 {
+		errs = append(errs, ErrMissingServerURL)
+	}
+	for _, u := range cfg.apmServerURLs {
+		if err := validateAPMServerURL(u); err != nil {
+			errs = append(errs, err)
+		}
 	}
 
 	if cfg.apiKey == "" {
-		errs = append(errs, errors.New("API Key must be configured"))
+		errs = append(errs, ErrMissingAPIKey)
+	}
+	switch cfg.otlpProtocol {
+	case "grpc", "http/protobuf":
+	default:
+		errs = append(errs, fmt.Errorf(
+			"%w %q provided. allowed values: grpc, http/protobuf",
+			ErrInvalidOTLPProtocol, cfg.otlpProtocol,
+		))
+	}
+	if _, err := cfg.tlsConfig(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := apmconn.ProxyFunc(cfg.proxyURL); err != nil {
+		errs = append(errs, err)
+	}
+	if cfg.traceparent != "" {
+		if _, err := parseTraceparentHeader(cfg.traceparent); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if cfg.spanCount < 0 {
+		errs = append(errs, fmt.Errorf("invalid span count %d provided. must be >= 0", cfg.spanCount))
+	}
+	if cfg.spanDepth < 1 || (cfg.spanDepth == 1 && cfg.spanCount > 1) {
+		errs = append(errs, fmt.Errorf(
+			"invalid span depth %d provided. must be >= 1, and >= 2 for more than one span",
+			cfg.spanDepth,
+		))
+	}
+	if cfg.spanLinks < 0 {
+		errs = append(errs, fmt.Errorf("invalid span links %d provided. must be >= 0", cfg.spanLinks))
+	}
+	if _, ok := exitSpanKinds[cfg.exitSpanKind]; !ok && cfg.exitSpanKind != "" {
+		errs = append(errs, fmt.Errorf(
+			"invalid exit span kind %q provided. allowed values: db, messaging, external, cache",
+			cfg.exitSpanKind,
+		))
+	}
+	switch cfg.transactionOutcome {
+	case "", "success", "failure", "unknown":
+	default:
+		errs = append(errs, fmt.Errorf(
+			"invalid transaction outcome %q provided. allowed values: success, failure, unknown",
+			cfg.transactionOutcome,
+		))
+	}
+	if cfg.failureRate < 0 || cfg.failureRate > 1 {
+		errs = append(errs,
+			fmt.Errorf("invalid failure rate %f provided. allowed value: 0 <= failure-rate <= 1.0", cfg.failureRate),
+		)
+	}
+	if cfg.grpcKeepaliveTime < 0 || cfg.grpcKeepaliveTimeout < 0 || cfg.dialTimeout < 0 {
+		errs = append(errs, errors.New("gRPC keepalive and dial timeouts must not be negative"))
+	}
+	if cfg.parentDuration < 0 || cfg.childDuration < 0 || cfg.exitDuration < 0 {
+		errs = append(errs, errors.New("durations must not be negative"))
+	}
+	if cfg.errorRate < 0 || cfg.errorRate > 1 {
+		errs = append(errs,
+			fmt.Errorf("invalid error rate %f provided. allowed value: 0 <= error-rate <= 1.0", cfg.errorRate),
+		)
+	}
+	if cfg.logCount < 0 {
+		errs = append(errs, fmt.Errorf("invalid log count %d provided. must be >= 0", cfg.logCount))
+	}
+	for _, severity := range cfg.logSeverities {
+		if _, ok := logSeverities[severity]; !ok {
+			errs = append(errs, fmt.Errorf(
+				"invalid log severity %q provided. allowed values: trace, debug, info, warn, error, fatal",
+				severity,
+			))
+		}
+	}
+	if _, err := cfg.logBodyTemplate(); err != nil {
+		errs = append(errs, err)
 	}
 	return errors.Join(errs...)
 }
 
+// tlsConfig returns the TLS configuration for connecting to the APM Server.
+func (cfg Config) tlsConfig() (*tls.Config, error) {
+	return apmconn.TLSOptions{
+		InsecureSkipVerify: cfg.insecure,
+		CertFile:           cfg.certFile,
+		KeyFile:            cfg.keyFile,
+		CAFile:             cfg.caFile,
+	}.Config()
+}
+
+// grpcOptions returns the settings for OTLP gRPC connections.
+func (cfg Config) grpcOptions() apmconn.GRPCOptions {
+	return apmconn.GRPCOptions{
+		KeepaliveTime:    cfg.grpcKeepaliveTime,
+		KeepaliveTimeout: cfg.grpcKeepaliveTimeout,
+		DialTimeout:      cfg.dialTimeout,
+	}
+}
+
+// validateAPMServerURL checks that s is an absolute http or https URL,
+// so that misconfiguration is reported before sending any events.
+func validateAPMServerURL(s string) error {
+	u, err := url.Parse(s)
+	if err != nil {
+		return fmt.Errorf("invalid APM Server URL %q provided: %w", s, err)
+	}
+	switch u.Scheme {
+	case "http", "https":
+	default:
+		return fmt.Errorf("invalid APM Server URL %q provided. APM Server URL must be prefixed with http:// or https://", s)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("invalid APM Server URL %q provided. APM Server URL must include a host", s)
+	}
+	return nil
+}
+
+// outcome returns the outcome for a generated transaction, taking into
+// account the failure rate. If no outcome has been configured and the
+// transaction has not randomly failed, outcome returns an empty string.
+func (cfg Config) outcome() string {
+	if cfg.failureRate > 0 && rand.Float64() < cfg.failureRate {
+		return "failure"
+	}
+	return cfg.transactionOutcome
+}
+
+// durationOrDefault returns d if it is non-zero, or otherwise def.
+func durationOrDefault(d, def time.Duration) time.Duration {
+	if d == 0 {
+		return def
+	}
+	return d
+}
+
 // configureEnv parses or sets env configs to work with both Elastic GO Agent and OTLP library
 func (cfg *Config) configureEnv() error {
 	if cfg.apiKey == "" {
@@ -145,10 +630,12 @@ func (cfg *Config) configureEnv() error {
 		os.Setenv("ELASTIC_APM_API_KEY", cfg.apiKey)
 	}
 
-	if cfg.apmServerURL == "" {
-		cfg.apmServerURL = os.Getenv("ELASTIC_APM_SERVER_URL")
+	if len(cfg.apmServerURLs) == 0 {
+		if u := os.Getenv("ELASTIC_APM_SERVER_URL"); u != "" {
+			cfg.apmServerURLs = []string{u}
+		}
 	} else {
-		os.Setenv("ELASTIC_APM_SERVER_URL", cfg.apmServerURL)
+		os.Setenv("ELASTIC_APM_SERVER_URL", cfg.apmServerURLs[0])
 	}
 
 	if cfg.insecure {
@@ -0,0 +1,104 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tracegen
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSentinelErrors(t *testing.T) {
+	for name, tc := range map[string]struct {
+		opts     []ConfigOption
+		expected error
+		message  string
+	}{
+		"missing_server_url": {
+			opts:     []ConfigOption{WithAPIKey("abc123")},
+			expected: ErrMissingServerURL,
+			message:  "APM Server URL must be configured",
+		},
+		"missing_api_key": {
+			opts:     []ConfigOption{WithAPMServerURL("http://localhost:8200")},
+			expected: ErrMissingAPIKey,
+			message:  "API Key must be configured",
+		},
+		"invalid_sample_rate": {
+			opts: []ConfigOption{
+				WithAPMServerURL("http://localhost:8200"), WithAPIKey("abc123"), WithSampleRate(2),
+			},
+			expected: ErrInvalidSampleRate,
+			message:  "invalid sample rate 2.000000 provided. allowed value: 0.0001 <= sample-rate <= 1.0",
+		},
+		"invalid_otlp_protocol": {
+			opts: []ConfigOption{
+				WithAPMServerURL("http://localhost:8200"), WithAPIKey("abc123"), WithOTLPProtocol("http/json"),
+			},
+			expected: ErrInvalidOTLPProtocol,
+			message:  `invalid OTLP protocol "http/json" provided. allowed values: grpc, http/protobuf`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// NewConfig sets these from, and in, the environment.
+			t.Setenv("ELASTIC_APM_SERVER_URL", "")
+			t.Setenv("ELASTIC_APM_API_KEY", "")
+			err := NewConfig(tc.opts...).validate()
+			assert.ErrorIs(t, err, tc.expected)
+			assert.EqualError(t, err, tc.message)
+		})
+	}
+
+	// Multiple errors are joined, and each remains detectable.
+	t.Setenv("ELASTIC_APM_SERVER_URL", "")
+	t.Setenv("ELASTIC_APM_API_KEY", "")
+	err := NewConfig(WithSampleRate(0)).validate()
+	for _, expected := range []error{ErrMissingServerURL, ErrMissingAPIKey, ErrInvalidSampleRate} {
+		assert.ErrorIs(t, err, expected)
+	}
+	assert.NotErrorIs(t, err, ErrInvalidOTLPProtocol)
+}
+
+func TestValidateAPMServerURL(t *testing.T) {
+	for name, tc := range map[string]struct {
+		url         string
+		expectedErr string
+	}{
+		"http":         {url: "http://localhost:8200"},
+		"https":        {url: "https://apm.example.com"},
+		"https_path":   {url: "https://apm.example.com:443/prefix"},
+		"empty":        {url: "", expectedErr: "APM Server URL must be configured"},
+		"no_scheme":    {url: "localhost:8200", expectedErr: "must be prefixed with http:// or https://"},
+		"bare_host":    {url: "apm.example.com", expectedErr: "must be prefixed with http:// or https://"},
+		"unsupported":  {url: "ftp://apm.example.com", expectedErr: "must be prefixed with http:// or https://"},
+		"missing_host": {url: "http://", expectedErr: "must include a host"},
+		"malformed":    {url: "http://local host:8200", expectedErr: "invalid APM Server URL"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// NewConfig sets these from, and in, the environment.
+			t.Setenv("ELASTIC_APM_SERVER_URL", "")
+			t.Setenv("ELASTIC_APM_API_KEY", "")
+			err := NewConfig(WithAPMServerURL(tc.url), WithAPIKey("api_key")).validate()
+			if tc.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tc.expectedErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tracegen
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// GenerateContinuous sends distributed traces at the rate specified
+// by WithRate, until the duration specified by WithDuration elapses
+// or ctx is cancelled. Each trace is sent with a new random trace ID,
+// unless one is specified with WithTraceID or WithTraceparent.
+//
+// The returned EventStats holds the aggregated stats of all traces sent.
+func GenerateContinuous(ctx context.Context, cfg Config) (EventStats, error) {
+	return generateContinuous(ctx, cfg, SendDistributedTrace)
+}
+
+func generateContinuous(
+	ctx context.Context, cfg Config,
+	send func(context.Context, Config) (EventStats, error),
+) (EventStats, error) {
+	if err := cfg.validate(); err != nil {
+		return EventStats{}, err
+	}
+	if cfg.rate <= 0 {
+		return EventStats{}, fmt.Errorf("invalid rate %f provided. must be > 0", cfg.rate)
+	}
+	if cfg.duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.duration)
+		defer cancel()
+	}
+
+	// Schedule each trace relative to the previous schedule rather
+	// than when the previous send completed, so slow sends do not
+	// reduce the rate. Jitter the interval to avoid sending traces
+	// in lockstep with other generators.
+	interval := time.Duration(float64(time.Second) / cfg.rate)
+	next := time.Now().Add(jitter(interval))
+	timer := time.NewTimer(time.Until(next))
+	defer timer.Stop()
+
+	var stats EventStats
+	for {
+		select {
+		case <-ctx.Done():
+			return stats, nil
+		case <-timer.C:
+		}
+		if !cfg.fixedTraceID {
+			cfg.traceID = NewRandomTraceID()
+		}
+		traceStats, err := send(ctx, cfg)
+		if err != nil {
+			if ctx.Err() != nil {
+				// Interrupted by cancellation or the duration elapsing.
+				return stats, nil
+			}
+			return stats, err
+		}
+		stats = stats.Add(traceStats)
+		next = next.Add(jitter(interval))
+		timer.Reset(time.Until(next))
+	}
+}
+
+// jitter returns d adjusted randomly by up to +/-10%.
+func jitter(d time.Duration) time.Duration {
+	return d + time.Duration((rand.Float64()-0.5)*0.2*float64(d))
+}
@@ -0,0 +1,64 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tracegen
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.elastic.co/apm/v2"
+)
+
+func TestGenerateContinuous(t *testing.T) {
+	cfg := NewConfig(
+		WithAPMServerURL("http://localhost:8200"),
+		WithAPIKey("abc123"),
+		WithRate(10),
+		WithDuration(2*time.Second),
+	)
+	traceIDs := make(map[apm.TraceID]bool)
+	stats, err := generateContinuous(context.Background(), cfg,
+		func(ctx context.Context, cfg Config) (EventStats, error) {
+			traceIDs[cfg.traceID] = true
+			return EventStats{SpansSent: 1}, nil
+		},
+	)
+	require.NoError(t, err)
+	assert.InDelta(t, 20, stats.SpansSent, 3)
+	assert.Len(t, traceIDs, stats.SpansSent)
+}
+
+func TestGenerateContinuousCancel(t *testing.T) {
+	cfg := NewConfig(
+		WithAPMServerURL("http://localhost:8200"),
+		WithAPIKey("abc123"),
+		WithRate(100),
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	stats, err := generateContinuous(ctx, cfg,
+		func(ctx context.Context, cfg Config) (EventStats, error) {
+			cancel()
+			return EventStats{SpansSent: 1}, nil
+		},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, EventStats{SpansSent: 1}, stats)
+}
@@ -19,7 +19,9 @@ package tracegen
 
 import (
 	"context"
+	"encoding/hex"
 	"fmt"
+	"strings"
 
 	"go.elastic.co/apm/v2"
 )
@@ -31,22 +33,83 @@ func SendDistributedTrace(ctx context.Context, cfg Config) (EventStats, error) {
 		return EventStats{}, err
 	}
 
-	txCtx, apmStats, err := SendIntakeV2Trace(ctx, cfg)
+	var ids TraceIDs
+	txCtx, apmStats, err := sendIntakeV2Trace(ctx, cfg, &ids)
 	if err != nil {
 		return EventStats{}, err
 	}
 
 	traceparent := formatTraceparentHeader(txCtx)
-	tracestate := txCtx.State.String()
+	tracestate := mergeTracestate(txCtx.State.String(), cfg.tracestate)
 	ctx = SetOTLPTracePropagator(ctx, traceparent, tracestate)
 
-	otlpStats, err := SendOTLPTrace(ctx, cfg)
+	otlpStats, err := sendOTLPTrace(ctx, cfg, &ids)
 	if err != nil {
 		return EventStats{}, err
 	}
+	cfg.reportTraceIDs(ids)
 	return apmStats.Add(otlpStats), nil
 }
 
 func formatTraceparentHeader(c apm.TraceContext) string {
 	return fmt.Sprintf("%02x-%032x-%016x-%02x", 0, c.Trace[:], c.Span[:], c.Options)
 }
+
+// parseTraceparentHeader parses a W3C traceparent header in the format
+// 00-<32 hex trace ID>-<16 hex parent ID>-<2 hex flags>.
+func parseTraceparentHeader(h string) (apm.TraceContext, error) {
+	var tc apm.TraceContext
+	parts := strings.Split(h, "-")
+	if len(parts) != 4 || parts[0] != "00" ||
+		len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 ||
+		strings.ToLower(h) != h {
+		return tc, fmt.Errorf("invalid traceparent %q provided. expected format: 00-<32 hex>-<16 hex>-<2 hex>", h)
+	}
+	var flags [1]byte
+	for _, field := range []struct {
+		dst []byte
+		src string
+	}{
+		{tc.Trace[:], parts[1]},
+		{tc.Span[:], parts[2]},
+		{flags[:], parts[3]},
+	} {
+		if _, err := hex.Decode(field.dst, []byte(field.src)); err != nil {
+			return tc, fmt.Errorf("invalid traceparent %q provided: %w", h, err)
+		}
+	}
+	if err := tc.Trace.Validate(); err != nil {
+		return tc, fmt.Errorf("invalid traceparent %q provided: %w", h, err)
+	}
+	if err := tc.Span.Validate(); err != nil {
+		return tc, fmt.Errorf("invalid traceparent %q provided: %w", h, err)
+	}
+	tc.Options = apm.TraceOptions(flags[0])
+	return tc, nil
+}
+
+// mergeTracestate returns the W3C tracestate with the entries of
+// the local tracestate followed by those of the upstream tracestate,
+// excluding any upstream entries with the same keys.
+func mergeTracestate(local, upstream string) string {
+	if upstream == "" {
+		return local
+	}
+	keys := make(map[string]bool)
+	var entries []string
+	for _, entry := range strings.Split(local, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			key, _, _ := strings.Cut(entry, "=")
+			keys[key] = true
+			entries = append(entries, entry)
+		}
+	}
+	for _, entry := range strings.Split(upstream, ",") {
+		entry = strings.TrimSpace(entry)
+		key, _, _ := strings.Cut(entry, "=")
+		if entry != "" && !keys[key] {
+			entries = append(entries, entry)
+		}
+	}
+	return strings.Join(entries, ",")
+}
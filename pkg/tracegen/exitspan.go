@@ -0,0 +1,120 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tracegen
+
+import (
+	"net/http"
+	"net/url"
+
+	"go.elastic.co/apm/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// exitSpanKind describes how an exit span of a particular kind
+// is represented by both the Elastic APM agent and OpenTelemetry.
+type exitSpanKind struct {
+	// spanType holds the Elastic APM span type,
+	// in the form <type>.<subtype>.<action>.
+	spanType string
+
+	// target holds the Elastic APM service target.
+	target apm.ServiceTargetSpanContext
+
+	// setContext sets kind-specific Elastic APM span context.
+	setContext func(*apm.SpanContext)
+
+	// otelSpanKind holds the OpenTelemetry span kind.
+	otelSpanKind trace.SpanKind
+
+	// otelAttributes holds the OpenTelemetry span attributes.
+	otelAttributes []attribute.KeyValue
+}
+
+const (
+	exitSpanTargetName = "tracegen"
+	exitSpanStatement  = "SELECT * FROM tracegen"
+	exitSpanURL        = "http://tracegen.example:8080/tracegen"
+)
+
+// exitSpanKinds holds the supported exit span kinds,
+// as specified by WithExitSpanKind.
+var exitSpanKinds = map[string]exitSpanKind{
+	"db": {
+		spanType: "db.postgresql.query",
+		target:   apm.ServiceTargetSpanContext{Type: "postgresql", Name: exitSpanTargetName},
+		setContext: func(c *apm.SpanContext) {
+			c.SetDatabase(apm.DatabaseSpanContext{
+				Instance:  exitSpanTargetName,
+				Statement: exitSpanStatement,
+				Type:      "sql",
+			})
+		},
+		otelSpanKind: trace.SpanKindClient,
+		otelAttributes: []attribute.KeyValue{
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.name", exitSpanTargetName),
+			attribute.String("db.statement", exitSpanStatement),
+		},
+	},
+	"messaging": {
+		spanType: "messaging.kafka.send",
+		target:   apm.ServiceTargetSpanContext{Type: "kafka", Name: exitSpanTargetName},
+		setContext: func(c *apm.SpanContext) {
+			c.SetMessage(apm.MessageSpanContext{QueueName: exitSpanTargetName})
+		},
+		otelSpanKind: trace.SpanKindProducer,
+		otelAttributes: []attribute.KeyValue{
+			attribute.String("messaging.system", "kafka"),
+			attribute.String("messaging.destination.name", exitSpanTargetName),
+			attribute.String("messaging.operation", "publish"),
+		},
+	},
+	"external": {
+		spanType: "external.http.request",
+		target:   apm.ServiceTargetSpanContext{Type: "http", Name: "tracegen.example:8080"},
+		setContext: func(c *apm.SpanContext) {
+			u, _ := url.Parse(exitSpanURL)
+			c.SetHTTPRequest(&http.Request{Method: http.MethodGet, URL: u})
+			c.SetHTTPStatusCode(http.StatusOK)
+		},
+		otelSpanKind: trace.SpanKindClient,
+		otelAttributes: []attribute.KeyValue{
+			attribute.String("http.request.method", http.MethodGet),
+			attribute.String("url.full", exitSpanURL),
+			attribute.String("server.address", "tracegen.example"),
+			attribute.Int("server.port", 8080),
+			attribute.Int("http.response.status_code", http.StatusOK),
+		},
+	},
+	"cache": {
+		spanType: "db.redis.query",
+		target:   apm.ServiceTargetSpanContext{Type: "redis"},
+		setContext: func(c *apm.SpanContext) {
+			c.SetDatabase(apm.DatabaseSpanContext{
+				Statement: "GET tracegen",
+				Type:      "redis",
+			})
+		},
+		otelSpanKind: trace.SpanKindClient,
+		otelAttributes: []attribute.KeyValue{
+			attribute.String("db.system", "redis"),
+			attribute.String("db.statement", "GET tracegen"),
+		},
+	},
+}
@@ -0,0 +1,149 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tracegen
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.elastic.co/apm/v2/apmtest"
+	"go.elastic.co/apm/v2/model"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestExitSpanKindIntake(t *testing.T) {
+	type expectation struct {
+		spanType, subtype, action string
+		target                    model.ServiceTargetSpanContext
+		check                     func(t *testing.T, context *model.SpanContext)
+	}
+	for kind, expected := range map[string]expectation{
+		"": {
+			spanType: "apmtool",
+			target:   model.ServiceTargetSpanContext{Type: "service_type", Name: "service_name"},
+		},
+		"db": {
+			spanType: "db", subtype: "postgresql", action: "query",
+			target: model.ServiceTargetSpanContext{Type: "postgresql", Name: "tracegen"},
+			check: func(t *testing.T, context *model.SpanContext) {
+				require.NotNil(t, context.Database)
+				assert.Equal(t, "sql", context.Database.Type)
+				assert.Equal(t, "tracegen", context.Database.Instance)
+				assert.Equal(t, "SELECT * FROM tracegen", context.Database.Statement)
+			},
+		},
+		"messaging": {
+			spanType: "messaging", subtype: "kafka", action: "send",
+			target: model.ServiceTargetSpanContext{Type: "kafka", Name: "tracegen"},
+			check: func(t *testing.T, context *model.SpanContext) {
+				require.NotNil(t, context.Message)
+				assert.Equal(t, "tracegen", context.Message.Queue.Name)
+			},
+		},
+		"external": {
+			spanType: "external", subtype: "http", action: "request",
+			target: model.ServiceTargetSpanContext{Type: "http", Name: "tracegen.example:8080"},
+			check: func(t *testing.T, context *model.SpanContext) {
+				require.NotNil(t, context.HTTP)
+				assert.Equal(t, "tracegen.example", context.HTTP.URL.Hostname())
+				assert.Equal(t, 200, context.HTTP.StatusCode)
+			},
+		},
+		"cache": {
+			spanType: "db", subtype: "redis", action: "query",
+			target: model.ServiceTargetSpanContext{Type: "redis"},
+			check: func(t *testing.T, context *model.SpanContext) {
+				require.NotNil(t, context.Database)
+				assert.Equal(t, "redis", context.Database.Type)
+			},
+		},
+	} {
+		t.Run(kind, func(t *testing.T) {
+			tracer := apmtest.NewRecordingTracer()
+			defer tracer.Close()
+			tx := tracer.StartTransaction("tx", "request")
+			generateIntakeSpans(tracer.Tracer, tx, NewConfig(WithExitSpanKind(kind)))
+			tracer.Flush(nil)
+
+			var exit *model.Span
+			payloads := tracer.Payloads()
+			for i, span := range payloads.Spans {
+				if span.Name == "exit-span" {
+					exit = &payloads.Spans[i]
+				}
+			}
+			require.NotNil(t, exit)
+			assert.Equal(t, expected.spanType, exit.Type)
+			assert.Equal(t, expected.subtype, exit.Subtype)
+			assert.Equal(t, expected.action, exit.Action)
+			require.NotNil(t, exit.Context)
+			require.NotNil(t, exit.Context.Service)
+			assert.Equal(t, &expected.target, exit.Context.Service.Target)
+			if expected.check != nil {
+				expected.check(t, exit.Context)
+			}
+		})
+	}
+}
+
+func TestExitSpanKindOTLP(t *testing.T) {
+	for kind, expected := range map[string]struct {
+		spanKind   trace.SpanKind
+		attributes map[attribute.Key]string
+	}{
+		"": {spanKind: trace.SpanKindInternal},
+		"db": {
+			spanKind:   trace.SpanKindClient,
+			attributes: map[attribute.Key]string{"db.system": "postgresql", "db.name": "tracegen"},
+		},
+		"messaging": {
+			spanKind:   trace.SpanKindProducer,
+			attributes: map[attribute.Key]string{"messaging.system": "kafka", "messaging.destination.name": "tracegen"},
+		},
+		"external": {
+			spanKind:   trace.SpanKindClient,
+			attributes: map[attribute.Key]string{"url.full": "http://tracegen.example:8080/tracegen"},
+		},
+		"cache": {
+			spanKind:   trace.SpanKindClient,
+			attributes: map[attribute.Key]string{"db.system": "redis"},
+		},
+	} {
+		t.Run(kind, func(t *testing.T) {
+			spans, _ := generateTestSpans(t, NewConfig(WithExitSpanKind(kind)))
+			var found bool
+			for _, span := range spans {
+				if span.Name != "child2" {
+					continue
+				}
+				found = true
+				assert.Equal(t, expected.spanKind, span.SpanKind)
+				attributes := make(map[attribute.Key]string)
+				for _, kv := range span.Attributes {
+					attributes[kv.Key] = kv.Value.Emit()
+				}
+				for k, v := range expected.attributes {
+					assert.Equal(t, v, attributes[k], k)
+				}
+			}
+			assert.True(t, found)
+		})
+	}
+}
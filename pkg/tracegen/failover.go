@@ -0,0 +1,94 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tracegen
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"time"
+)
+
+// defaultAPMServerDialTimeout holds the timeout for connecting to each
+// APM Server in selectAPMServerURL, if no dial timeout is configured.
+const defaultAPMServerDialTimeout = 5 * time.Second
+
+// selectAPMServerURL returns the APM Server URL to send events to.
+//
+// If multiple URLs are configured, each is tried in order until a
+// connection and any TLS handshake succeeds. An error is returned if
+// none of the APM Servers can be reached.
+func (cfg Config) selectAPMServerURL(ctx context.Context) (string, error) {
+	if len(cfg.apmServerURLs) == 1 {
+		return cfg.apmServerURLs[0], nil
+	}
+	var errs []error
+	for _, s := range cfg.apmServerURLs {
+		err := cfg.dialAPMServer(ctx, s)
+		if err == nil {
+			slog.Info("selected APM Server", "url", s)
+			return s, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", s, err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return "", fmt.Errorf("failed to connect to any APM Server: %w", errors.Join(errs...))
+}
+
+// dialAPMServer connects to the APM Server at rawURL, performing a
+// TLS handshake for https URLs, and then closes the connection.
+func (cfg Config) dialAPMServer(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		port := "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+		addr = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	dialer := &net.Dialer{Timeout: durationOrDefault(cfg.dialTimeout, defaultAPMServerDialTimeout)}
+	var conn net.Conn
+	if u.Scheme == "https" {
+		tlsConfig, err := cfg.tlsConfig()
+		if err != nil {
+			return err
+		}
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: tlsConfig}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return err
+		}
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return err
+		}
+	}
+	return conn.Close()
+}
@@ -0,0 +1,82 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tracegen
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectAPMServerURL(t *testing.T) {
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+	live := httptest.NewServer(http.NotFoundHandler())
+	defer live.Close()
+
+	cfg := Config{apmServerURLs: []string{dead.URL, live.URL}}
+	u, err := cfg.selectAPMServerURL(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, live.URL, u)
+
+	cfg = Config{apmServerURLs: []string{dead.URL}}
+	u, err = cfg.selectAPMServerURL(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, dead.URL, u, "a single URL is used without connecting")
+
+	cfg = Config{apmServerURLs: []string{dead.URL, dead.URL}}
+	_, err = cfg.selectAPMServerURL(context.Background())
+	assert.ErrorContains(t, err, "failed to connect to any APM Server")
+}
+
+func TestSendOTLPTraceFailover(t *testing.T) {
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+
+	var mu sync.Mutex
+	var paths []string
+	live := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		paths = append(paths, r.URL.Path)
+		w.Header().Set("Content-Type", "application/x-protobuf")
+	}))
+	defer live.Close()
+
+	// NewConfig sets these from, and in, the environment.
+	t.Setenv("ELASTIC_APM_SERVER_URL", "")
+	t.Setenv("ELASTIC_APM_API_KEY", "")
+	cfg := NewConfig(
+		WithAPMServerURLs([]string{dead.URL, live.URL}),
+		WithAPIKey("api_key"),
+		WithOTLPProtocol("http/protobuf"),
+	)
+	stats, err := SendOTLPTrace(context.Background(), cfg)
+	require.NoError(t, err)
+	assert.NotZero(t, stats.SpansSent)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Contains(t, paths, "/v1/traces")
+	assert.Contains(t, paths, "/v1/logs")
+}
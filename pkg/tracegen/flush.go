@@ -0,0 +1,47 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tracegen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrFlushTimeout is returned when flushing generated data to the
+// server does not complete within the timeout specified by
+// WithFlushTimeout.
+var ErrFlushTimeout = errors.New("flush timed out")
+
+// flushWithTimeout calls flush with ctx, bounded by the timeout
+// specified by WithFlushTimeout, if any. If the timeout elapses, the
+// returned error wraps ErrFlushTimeout, distinguishing it from other
+// failures, including cancellation of ctx.
+func (cfg Config) flushWithTimeout(ctx context.Context, flush func(context.Context) error) error {
+	if cfg.flushTimeout <= 0 {
+		return flush(ctx)
+	}
+	timeoutErr := fmt.Errorf("%w after %s", ErrFlushTimeout, cfg.flushTimeout)
+	ctx, cancel := context.WithTimeoutCause(ctx, cfg.flushTimeout, timeoutErr)
+	defer cancel()
+	err := flush(ctx)
+	if cause := context.Cause(ctx); cause == timeoutErr {
+		return cause
+	}
+	return err
+}
@@ -0,0 +1,82 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tracegen
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// stallingExporter is a span exporter which never completes exporting
+// or shutting down, as if the server were unresponsive.
+type stallingExporter struct{}
+
+func (stallingExporter) ExportSpans(ctx context.Context, _ []sdktrace.ReadOnlySpan) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (stallingExporter) Shutdown(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestFlushTimeout(t *testing.T) {
+	cfg := NewConfig(WithFlushTimeout(100 * time.Millisecond))
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(stallingExporter{}))
+	_, span := tracerProvider.Tracer("tracegen").Start(context.Background(), "span")
+	span.End()
+
+	start := time.Now()
+	err := cfg.flushWithTimeout(context.Background(), tracerProvider.Shutdown)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrFlushTimeout)
+	assert.EqualError(t, err, "flush timed out after 100ms")
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestFlushTimeoutOtherErrors(t *testing.T) {
+	cfg := NewConfig(WithFlushTimeout(time.Minute))
+
+	// Failures other than the timeout elapsing are returned as is.
+	err := cfg.flushWithTimeout(context.Background(), func(context.Context) error {
+		return errors.New("connection refused")
+	})
+	assert.EqualError(t, err, "connection refused")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = cfg.flushWithTimeout(ctx, stallingExporter{}.Shutdown)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.NotErrorIs(t, err, ErrFlushTimeout)
+
+	// Without a timeout, flush is bounded only by ctx.
+	cfg = NewConfig()
+	err = cfg.flushWithTimeout(context.Background(), func(ctx context.Context) error {
+		_, ok := ctx.Deadline()
+		assert.False(t, ok)
+		return nil
+	})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,86 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tracegen
+
+import (
+	"context"
+	"sync"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TraceIDs holds the IDs of the events generated for a trace.
+type TraceIDs struct {
+	TraceID        string   `json:"trace.id"`
+	TransactionIDs []string `json:"transaction.ids"`
+	SpanIDs        []string `json:"span.ids"`
+}
+
+// reportTraceIDs calls the callback specified with WithTraceIDsCallback,
+// if any, with ids.
+func (cfg Config) reportTraceIDs(ids TraceIDs) {
+	if cfg.traceIDsCallback != nil {
+		cfg.traceIDsCallback(ids)
+	}
+}
+
+// spanIDRecorder is an sdktrace.SpanProcessor that records the
+// contexts of ended spans, for reporting their IDs.
+type spanIDRecorder struct {
+	mu    sync.Mutex
+	spans []sdktrace.ReadOnlySpan
+}
+
+func (r *spanIDRecorder) OnStart(context.Context, sdktrace.ReadWriteSpan) {}
+
+func (r *spanIDRecorder) OnEnd(s sdktrace.ReadOnlySpan) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, s)
+}
+
+func (r *spanIDRecorder) Shutdown(context.Context) error { return nil }
+
+func (r *spanIDRecorder) ForceFlush(context.Context) error { return nil }
+
+// addTraceIDs adds the IDs of the recorded spans in the trace
+// with ID traceID to ids. Spans which APM Server records as
+// transactions, i.e. local roots and server or consumer spans,
+// are added to ids.TransactionIDs, and others to ids.SpanIDs.
+func (r *spanIDRecorder) addTraceIDs(traceID trace.TraceID, ids *TraceIDs) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if ids.TraceID == "" {
+		ids.TraceID = traceID.String()
+	}
+	for _, s := range r.spans {
+		if s.SpanContext().TraceID() != traceID {
+			continue // e.g. linked spans
+		}
+		id := s.SpanContext().SpanID().String()
+		switch {
+		case !s.Parent().IsValid(), s.Parent().IsRemote(),
+			s.SpanKind() == trace.SpanKindServer,
+			s.SpanKind() == trace.SpanKindConsumer:
+			ids.TransactionIDs = append(ids.TransactionIDs, id)
+		default:
+			ids.SpanIDs = append(ids.SpanIDs, id)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tracegen
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.elastic.co/apm/v2"
+	"go.elastic.co/apm/v2/apmtest"
+	"go.opentelemetry.io/collector/pdata/ptrace/ptraceotlp"
+)
+
+func TestGenerateIntakeSpansIDs(t *testing.T) {
+	for name, cfg := range map[string]Config{
+		"default":    NewConfig(),
+		"span_count": NewConfig(WithSpanCount(5)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			tracer := apmtest.NewRecordingTracer()
+			defer tracer.Close()
+			tx := tracer.StartTransaction("tx", "request")
+			var spanIDs []apm.SpanID
+			if cfg.spanCount > 0 {
+				spanIDs = generateIntakeSpanTree(tracer.Tracer, tx, cfg)
+			} else {
+				spanIDs = generateIntakeSpans(tracer.Tracer, tx, cfg)
+			}
+			tracer.Flush(nil)
+
+			var expected []apm.SpanID
+			for _, span := range tracer.Payloads().Spans {
+				expected = append(expected, apm.SpanID(span.ID))
+			}
+			assert.NotEmpty(t, spanIDs)
+			assert.ElementsMatch(t, expected, spanIDs)
+		})
+	}
+}
+
+func TestSendOTLPTraceIDs(t *testing.T) {
+	var mu sync.Mutex
+	var traceIDs, spanIDs []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		if r.URL.Path != "/v1/traces" {
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		req := ptraceotlp.NewExportRequest()
+		require.NoError(t, req.UnmarshalProto(body))
+
+		mu.Lock()
+		defer mu.Unlock()
+		resourceSpans := req.Traces().ResourceSpans()
+		for i := 0; i < resourceSpans.Len(); i++ {
+			scopeSpans := resourceSpans.At(i).ScopeSpans()
+			for j := 0; j < scopeSpans.Len(); j++ {
+				spans := scopeSpans.At(j).Spans()
+				for k := 0; k < spans.Len(); k++ {
+					traceIDs = append(traceIDs, spans.At(k).TraceID().String())
+					spanIDs = append(spanIDs, spans.At(k).SpanID().String())
+				}
+			}
+		}
+	}))
+	defer srv.Close()
+
+	// NewConfig sets these from, and in, the environment.
+	t.Setenv("ELASTIC_APM_SERVER_URL", "")
+	t.Setenv("ELASTIC_APM_API_KEY", "")
+	var reported []TraceIDs
+	cfg := NewConfig(
+		WithAPMServerURL(srv.URL),
+		WithAPIKey("api_key"),
+		WithOTLPProtocol("http/protobuf"),
+		WithTraceIDsCallback(func(ids TraceIDs) {
+			reported = append(reported, ids)
+		}),
+	)
+	_, err := SendOTLPTrace(context.Background(), cfg)
+	require.NoError(t, err)
+	require.Len(t, reported, 1)
+
+	ids := reported[0]
+	assert.NotEmpty(t, ids.TraceID)
+	assert.Len(t, ids.TransactionIDs, 1) // the server span
+	assert.Len(t, ids.SpanIDs, 2)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.NotEmpty(t, traceIDs)
+	for _, traceID := range traceIDs {
+		assert.Equal(t, ids.TraceID, traceID)
+	}
+	assert.ElementsMatch(t, spanIDs, append(ids.TransactionIDs, ids.SpanIDs...))
+}
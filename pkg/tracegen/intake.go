@@ -33,11 +33,23 @@ import (
 
 // SendIntakeV2Trace generate a trace including a transaction, a span and an error
 func SendIntakeV2Trace(ctx context.Context, cfg Config) (apm.TraceContext, EventStats, error) {
+	var ids TraceIDs
+	traceContext, stats, err := sendIntakeV2Trace(ctx, cfg, &ids)
+	if err != nil {
+		return apm.TraceContext{}, EventStats{}, err
+	}
+	cfg.reportTraceIDs(ids)
+	return traceContext, stats, nil
+}
+
+// sendIntakeV2Trace is like SendIntakeV2Trace, additionally
+// recording the IDs of the generated events in ids.
+func sendIntakeV2Trace(ctx context.Context, cfg Config, ids *TraceIDs) (apm.TraceContext, EventStats, error) {
 	if err := cfg.validate(); err != nil {
 		return apm.TraceContext{}, EventStats{}, err
 	}
 
-	tracer, err := newTracer(cfg)
+	tracer, err := newTracer(ctx, cfg)
 	if err != nil {
 		return apm.TraceContext{}, EventStats{}, fmt.Errorf("failed to create tracer: %w", err)
 	}
@@ -50,6 +62,7 @@ func SendIntakeV2Trace(ctx context.Context, cfg Config) (apm.TraceContext, Event
 
 	traceContext := apm.TraceContext{
 		Trace:   cfg.traceID,
+		Span:    cfg.parentSpanID,
 		Options: apm.TraceOptions(0).WithRecorded(true),
 		State:   ts,
 	}
@@ -57,57 +70,148 @@ func SendIntakeV2Trace(ctx context.Context, cfg Config) (apm.TraceContext, Event
 	tx := tracer.StartTransactionOptions("parent-tx", "apmtool", apm.TransactionOptions{
 		TraceContext: traceContext,
 	})
+	ids.TraceID = tx.TraceContext().Trace.String()
+	ids.TransactionIDs = append(ids.TransactionIDs, tx.TraceContext().Span.String())
+	var spanIDs []apm.SpanID
+	if cfg.spanCount > 0 {
+		spanIDs = generateIntakeSpanTree(tracer, tx, cfg)
+	} else {
+		spanIDs = generateIntakeSpans(tracer, tx, cfg)
+	}
+	for _, id := range spanIDs {
+		ids.SpanIDs = append(ids.SpanIDs, id.String())
+	}
 
+	if err := cfg.flushWithTimeout(ctx, func(ctx context.Context) error {
+		tracer.Flush(ctx.Done())
+		return nil
+	}); err != nil {
+		return apm.TraceContext{}, EventStats{}, err
+	}
+	tracerStats := tracer.Stats()
+	stats := EventStats{
+		ExceptionsSent: int(tracerStats.ErrorsSent),
+		SpansSent:      int(tracerStats.SpansSent + tracerStats.TransactionsSent),
+	}
+
+	return tx.TraceContext(), stats, nil
+}
+
+// generateIntakeSpans generates a fixed set of spans and an error
+// within tx, and then ends tx, returning the IDs of the spans.
+func generateIntakeSpans(tracer *apm.Tracer, tx *apm.Transaction, cfg Config) []apm.SpanID {
 	span := tx.StartSpanOptions("parent-span", "apmtool", apm.SpanOptions{
 		Parent: tx.TraceContext(),
 	})
 
-	exit := tx.StartSpanOptions("exit-span", "apmtool", apm.SpanOptions{
-		Parent:   span.TraceContext(),
-		ExitSpan: true,
-	})
-
-	exit.Context.SetServiceTarget(apm.ServiceTargetSpanContext{
+	exitSpanType := "apmtool"
+	exitSpanTarget := apm.ServiceTargetSpanContext{
 		Type: "service_type",
 		Name: "service_name",
+	}
+	kind, hasKind := exitSpanKinds[cfg.exitSpanKind]
+	if hasKind {
+		exitSpanType = kind.spanType
+		exitSpanTarget = kind.target
+	}
+	exit := tx.StartSpanOptions("exit-span", exitSpanType, apm.SpanOptions{
+		Parent:   span.TraceContext(),
+		ExitSpan: true,
 	})
+	if hasKind {
+		kind.setContext(&exit.Context)
+	}
+	exit.Context.SetServiceTarget(exitSpanTarget)
+	spanIDs := []apm.SpanID{span.TraceContext().Span, exit.TraceContext().Span}
 
-	exit.Duration = 999 * time.Millisecond
+	// Cap child durations so that each span ends within its parent.
+	txDuration := durationOrDefault(cfg.parentDuration, 2*time.Second)
+	spanDuration := min(durationOrDefault(cfg.childDuration, time.Second), txDuration)
+	exit.Duration = min(durationOrDefault(cfg.exitDuration, 999*time.Millisecond), spanDuration)
 	exit.Outcome = "failure"
 
-	// error
-	e := tracer.NewError(errors.New("timeout"))
-	e.Culprit = "timeout"
-	e.SetSpan(exit)
-	e.Send()
+	if !cfg.disableExceptions {
+		e := tracer.NewError(errors.New("timeout"))
+		e.Culprit = "timeout"
+		e.SetSpan(exit)
+		e.Send()
+	}
 	exit.End()
 
-	span.Duration = time.Second
+	span.Duration = spanDuration
 	span.Outcome = "success"
 	span.End()
-	tx.Duration = 2 * time.Second
-	tx.Outcome = "success"
+	tx.Duration = txDuration
+	tx.Outcome = intakeOutcome(cfg)
 	tx.End()
+	return spanIDs
+}
 
-	tracer.Flush(ctx.Done())
-	tracerStats := tracer.Stats()
-	stats := EventStats{
-		ExceptionsSent: int(tracerStats.ErrorsSent),
-		SpansSent:      int(tracerStats.SpansSent + tracerStats.TransactionsSent),
+// intakeOutcome returns the outcome for a generated
+// transaction, defaulting to success.
+func intakeOutcome(cfg Config) string {
+	if outcome := cfg.outcome(); outcome != "" {
+		return outcome
 	}
+	return "success"
+}
 
-	return tx.TraceContext(), stats, nil
+// generateIntakeSpanTree generates spans in a tree shaped according
+// to cfg, rooted at tx, and then ends tx, returning the IDs of the spans.
+func generateIntakeSpanTree(tracer *apm.Tracer, tx *apm.Transaction, cfg Config) []apm.SpanID {
+	start := time.Now()
+	tree := newSpanTree(cfg.spanCount, cfg.spanDepth, cfg.errorRate)
+	spans := make([]*apm.Span, len(tree))
+	for i, s := range tree {
+		if s.parent < 0 {
+			continue // root is the transaction
+		}
+		parent := tx.TraceContext()
+		if s.parent > 0 {
+			parent = spans[s.parent].TraceContext()
+		}
+		spans[i] = tx.StartSpanOptions(fmt.Sprintf("child%d", i), "apmtool", apm.SpanOptions{
+			Parent: parent,
+			Start:  start.Add(s.start),
+		})
+		spans[i].Duration = s.end - s.start
+		spans[i].Outcome = "success"
+		if s.error {
+			if !cfg.disableExceptions {
+				e := tracer.NewError(errors.New("an exception occurred"))
+				e.SetSpan(spans[i])
+				e.Send()
+			}
+			spans[i].Outcome = "failure"
+		}
+	}
+	// End children before their parents.
+	spanIDs := make([]apm.SpanID, 0, len(spans)-1)
+	for i := len(spans) - 1; i > 0; i-- {
+		spanIDs = append(spanIDs, spans[i].TraceContext().Span)
+		spans[i].End()
+	}
+	tx.Duration = spanTreeDuration
+	tx.Outcome = intakeOutcome(cfg)
+	tx.End()
+	return spanIDs
 }
 
-func newTracer(cfg Config) (*apm.Tracer, error) {
-	apmServerURL, err := url.Parse(cfg.apmServerURL)
+func newTracer(ctx context.Context, cfg Config) (*apm.Tracer, error) {
+	rawURL, err := cfg.selectAPMServerURL(ctx)
+	if err != nil {
+		return nil, err
+	}
+	apmServerURL, err := url.Parse(rawURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse endpoint: %w", err)
 	}
 
 	var apmServerTLSConfig *tls.Config
-	if cfg.insecure {
-		apmServerTLSConfig = &tls.Config{InsecureSkipVerify: true}
+	if cfg.insecure || cfg.certFile != "" || cfg.caFile != "" {
+		if apmServerTLSConfig, err = cfg.tlsConfig(); err != nil {
+			return nil, err
+		}
 	}
 
 	apmTransport, err := transport.NewHTTPTransport(transport.HTTPTransportOptions{
@@ -119,9 +223,14 @@ func newTracer(cfg Config) (*apm.Tracer, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create APM transport: %w", err)
 	}
+	serviceVersion := cfg.serviceVersion
+	if serviceVersion == "" {
+		serviceVersion = "0.0.1"
+	}
 	return apm.NewTracerOptions(apm.TracerOptions{
-		ServiceName:    cfg.apmServiceName,
-		ServiceVersion: "0.0.1",
-		Transport:      apmTransport,
+		ServiceName:        cfg.apmServiceName,
+		ServiceVersion:     serviceVersion,
+		ServiceEnvironment: cfg.serviceEnv,
+		Transport:          apmTransport,
 	})
 }
@@ -0,0 +1,129 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tracegen
+
+import (
+	"bufio"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestNewTracerServiceMetadata(t *testing.T) {
+	// A fake intake endpoint, recording the metadata
+	// line at the start of each request body.
+	metadata := make(chan []byte, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/intake/v2/events" {
+			http.NotFound(w, r)
+			return
+		}
+		zr, err := zlib.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer zr.Close()
+		line, err := bufio.NewReader(zr).ReadBytes('\n')
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		metadata <- line
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	// NewConfig sets these from, and in, the environment.
+	t.Setenv("ELASTIC_APM_SERVER_URL", "")
+	t.Setenv("ELASTIC_APM_API_KEY", "")
+	t.Setenv("ELASTIC_APM_ENVIRONMENT", "")
+	for name, tc := range map[string]struct {
+		opts                 []ConfigOption
+		version, environment string
+	}{
+		"default": {
+			version: "0.0.1",
+		},
+		"configured": {
+			opts:        []ConfigOption{WithServiceVersion("1.2.3"), WithServiceEnvironment("staging")},
+			version:     "1.2.3",
+			environment: "staging",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg := NewConfig(append([]ConfigOption{
+				WithAPMServerURL(srv.URL),
+				WithAPIKey("api_key"),
+				WithElasticAPMServiceName("tracegen"),
+			}, tc.opts...)...)
+			tracer, err := newTracer(context.Background(), cfg)
+			require.NoError(t, err)
+			defer tracer.Close()
+			tracer.StartTransaction("name", "type").End()
+			tracer.Flush(nil)
+
+			var line []byte
+			select {
+			case line = <-metadata:
+			case <-time.After(10 * time.Second):
+				t.Fatal("timed out waiting for intake request")
+			}
+			var decoded struct {
+				Metadata struct {
+					Service struct {
+						Name        string `json:"name"`
+						Version     string `json:"version"`
+						Environment string `json:"environment"`
+					} `json:"service"`
+				} `json:"metadata"`
+			}
+			require.NoError(t, json.Unmarshal(line, &decoded))
+			service := decoded.Metadata.Service
+			assert.Equal(t, "tracegen", service.Name)
+			assert.Equal(t, tc.version, service.Version)
+			assert.Equal(t, tc.environment, service.Environment)
+		})
+	}
+}
+
+func TestOTLPResourceAttributes(t *testing.T) {
+	cfg := NewConfig(
+		WithOTLPServiceName("tracegen"),
+		WithServiceVersion("1.2.3"),
+		WithServiceEnvironment("staging"),
+	)
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String("service.name", "tracegen"),
+		attribute.String("service.version", "1.2.3"),
+		attribute.String("deployment.environment", "staging"),
+	}, cfg.otlpResourceAttributes())
+
+	cfg = NewConfig(WithOTLPServiceName("tracegen"))
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String("service.name", "tracegen"),
+	}, cfg.otlpResourceAttributes())
+}
@@ -0,0 +1,71 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tracegen
+
+import (
+	"fmt"
+	"text/template"
+
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+const (
+	defaultLogSeverity = "fatal"
+	defaultLogBody     = "sample body value"
+)
+
+// logSeverities maps the log severities accepted by
+// WithLogSeverities to OpenTelemetry severity numbers.
+var logSeverities = map[string]plog.SeverityNumber{
+	"trace": plog.SeverityNumberTrace,
+	"debug": plog.SeverityNumberDebug,
+	"info":  plog.SeverityNumberInfo,
+	"warn":  plog.SeverityNumberWarn,
+	"error": plog.SeverityNumberError,
+	"fatal": plog.SeverityNumberFatal,
+}
+
+// logRecord holds the fields that may be referred
+// to by the template specified with WithLogBody.
+type logRecord struct {
+	Index    int
+	Severity string
+}
+
+// logBodyTemplate parses the log body template specified
+// with WithLogBody, or the default body if unspecified.
+func (cfg Config) logBodyTemplate() (*template.Template, error) {
+	body := cfg.logBody
+	if body == "" {
+		body = defaultLogBody
+	}
+	tmpl, err := template.New("body").Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid log body template provided: %w", err)
+	}
+	return tmpl, nil
+}
+
+// logSeverity returns the severity of the i'th generated log record,
+// cycling through the severities specified with WithLogSeverities.
+func (cfg Config) logSeverity(i int) string {
+	if len(cfg.logSeverities) == 0 {
+		return defaultLogSeverity
+	}
+	return cfg.logSeverities[i%len(cfg.logSeverities)]
+}
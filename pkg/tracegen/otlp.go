@@ -18,11 +18,14 @@
 package tracegen
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
-	"crypto/tls"
 	"errors"
 	"fmt"
+	"io"
 	"net"
+	"net/http"
 	"net/url"
 	"time"
 
@@ -31,6 +34,7 @@ import (
 	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
@@ -38,21 +42,41 @@ import (
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/genproto/googleapis/rpc/status"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 	grpcinsecure "google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/elastic/apm-tools/internal/apmconn"
 )
 
 // SendOTLPTrace sends spans, error and logs to the configured APM Server
 // If distributed tracing is needed, you might want to set up the propagator
 // using SetOTLPTracePropagator function before calling this function
 func SendOTLPTrace(ctx context.Context, cfg Config) (EventStats, error) {
+	var ids TraceIDs
+	stats, err := sendOTLPTrace(ctx, cfg, &ids)
+	if err != nil {
+		return EventStats{}, err
+	}
+	cfg.reportTraceIDs(ids)
+	return stats, nil
+}
+
+// sendOTLPTrace is like SendOTLPTrace, additionally
+// recording the IDs of the generated spans in ids.
+func sendOTLPTrace(ctx context.Context, cfg Config, ids *TraceIDs) (EventStats, error) {
 	if err := cfg.validate(); err != nil {
 		return EventStats{}, err
 	}
 
-	endpointURL, err := url.Parse(cfg.apmServerURL)
+	rawURL, err := cfg.selectAPMServerURL(ctx)
+	if err != nil {
+		return EventStats{}, err
+	}
+	endpointURL, err := url.Parse(rawURL)
 	if err != nil {
 		return EventStats{}, fmt.Errorf("failed to parse endpoint: %w", err)
 	}
@@ -65,8 +89,6 @@ func SendOTLPTrace(ctx context.Context, cfg Config) (EventStats, error) {
 		if endpointURL.Port() == "" {
 			endpointURL.Host = net.JoinHostPort(endpointURL.Host, "443")
 		}
-	default:
-		return EventStats{}, fmt.Errorf("endpoint must be prefixed with http:// or https://")
 	}
 
 	otlpExporters, err := newOTLPExporters(ctx, endpointURL, cfg)
@@ -75,62 +97,183 @@ func SendOTLPTrace(ctx context.Context, cfg Config) (EventStats, error) {
 	}
 	defer otlpExporters.cleanup(ctx)
 
-	resource := resource.NewSchemaless(
-		attribute.String("service.name", cfg.otlpServiceName),
-	)
+	resource := resource.NewSchemaless(cfg.otlpResourceAttributes()...)
+	var recorder spanIDRecorder
 	tracerProvider := sdktrace.NewTracerProvider(
 		sdktrace.WithSyncer(otlpExporters.trace),
+		sdktrace.WithSpanProcessor(&recorder),
 		sdktrace.WithResource(resource),
 	)
 
 	// generateSpans returns ctx that contains trace context
 	var stats EventStats
-	ctx, err = generateSpans(ctx, tracerProvider.Tracer("tracegen"), &stats)
+	ctx, err = generateSpans(ctx, tracerProvider.Tracer("tracegen"), cfg, &stats)
 	if err != nil {
 		return EventStats{}, err
 	}
-	if err := generateLogs(ctx, otlpExporters.log, resource, &stats); err != nil {
+	recorder.addTraceIDs(trace.SpanContextFromContext(ctx).TraceID(), ids)
+	if err := generateLogs(ctx, otlpExporters.log, resource, cfg, &stats); err != nil {
 		return EventStats{}, err
 	}
 
 	// Shutdown, flushing all data to the server.
-	if err := tracerProvider.Shutdown(ctx); err != nil {
-		return EventStats{}, err
-	}
-	if err := otlpExporters.cleanup(ctx); err != nil {
+	if err := cfg.flushWithTimeout(ctx, func(ctx context.Context) error {
+		if err := tracerProvider.Shutdown(ctx); err != nil {
+			return err
+		}
+		return otlpExporters.cleanup(ctx)
+	}); err != nil {
 		return EventStats{}, err
 	}
 	return stats, nil
 }
 
-func generateSpans(ctx context.Context, tracer trace.Tracer, stats *EventStats) (context.Context, error) {
+// otlpResourceAttributes returns the OpenTelemetry
+// resource attributes describing the service.
+func (cfg Config) otlpResourceAttributes() []attribute.KeyValue {
+	attrs := []attribute.KeyValue{attribute.String("service.name", cfg.otlpServiceName)}
+	if cfg.serviceVersion != "" {
+		attrs = append(attrs, attribute.String("service.version", cfg.serviceVersion))
+	}
+	if cfg.serviceEnv != "" {
+		attrs = append(attrs, attribute.String("deployment.environment", cfg.serviceEnv))
+	}
+	return attrs
+}
+
+func generateSpans(ctx context.Context, tracer trace.Tracer, cfg Config, stats *EventStats) (context.Context, error) {
+	links := generateLinkedSpans(ctx, tracer, cfg.spanLinks, stats)
+	if cfg.spanCount > 0 {
+		return generateSpanTree(ctx, tracer, cfg, links, stats)
+	}
+
 	now := time.Now()
 	ctx, parent := tracer.Start(ctx,
 		"parent",
 		trace.WithSpanKind(trace.SpanKindServer),
 		trace.WithTimestamp(now),
+		trace.WithLinks(links...),
 	)
-	defer parent.End(trace.WithTimestamp(now.Add(time.Millisecond * 1500)))
+	setOTLPSpanOutcome(parent, cfg.outcome())
+	// Child offsets scale with the parent duration, and child
+	// durations are capped, so children end within the parent.
+	parentDuration := durationOrDefault(cfg.parentDuration, 1500*time.Millisecond)
+	defer parent.End(trace.WithTimestamp(now.Add(parentDuration)))
 	stats.SpansSent++
 
-	_, child1 := tracer.Start(ctx, "child1", trace.WithTimestamp(now.Add(time.Millisecond*500)))
+	child1Offset := parentDuration / 3
+	child1Duration := min(durationOrDefault(cfg.childDuration, parentDuration/3), parentDuration-child1Offset)
+	child1Start := now.Add(child1Offset)
+	_, child1 := tracer.Start(ctx, "child1", trace.WithTimestamp(child1Start))
 	time.Sleep(10 * time.Millisecond)
-	child1.AddEvent("an arbitrary event")
-	child1.End(trace.WithTimestamp(now.Add(time.Second * 1)))
+	if !cfg.disableEvents {
+		child1.AddEvent("an arbitrary event")
+		stats.LogsSent++ // span event is captured as a log
+	}
+	child1.End(trace.WithTimestamp(child1Start.Add(child1Duration)))
 	stats.SpansSent++
-	stats.LogsSent++ // span event is captured as a log
 
-	_, child2 := tracer.Start(ctx, "child2", trace.WithTimestamp(now.Add(time.Millisecond*600)))
+	child2Offset := parentDuration * 2 / 5
+	child2Duration := min(durationOrDefault(cfg.exitDuration, parentDuration*7/15), parentDuration-child2Offset)
+	child2Start := now.Add(child2Offset)
+	child2Opts := []trace.SpanStartOption{trace.WithTimestamp(child2Start)}
+	if kind, ok := exitSpanKinds[cfg.exitSpanKind]; ok {
+		child2Opts = append(child2Opts,
+			trace.WithSpanKind(kind.otelSpanKind),
+			trace.WithAttributes(kind.otelAttributes...),
+		)
+	}
+	_, child2 := tracer.Start(ctx, "child2", child2Opts...)
 	time.Sleep(10 * time.Millisecond)
-	child2.RecordError(errors.New("an exception occurred"))
-	child2.End(trace.WithTimestamp(now.Add(time.Millisecond * 1300)))
+	if !cfg.disableExceptions {
+		child2.RecordError(errors.New("an exception occurred"))
+		stats.ExceptionsSent++ // error captured as an error/exception log event
+	}
+	child2.End(trace.WithTimestamp(child2Start.Add(child2Duration)))
 	stats.SpansSent++
-	stats.ExceptionsSent++ // error captured as an error/exception log event
 
 	return ctx, nil
 }
 
-func generateLogs(ctx context.Context, logger otlplogExporter, res *resource.Resource, stats *EventStats) error {
+// setOTLPSpanOutcome sets the status of span according to the given
+// Elastic APM outcome, leaving it unset if outcome is empty or unknown.
+func setOTLPSpanOutcome(span trace.Span, outcome string) {
+	switch outcome {
+	case "success":
+		span.SetStatus(codes.Ok, "")
+	case "failure":
+		span.SetStatus(codes.Error, "failure")
+	}
+}
+
+// generateLinkedSpans generates n short-lived spans, each in a new trace,
+// returning links to them for linking from another span.
+func generateLinkedSpans(ctx context.Context, tracer trace.Tracer, n int, stats *EventStats) []trace.Link {
+	links := make([]trace.Link, 0, n)
+	now := time.Now()
+	for i := 0; i < n; i++ {
+		_, span := tracer.Start(ctx, fmt.Sprintf("linked%d", i),
+			trace.WithNewRoot(),
+			trace.WithSpanKind(trace.SpanKindProducer),
+			trace.WithTimestamp(now),
+		)
+		span.End(trace.WithTimestamp(now.Add(10 * time.Millisecond)))
+		links = append(links, trace.Link{SpanContext: span.SpanContext()})
+		stats.SpansSent++
+		stats.SpanLinksSent++
+	}
+	return links
+}
+
+// generateSpanTree generates spans in a tree shaped according
+// to cfg, returning a ctx that contains the root span.
+func generateSpanTree(
+	ctx context.Context, tracer trace.Tracer, cfg Config,
+	links []trace.Link, stats *EventStats,
+) (context.Context, error) {
+	now := time.Now()
+	tree := newSpanTree(cfg.spanCount, cfg.spanDepth, cfg.errorRate)
+	spanContexts := make([]context.Context, len(tree))
+	spans := make([]trace.Span, len(tree))
+	for i, s := range tree {
+		opts := []trace.SpanStartOption{trace.WithTimestamp(now.Add(s.start))}
+		parentCtx := ctx
+		name := "parent"
+		if s.parent >= 0 {
+			parentCtx = spanContexts[s.parent]
+			name = fmt.Sprintf("child%d", i)
+		} else {
+			opts = append(opts,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithLinks(links...),
+			)
+		}
+		spanContexts[i], spans[i] = tracer.Start(parentCtx, name, opts...)
+		if s.parent < 0 {
+			setOTLPSpanOutcome(spans[i], cfg.outcome())
+		}
+		if s.error && !cfg.disableExceptions {
+			spans[i].RecordError(errors.New("an exception occurred"))
+			stats.ExceptionsSent++
+		}
+	}
+	// End children before their parents.
+	for i := len(tree) - 1; i >= 0; i-- {
+		spans[i].End(trace.WithTimestamp(now.Add(tree[i].end)))
+		stats.SpansSent++
+	}
+	return spanContexts[0], nil
+}
+
+func generateLogs(ctx context.Context, logger otlplogExporter, res *resource.Resource, cfg Config, stats *EventStats) error {
+	if cfg.disableStandaloneLogs {
+		return nil
+	}
+	body, err := cfg.logBodyTemplate()
+	if err != nil {
+		return err
+	}
+
 	logs := plog.NewLogs()
 	rl := logs.ResourceLogs().AppendEmpty()
 	attribs := rl.Resource().Attributes()
@@ -145,12 +288,23 @@ func generateLogs(ctx context.Context, logger otlplogExporter, res *resource.Res
 	}
 
 	sl := rl.ScopeLogs().AppendEmpty().LogRecords()
-	record := sl.AppendEmpty()
-	record.Body().SetStr("sample body value")
-	record.SetTimestamp(pcommon.NewTimestampFromTime(time.Now()))
-	record.SetSeverityNumber(plog.SeverityNumberFatal)
-	record.SetSeverityText("fatal")
-	stats.LogsSent++
+	now := pcommon.NewTimestampFromTime(time.Now())
+	for i := 0; i < cfg.logCount; i++ {
+		severity := cfg.logSeverity(i)
+		var buf bytes.Buffer
+		if err := body.Execute(&buf, logRecord{Index: i, Severity: severity}); err != nil {
+			return fmt.Errorf("failed to render log body: %w", err)
+		}
+		record := sl.AppendEmpty()
+		record.Body().SetStr(buf.String())
+		record.SetTimestamp(now)
+		record.SetSeverityNumber(logSeverities[severity])
+		record.SetSeverityText(severity)
+		stats.LogsSent++
+	}
+	if sl.Len() == 0 {
+		return nil
+	}
 	return logger.Export(ctx, logs)
 }
 
@@ -167,7 +321,7 @@ func newOTLPExporters(ctx context.Context, endpointURL *url.URL, cfg Config) (*o
 	case "http/protobuf":
 		return newOTLPHTTPExporters(ctx, endpointURL, cfg)
 	default:
-		return nil, fmt.Errorf("invalid protocol %q", cfg.otlpProtocol)
+		return nil, fmt.Errorf("%w %q", ErrInvalidOTLPProtocol, cfg.otlpProtocol)
 	}
 }
 
@@ -179,14 +333,14 @@ func newOTLPGRPCExporters(ctx context.Context, endpointURL *url.URL, cfg Config)
 		// If http:// is specified, then use insecure (plaintext).
 		transportCredentials = grpcinsecure.NewCredentials()
 	case "https":
-		transportCredentials = credentials.NewTLS(&tls.Config{InsecureSkipVerify: cfg.insecure})
+		tlsConfig, err := cfg.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		transportCredentials = credentials.NewTLS(tlsConfig)
 	}
 
-	grpcConn, err := grpc.NewClient(
-		endpointURL.Host,
-		grpc.WithTransportCredentials(transportCredentials),
-		grpc.WithDefaultCallOptions(grpc.UseCompressor("gzip")),
-	)
+	grpcConn, err := grpc.NewClient(endpointURL.Host, cfg.grpcOptions().DialOptions(transportCredentials)...)
 	if err != nil {
 		return nil, err
 	}
@@ -218,7 +372,10 @@ func newOTLPGRPCExporters(ctx context.Context, endpointURL *url.URL, cfg Config)
 }
 
 func newOTLPHTTPExporters(ctx context.Context, endpointURL *url.URL, cfg Config) (*otlpExporters, error) {
-	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.insecure}
+	tlsConfig, err := cfg.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
 	traceOptions := []otlptracehttp.Option{
 		otlptracehttp.WithEndpoint(endpointURL.Host),
 		otlptracehttp.WithTLSClientConfig(tlsConfig),
@@ -226,6 +383,11 @@ func newOTLPHTTPExporters(ctx context.Context, endpointURL *url.URL, cfg Config)
 	if endpointURL.Scheme == "http" {
 		traceOptions = append(traceOptions, otlptracehttp.WithInsecure())
 	}
+	proxy, err := apmconn.ProxyFunc(cfg.proxyURL)
+	if err != nil {
+		return nil, err
+	}
+	traceOptions = append(traceOptions, otlptracehttp.WithProxy(proxy))
 
 	headers := map[string]string{"Authorization": "ApiKey " + cfg.apiKey}
 	traceOptions = append(traceOptions, otlptracehttp.WithHeaders(headers))
@@ -239,10 +401,18 @@ func newOTLPHTTPExporters(ctx context.Context, endpointURL *url.URL, cfg Config)
 	}
 	cleanup = combineCleanup(otlpTraceExporter.Shutdown, cleanup)
 
+	logsURL := url.URL{Scheme: endpointURL.Scheme, Host: endpointURL.Host, Path: "/v1/logs"}
+	httpTransport := http.DefaultTransport.(*http.Transport).Clone()
+	httpTransport.TLSClientConfig = tlsConfig
+	httpTransport.Proxy = proxy
 	return &otlpExporters{
 		cleanup: cleanup,
 		trace:   otlpTraceExporter,
-		log:     &otlploghttpExporter{},
+		log: &otlploghttpExporter{
+			client:   &http.Client{Transport: httpTransport},
+			endpoint: logsURL.String(),
+			headers:  headers,
+		},
 	}, nil
 }
 
@@ -270,22 +440,91 @@ func (e *otlploggrpcExporter) Export(ctx context.Context, logs plog.Logs) error
 	md := metadata.New(e.headers)
 	ctx = metadata.NewOutgoingContext(ctx, md)
 
-	_, err := e.client.Export(ctx, req)
+	resp, err := e.client.Export(ctx, req)
 	if err != nil {
 		return err
 	}
-
-	// TODO: parse response for error
-	return nil
+	return checkLogsPartialSuccess(resp)
 }
 
 // otlploghttpExporter is a simple synchronous log exporter using protobuf over HTTP
 type otlploghttpExporter struct {
+	client   *http.Client
+	endpoint string
+	headers  map[string]string
 }
 
 func (e *otlploghttpExporter) Export(ctx context.Context, logs plog.Logs) error {
-	// TODO: implement
-	return errors.New("otlploghttpExporter isn't implemented")
+	req := plogotlp.NewExportRequestFromLogs(logs)
+	data, err := req.MarshalProto()
+	if err != nil {
+		return fmt.Errorf("failed to encode logs: %w", err)
+	}
+
+	var body bytes.Buffer
+	gzipWriter := gzip.NewWriter(&body)
+	if _, err := gzipWriter.Write(data); err != nil {
+		return fmt.Errorf("failed to compress logs: %w", err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return fmt.Errorf("failed to compress logs: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, &body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	for k, v := range e.headers {
+		httpReq.Header.Set(k, v)
+	}
+	httpReq.Header.Set("Content-Type", "application/x-protobuf")
+	httpReq.Header.Set("Content-Encoding", "gzip")
+
+	resp, err := e.client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to export logs: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to export logs: server responded with %q: %s",
+			resp.Status, otlpHTTPStatusMessage(resp.Header.Get("Content-Type"), respBody),
+		)
+	}
+	exportResp := plogotlp.NewExportResponse()
+	if err := exportResp.UnmarshalProto(respBody); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return checkLogsPartialSuccess(exportResp)
+}
+
+// checkLogsPartialSuccess returns an error if the server
+// rejected any of the exported log records.
+func checkLogsPartialSuccess(resp plogotlp.ExportResponse) error {
+	partialSuccess := resp.PartialSuccess()
+	if rejected := partialSuccess.RejectedLogRecords(); rejected > 0 {
+		return fmt.Errorf(
+			"server rejected %d log record(s): %s",
+			rejected, partialSuccess.ErrorMessage(),
+		)
+	}
+	return nil
+}
+
+// otlpHTTPStatusMessage returns the message of the google.rpc.Status
+// encoded in an OTLP/HTTP error response body, or otherwise the body
+// itself if it could not be decoded.
+func otlpHTTPStatusMessage(contentType string, body []byte) string {
+	if contentType == "application/x-protobuf" {
+		var st status.Status
+		if err := proto.Unmarshal(body, &st); err == nil {
+			return st.GetMessage()
+		}
+	}
+	return string(body)
 }
 
 func SetOTLPTracePropagator(ctx context.Context, traceparent string, tracestate string) context.Context {
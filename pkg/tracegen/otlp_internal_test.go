@@ -0,0 +1,156 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tracegen
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/grpc"
+	grpcinsecure "google.golang.org/grpc/credentials/insecure"
+
+	"github.com/elastic/apm-tools/internal/apmconn"
+)
+
+func TestOTLPLogHTTPExporterPartialSuccess(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/logs", r.URL.Path)
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		assert.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+		assert.Equal(t, "ApiKey abc123", r.Header.Get("Authorization"))
+
+		resp := newPartialSuccessResponse()
+		data, err := resp.MarshalProto()
+		require.NoError(t, err)
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		w.Write(data)
+	}))
+	defer srv.Close()
+
+	exporter := &otlploghttpExporter{
+		client:   srv.Client(),
+		endpoint: srv.URL + "/v1/logs",
+		headers:  map[string]string{"Authorization": "ApiKey abc123"},
+	}
+	err := exporter.Export(context.Background(), newTestLogs())
+	assert.EqualError(t, err, "server rejected 1 log record(s): too many logs")
+}
+
+func TestOTLPLogGRPCExporterPartialSuccess(t *testing.T) {
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	srv := grpc.NewServer()
+	plogotlp.RegisterGRPCServer(srv, &partialSuccessLogsServer{})
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	conn, err := grpc.NewClient(lis.Addr().String(),
+		grpc.WithTransportCredentials(grpcinsecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	exporter := &otlploggrpcExporter{client: plogotlp.NewGRPCClient(conn)}
+	err = exporter.Export(context.Background(), newTestLogs())
+	assert.EqualError(t, err, "server rejected 1 log record(s): too many logs")
+}
+
+func TestOTLPHTTPExportersProxy(t *testing.T) {
+	var mu sync.Mutex
+	var requests []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, r.URL.String())
+		w.Header().Set("Content-Type", "application/x-protobuf")
+	}))
+	defer proxy.Close()
+
+	endpointURL, err := url.Parse("http://apm.invalid:8200")
+	require.NoError(t, err)
+	cfg := NewConfig(WithAPIKey("abc123"), WithProxyURL(proxy.URL))
+	exporters, err := newOTLPHTTPExporters(context.Background(), endpointURL, cfg)
+	require.NoError(t, err)
+	defer exporters.cleanup(context.Background())
+
+	spans := tracetest.SpanStubs{{Name: "span"}}.Snapshots()
+	require.NoError(t, exporters.trace.ExportSpans(context.Background(), spans))
+	require.NoError(t, exporters.log.Export(context.Background(), newTestLogs()))
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{
+		"http://apm.invalid:8200/v1/traces",
+		"http://apm.invalid:8200/v1/logs",
+	}, requests)
+}
+
+func TestTLSConfig(t *testing.T) {
+	tlsConfig, err := NewConfig(WithInsecureConn(true)).tlsConfig()
+	require.NoError(t, err)
+	assert.True(t, tlsConfig.InsecureSkipVerify)
+
+	_, err = NewConfig(WithClientCert("cert.pem", "missing.pem")).tlsConfig()
+	assert.ErrorContains(t, err, "failed to load client certificate")
+	_, err = NewConfig(WithCACert("missing.pem")).tlsConfig()
+	assert.ErrorContains(t, err, "failed to read CA certificate")
+}
+
+func TestGRPCOptions(t *testing.T) {
+	cfg := NewConfig(WithGRPCKeepalive(30*time.Second, 5*time.Second), WithDialTimeout(3*time.Second))
+	assert.Equal(t, apmconn.GRPCOptions{
+		KeepaliveTime:    30 * time.Second,
+		KeepaliveTimeout: 5 * time.Second,
+		DialTimeout:      3 * time.Second,
+	}, cfg.grpcOptions())
+	assert.Equal(t, apmconn.GRPCOptions{}, NewConfig().grpcOptions())
+}
+
+type partialSuccessLogsServer struct {
+	plogotlp.UnimplementedGRPCServer
+}
+
+func (*partialSuccessLogsServer) Export(context.Context, plogotlp.ExportRequest) (plogotlp.ExportResponse, error) {
+	return newPartialSuccessResponse(), nil
+}
+
+func newPartialSuccessResponse() plogotlp.ExportResponse {
+	resp := plogotlp.NewExportResponse()
+	resp.PartialSuccess().SetRejectedLogRecords(1)
+	resp.PartialSuccess().SetErrorMessage("too many logs")
+	return resp
+}
+
+func newTestLogs() plog.Logs {
+	logs := plog.NewLogs()
+	records := logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords()
+	records.AppendEmpty().Body().SetStr("first")
+	records.AppendEmpty().Body().SetStr("second")
+	return logs
+}
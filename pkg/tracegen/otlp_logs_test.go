@@ -0,0 +1,117 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tracegen
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// recordingLogExporter is an otlplogExporter that records exported logs.
+type recordingLogExporter struct {
+	logs []plog.Logs
+}
+
+func (e *recordingLogExporter) Export(ctx context.Context, logs plog.Logs) error {
+	e.logs = append(e.logs, logs)
+	return nil
+}
+
+func TestGenerateLogsDefault(t *testing.T) {
+	records, stats := generateTestLogs(t, NewConfig())
+	require.Len(t, records, 1)
+	assert.Equal(t, "sample body value", records[0].Body().Str())
+	assert.Equal(t, plog.SeverityNumberFatal, records[0].SeverityNumber())
+	assert.Equal(t, "fatal", records[0].SeverityText())
+	assert.Equal(t, EventStats{LogsSent: 1}, stats)
+}
+
+func TestGenerateLogsSeverities(t *testing.T) {
+	records, stats := generateTestLogs(t, NewConfig(
+		WithLogCount(5),
+		WithLogSeverities([]string{"debug", "info", "error"}),
+		WithLogBody("{{.Severity}} log record {{.Index}}"),
+	))
+	require.Len(t, records, 5)
+	assert.Equal(t, EventStats{LogsSent: 5}, stats)
+
+	expected := []struct {
+		severity string
+		number   plog.SeverityNumber
+	}{
+		{"debug", plog.SeverityNumberDebug},
+		{"info", plog.SeverityNumberInfo},
+		{"error", plog.SeverityNumberError},
+		{"debug", plog.SeverityNumberDebug},
+		{"info", plog.SeverityNumberInfo},
+	}
+	for i, record := range records {
+		assert.Equal(t, expected[i].severity, record.SeverityText())
+		assert.Equal(t, expected[i].number, record.SeverityNumber())
+		assert.Equal(t, fmt.Sprintf("%s log record %d", expected[i].severity, i), record.Body().Str())
+	}
+}
+
+func TestGenerateLogsZeroCount(t *testing.T) {
+	records, stats := generateTestLogs(t, NewConfig(WithLogCount(0)))
+	assert.Empty(t, records)
+	assert.Equal(t, EventStats{}, stats)
+}
+
+func TestLogConfigInvalid(t *testing.T) {
+	t.Setenv("ELASTIC_APM_SERVER_URL", "")
+	t.Setenv("ELASTIC_APM_API_KEY", "")
+	for name, opt := range map[string]ConfigOption{
+		"count":    WithLogCount(-1),
+		"severity": WithLogSeverities([]string{"info", "critical"}),
+		"body":     WithLogBody("{{.Index"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg := NewConfig(WithAPMServerURL("http://localhost:8200"), WithAPIKey("api_key"), opt)
+			assert.Error(t, cfg.validate())
+		})
+	}
+}
+
+func generateTestLogs(t testing.TB, cfg Config) ([]plog.LogRecord, EventStats) {
+	var exporter recordingLogExporter
+	var stats EventStats
+	err := generateLogs(context.Background(), &exporter, resource.Empty(), cfg, &stats)
+	require.NoError(t, err)
+
+	var records []plog.LogRecord
+	for _, logs := range exporter.logs {
+		resourceLogs := logs.ResourceLogs()
+		for i := 0; i < resourceLogs.Len(); i++ {
+			scopeLogs := resourceLogs.At(i).ScopeLogs()
+			for j := 0; j < scopeLogs.Len(); j++ {
+				logRecords := scopeLogs.At(j).LogRecords()
+				for k := 0; k < logRecords.Len(); k++ {
+					records = append(records, logRecords.At(k))
+				}
+			}
+		}
+	}
+	return records, stats
+}
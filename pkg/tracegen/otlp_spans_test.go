@@ -0,0 +1,193 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tracegen
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestGenerateSpansDefault(t *testing.T) {
+	spans, stats := generateTestSpans(t, NewConfig())
+	assert.Len(t, spans, 3)
+	assert.Equal(t, EventStats{SpansSent: 3, ExceptionsSent: 1, LogsSent: 1}, stats)
+}
+
+func TestGenerateSpansSpanCount(t *testing.T) {
+	spans, stats := generateTestSpans(t, NewConfig(WithSpanCount(50), WithSpanDepth(4)))
+	assert.Len(t, spans, 50)
+	assert.Equal(t, EventStats{SpansSent: 50}, stats)
+
+	// Check the depth of the tree, and that all spans are in one trace.
+	depths := make(map[[8]byte]int)
+	var maxDepth int
+	for i := len(spans) - 1; i >= 0; i-- {
+		span := spans[i] // spans are ended, and exported, children first
+		assert.Equal(t, spans[0].SpanContext.TraceID(), span.SpanContext.TraceID())
+		depth := 1
+		if span.Parent.IsValid() {
+			parentDepth, ok := depths[span.Parent.SpanID()]
+			require.True(t, ok)
+			depth = parentDepth + 1
+		}
+		depths[span.SpanContext.SpanID()] = depth
+		maxDepth = max(maxDepth, depth)
+	}
+	assert.Equal(t, 4, maxDepth)
+}
+
+func TestGenerateSpansErrorRate(t *testing.T) {
+	_, stats := generateTestSpans(t, NewConfig(WithSpanCount(10), WithErrorRate(1)))
+	assert.Equal(t, EventStats{SpansSent: 10, ExceptionsSent: 9}, stats)
+}
+
+func TestGenerateSpansMinimal(t *testing.T) {
+	for name, cfg := range map[string]Config{
+		"default":    NewConfig(WithEvents(false), WithExceptions(false), WithStandaloneLogs(false)),
+		"span_count": NewConfig(WithEvents(false), WithExceptions(false), WithStandaloneLogs(false), WithSpanCount(5), WithErrorRate(1)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			spans, stats := generateTestSpans(t, cfg)
+			records, logStats := generateTestLogs(t, cfg)
+			assert.Empty(t, records)
+			assert.Equal(t, EventStats{SpansSent: len(spans)}, stats.Add(logStats))
+			for _, span := range spans {
+				assert.Empty(t, span.Events)
+			}
+		})
+	}
+}
+
+func TestGenerateSpansSpanLinks(t *testing.T) {
+	for name, cfg := range map[string]Config{
+		"default":    NewConfig(WithSpanLinks(3)),
+		"span_count": NewConfig(WithSpanLinks(3), WithSpanCount(5)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			spans, stats := generateTestSpans(t, cfg)
+			assert.Equal(t, 3, stats.SpanLinksSent)
+			assert.Len(t, spans, stats.SpansSent)
+
+			linked := make(map[trace.SpanID]bool)
+			var root *tracetest.SpanStub
+			for i, span := range spans {
+				switch {
+				case strings.HasPrefix(span.Name, "linked"):
+					linked[span.SpanContext.SpanID()] = true
+				case !span.Parent.IsValid():
+					root = &spans[i]
+				}
+			}
+			assert.Len(t, linked, 3)
+			require.NotNil(t, root)
+			require.Len(t, root.Links, 3)
+			for _, link := range root.Links {
+				assert.True(t, link.SpanContext.IsValid())
+				assert.True(t, linked[link.SpanContext.SpanID()])
+				assert.NotEqual(t, root.SpanContext.TraceID(), link.SpanContext.TraceID())
+			}
+		})
+	}
+}
+
+func TestGenerateSpansTraceparent(t *testing.T) {
+	const traceparent = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+	// NewConfig sets these from, and in, the environment.
+	t.Setenv("ELASTIC_APM_SERVER_URL", "")
+	t.Setenv("ELASTIC_APM_API_KEY", "")
+	cfg := NewConfig(
+		WithAPMServerURL("http://localhost:8200"),
+		WithAPIKey("api_key"),
+		WithTraceparent(traceparent, "vendor=value"),
+		WithSpanCount(5),
+	)
+	require.NoError(t, cfg.validate())
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", fmt.Sprintf("%x", cfg.traceID[:]))
+
+	exporter := tracetest.NewInMemoryExporter()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	defer tracerProvider.Shutdown(context.Background())
+
+	ctx := SetOTLPTracePropagator(context.Background(), cfg.traceparent, cfg.tracestate)
+	var stats EventStats
+	_, err := generateSpans(ctx, tracerProvider.Tracer("tracegen"), cfg, &stats)
+	require.NoError(t, err)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 5)
+	var remoteParents int
+	for _, span := range spans {
+		assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", span.SpanContext.TraceID().String())
+		assert.Equal(t, "vendor=value", span.SpanContext.TraceState().String())
+		if span.Parent.IsRemote() {
+			assert.Equal(t, "b7ad6b7169203331", span.Parent.SpanID().String())
+			remoteParents++
+		}
+	}
+	assert.Equal(t, 1, remoteParents)
+}
+
+func TestParseTraceparentHeader(t *testing.T) {
+	t.Setenv("ELASTIC_APM_SERVER_URL", "")
+	t.Setenv("ELASTIC_APM_API_KEY", "")
+	tc, err := parseTraceparentHeader("00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	require.NoError(t, err)
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01", fmt.Sprintf(
+		"%x-%x-%02x", tc.Trace[:], tc.Span[:], tc.Options,
+	))
+
+	for _, invalid := range []string{
+		"0af7651916cd43dd8448eb211c80319c",
+		"01-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+		"00-0AF7651916CD43DD8448EB211C80319C-b7ad6b7169203331-01",
+		"00-0af7651916cd43dd8448eb211c80319z-b7ad6b7169203331-01",
+		"00-00000000000000000000000000000000-b7ad6b7169203331-01",
+		"00-0af7651916cd43dd8448eb211c80319c-0000000000000000-01",
+		"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-1",
+	} {
+		_, err := parseTraceparentHeader(invalid)
+		assert.Error(t, err, invalid)
+		assert.Error(t, NewConfig(WithAPMServerURL("http://localhost:8200"), WithAPIKey("abc"), WithTraceparent(invalid, "")).validate(), invalid)
+	}
+}
+
+func TestMergeTracestate(t *testing.T) {
+	assert.Equal(t, "es=s:1", mergeTracestate("es=s:1", ""))
+	assert.Equal(t, "es=s:1,vendor=value", mergeTracestate("es=s:1", "es=s:0.5, vendor=value"))
+}
+
+// generateTestSpans calls generateSpans with cfg, returning
+// the exported spans along with the stats.
+func generateTestSpans(t testing.TB, cfg Config) (tracetest.SpanStubs, EventStats) {
+	exporter := tracetest.NewInMemoryExporter()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	defer tracerProvider.Shutdown(context.Background())
+
+	var stats EventStats
+	_, err := generateSpans(context.Background(), tracerProvider.Tracer("tracegen"), cfg, &stats)
+	require.NoError(t, err)
+	return exporter.GetSpans(), stats
+}
@@ -0,0 +1,160 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tracegen
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.elastic.co/apm/v2/apmtest"
+	"go.opentelemetry.io/otel/codes"
+)
+
+func TestFailureRateIntake(t *testing.T) {
+	for name, cfg := range map[string]Config{
+		"default":    NewConfig(WithFailureRate(1), WithTransactionOutcome("success")),
+		"span_count": NewConfig(WithFailureRate(1), WithSpanCount(5)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			tracer := apmtest.NewRecordingTracer()
+			defer tracer.Close()
+			for i := 0; i < 10; i++ {
+				tx := tracer.StartTransaction("tx", "request")
+				if cfg.spanCount > 0 {
+					generateIntakeSpanTree(tracer.Tracer, tx, cfg)
+				} else {
+					generateIntakeSpans(tracer.Tracer, tx, cfg)
+				}
+			}
+			tracer.Flush(nil)
+
+			transactions := tracer.Payloads().Transactions
+			require.Len(t, transactions, 10)
+			for _, tx := range transactions {
+				assert.Equal(t, "failure", tx.Outcome)
+			}
+		})
+	}
+}
+
+func TestFailureRateOTLP(t *testing.T) {
+	for name, cfg := range map[string]Config{
+		"default":    NewConfig(WithFailureRate(1), WithTransactionOutcome("success")),
+		"span_count": NewConfig(WithFailureRate(1), WithSpanCount(5)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			for i := 0; i < 10; i++ {
+				spans, _ := generateTestSpans(t, cfg)
+				var roots int
+				for _, span := range spans {
+					if !span.Parent.IsValid() {
+						roots++
+						assert.Equal(t, codes.Error, span.Status.Code)
+					}
+				}
+				assert.Equal(t, 1, roots)
+			}
+		})
+	}
+}
+
+func TestExceptionsDisabledIntake(t *testing.T) {
+	for name, cfg := range map[string]Config{
+		"default":    NewConfig(WithExceptions(false)),
+		"span_count": NewConfig(WithExceptions(false), WithSpanCount(5), WithErrorRate(1)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			tracer := apmtest.NewRecordingTracer()
+			defer tracer.Close()
+			tx := tracer.StartTransaction("tx", "request")
+			if cfg.spanCount > 0 {
+				generateIntakeSpanTree(tracer.Tracer, tx, cfg)
+			} else {
+				generateIntakeSpans(tracer.Tracer, tx, cfg)
+			}
+			tracer.Flush(nil)
+
+			payloads := tracer.Payloads()
+			assert.Len(t, payloads.Transactions, 1)
+			assert.NotEmpty(t, payloads.Spans)
+			assert.Empty(t, payloads.Errors)
+		})
+	}
+}
+
+func TestTransactionOutcomeIntake(t *testing.T) {
+	tracer := apmtest.NewRecordingTracer()
+	defer tracer.Close()
+	tx := tracer.StartTransaction("tx", "request")
+	generateIntakeSpans(tracer.Tracer, tx, NewConfig(
+		WithTransactionOutcome("unknown"),
+		WithDurations(3*time.Second, 2*time.Second, time.Second),
+	))
+	tracer.Flush(nil)
+
+	payloads := tracer.Payloads()
+	require.Len(t, payloads.Transactions, 1)
+	assert.Equal(t, "unknown", payloads.Transactions[0].Outcome)
+	assert.Equal(t, 3000.0, payloads.Transactions[0].Duration)
+	durations := make(map[string]float64)
+	for _, span := range payloads.Spans {
+		durations[span.Name] = span.Duration
+	}
+	assert.Equal(t, map[string]float64{"parent-span": 2000, "exit-span": 1000}, durations)
+}
+
+func TestTransactionOutcomeInvalid(t *testing.T) {
+	// NewConfig sets these from, and in, the environment.
+	t.Setenv("ELASTIC_APM_SERVER_URL", "")
+	t.Setenv("ELASTIC_APM_API_KEY", "")
+	err := NewConfig(
+		WithAPMServerURL("http://localhost:8200"),
+		WithAPIKey("api_key"),
+		WithTransactionOutcome("bogus"),
+	).validate()
+	assert.EqualError(t, err, `invalid transaction outcome "bogus" provided. allowed values: success, failure, unknown`)
+}
+
+func TestDurationsOTLPChildrenWithinParent(t *testing.T) {
+	for name, cfg := range map[string]Config{
+		"default":      NewConfig(),
+		"short_parent": NewConfig(WithDurations(100*time.Millisecond, 0, 0)),
+		"long_child":   NewConfig(WithDurations(time.Second, 5*time.Second, 5*time.Second)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			spans, _ := generateTestSpans(t, cfg)
+			require.Len(t, spans, 3)
+			var parentStart, parentEnd time.Time
+			for _, span := range spans {
+				if !span.Parent.IsValid() {
+					parentStart, parentEnd = span.StartTime, span.EndTime
+				}
+			}
+			for _, span := range spans {
+				if !span.Parent.IsValid() {
+					continue
+				}
+				assert.False(t, span.StartTime.Before(parentStart), "%s starts before parent", span.Name)
+				assert.False(t, span.EndTime.After(parentEnd), "%s ends after parent", span.Name)
+				assert.True(t, span.EndTime.After(span.StartTime), "%s has no duration", span.Name)
+			}
+		})
+	}
+}
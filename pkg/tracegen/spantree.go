@@ -0,0 +1,76 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tracegen
+
+import (
+	"math/rand"
+	"time"
+)
+
+// spanTreeDuration is the duration of the root of a generated span tree.
+const spanTreeDuration = 1500 * time.Millisecond
+
+// treeSpan describes a span in a generated span tree.
+type treeSpan struct {
+	// parent holds the index of the span's parent,
+	// or -1 if the span is the root of the tree.
+	parent int
+
+	// level holds the depth of the span in the tree,
+	// with 0 being the root.
+	level int
+
+	// start and end hold the offsets of the span's start
+	// and end times, relative to the start of the root.
+	start, end time.Duration
+
+	// error reports whether an error should be recorded
+	// for the span.
+	error bool
+}
+
+// newSpanTree returns count spans arranged in a tree of at most depth
+// levels. Non-root spans are distributed round-robin across the levels,
+// and across the parents within the level above.
+//
+// Each non-root span is marked with an error with probability errorRate.
+func newSpanTree(count, depth int, errorRate float64) []treeSpan {
+	if count <= 0 {
+		return nil
+	}
+	step := spanTreeDuration / time.Duration(2*depth)
+	spans := make([]treeSpan, 0, count)
+	spans = append(spans, treeSpan{parent: -1, end: spanTreeDuration})
+	levels := [][]int{{0}}
+	for i := 1; i < count && depth > 1; i++ {
+		level := 1 + (i-1)%(depth-1)
+		if level == len(levels) {
+			levels = append(levels, nil)
+		}
+		parents := levels[level-1]
+		spans = append(spans, treeSpan{
+			parent: parents[len(levels[level])%len(parents)],
+			level:  level,
+			start:  time.Duration(level) * step,
+			end:    spanTreeDuration - time.Duration(level)*step,
+			error:  rand.Float64() < errorRate,
+		})
+		levels[level] = append(levels[level], i)
+	}
+	return spans
+}
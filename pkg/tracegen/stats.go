@@ -28,6 +28,10 @@ type EventStats struct {
 
 	// SpansSent holds the number of transactions and spans sent.
 	SpansSent int
+
+	// SpanLinksSent holds the number of span links sent. Each
+	// linked span is also included in SpansSent.
+	SpanLinksSent int
 }
 
 // Add adds the statistics together, returning the result.
@@ -36,5 +40,6 @@ func (lhs EventStats) Add(rhs EventStats) EventStats {
 		ExceptionsSent: lhs.ExceptionsSent + rhs.ExceptionsSent,
 		LogsSent:       lhs.LogsSent + rhs.LogsSent,
 		SpansSent:      lhs.SpansSent + rhs.SpansSent,
+		SpanLinksSent:  lhs.SpanLinksSent + rhs.SpanLinksSent,
 	}
 }
@@ -0,0 +1,32 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tracegen_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/apm-tools/pkg/tracegen"
+)
+
+func TestEventStatsAdd(t *testing.T) {
+	lhs := tracegen.EventStats{SpansSent: 2, ExceptionsSent: 1}
+	rhs := tracegen.EventStats{SpansSent: 3, LogsSent: 4}
+	assert.Equal(t, tracegen.EventStats{SpansSent: 5, ExceptionsSent: 1, LogsSent: 4}, lhs.Add(rhs))
+}
@@ -19,6 +19,8 @@ package tracegen
 
 import (
 	"encoding/binary"
+	"encoding/hex"
+	"fmt"
 	"math/rand"
 
 	"go.elastic.co/apm/v2"
@@ -32,3 +34,18 @@ func NewRandomTraceID() apm.TraceID {
 	binary.LittleEndian.PutUint64(traceID[8:], rand.Uint64())
 	return traceID
 }
+
+// ParseTraceID parses a trace ID from its 32 character hex encoding.
+func ParseTraceID(s string) (apm.TraceID, error) {
+	var traceID apm.TraceID
+	if len(s) != hex.EncodedLen(len(traceID)) {
+		return traceID, fmt.Errorf("invalid trace ID %q: must be 32 hex characters", s)
+	}
+	if _, err := hex.Decode(traceID[:], []byte(s)); err != nil {
+		return traceID, fmt.Errorf("invalid trace ID %q: %w", s, err)
+	}
+	if err := traceID.Validate(); err != nil {
+		return traceID, fmt.Errorf("invalid trace ID %q: %w", s, err)
+	}
+	return traceID, nil
+}
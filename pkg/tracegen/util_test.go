@@ -0,0 +1,44 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tracegen
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTraceID(t *testing.T) {
+	traceID, err := ParseTraceID("0af7651916cd43dd8448eb211c80319c")
+	require.NoError(t, err)
+	cfg := NewConfig(WithTraceID(traceID))
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", fmt.Sprintf("%x", cfg.traceID[:]))
+	assert.True(t, cfg.fixedTraceID)
+
+	for _, invalid := range []string{
+		"",
+		"0af7651916cd43dd",
+		"0af7651916cd43dd8448eb211c80319z",
+		"00000000000000000000000000000000",
+	} {
+		_, err := ParseTraceID(invalid)
+		assert.Error(t, err, invalid)
+	}
+}